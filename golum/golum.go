@@ -33,6 +33,9 @@
 //
 // In an actual process, this is all managed from main with uboot.
 //
+// The state of each component is published in the golum_component_state
+// metric (see umetrics).
+//
 // You can see the available components and their settings from the command line.
 //
 //	program -show all
@@ -159,13 +162,15 @@ func Load(configs *uconfig.Array) (err error) {
 	}
 
 	//
-	// build the reloadables
+	// build the reloadables, reporting all that have problems
 	//
+	errs := uerr.NewErrors(0)
 	for _, g := range ready {
-		err = g.Build()
-		if err != nil {
-			return
-		}
+		errs.Add(g.Build())
+	}
+	err = errs.Err()
+	if err != nil {
+		return
 	}
 
 	//
@@ -475,6 +480,7 @@ func (g *golum_) Rebuild(c *uconfig.Section) (err error) {
 func (g *golum_) AfterBuild() {
 	if g.disabled {
 		uregistry.Put(g.name, disabled_{})
+		g.setState(stateDisabled)
 	} else {
 		uregistry.Put(g.name, g.curr)
 	}
@@ -504,6 +510,7 @@ func (g *golum_) Start() (err error) {
 	}
 	g.needStart = false
 	log.Printf("G: Starting %s", g.name)
+	g.setState(stateStarting)
 	timer := time.NewTimer(g.timeout)
 	startC := make(chan error)
 	go func() {
@@ -520,8 +527,10 @@ func (g *golum_) Start() (err error) {
 	}
 	if err != nil {
 		g.failed = true
+		g.setState(stateFailed)
 		return
 	}
+	g.setState(stateRunning)
 	if g.failed {
 		g.failed = false
 		onFail_(g.name, nil)
@@ -532,6 +541,7 @@ func (g *golum_) Start() (err error) {
 func (g *golum_) Stop() {
 	g.StopOld()
 	uregistry.Remove(g.name)
+	g.clearState()
 	if g.disabled || nil == g.curr {
 		return
 	}
@@ -64,6 +64,10 @@ type Reloadable interface {
 	// value obsoletes the initial Reloadable, and the initial Reloadable will
 	// be Stop()ed.
 	//
+	// Reload is also invoked with an empty config when producing help (via
+	// -show), so that the getters can document settings, such as the choices
+	// of GetEnum.  The result is discarded.
+	//
 	Reload(name string, config *uconfig.Chain) (rv Reloadable, err error)
 
 	//
@@ -0,0 +1,41 @@
+package golum
+
+import "github.com/tredeske/u/umetrics"
+
+// component states published in golum_component_state
+const (
+	stateDisabled = "disabled"
+	stateStarting = "starting"
+	stateRunning  = "running"
+	stateFailed   = "failed"
+)
+
+var (
+	states_         = []string{stateDisabled, stateStarting, stateRunning, stateFailed}
+	componentState_ = umetrics.NewGaugeVec("golum_component_state",
+		"1 for the current state of the component, 0 for the others",
+		"name", "type", "state")
+	componentFailures_ = umetrics.NewCounterVec("golum_component_failures_total",
+		"Component start failures", "name", "type")
+)
+
+// publish the state of the component
+func (g *golum_) setState(state string) {
+	for _, s := range states_ {
+		v := 0.0
+		if s == state {
+			v = 1
+		}
+		componentState_.With(g.name, g.typ, s).Set(v)
+	}
+	if stateFailed == state {
+		componentFailures_.With(g.name, g.typ).Inc()
+	}
+}
+
+// stop publishing the state of the component
+func (g *golum_) clearState() {
+	for _, s := range states_ {
+		componentState_.Delete(g.name, g.typ, s)
+	}
+}
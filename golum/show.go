@@ -4,16 +4,13 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/tredeske/u/uconfig"
 )
 
-// show info about named component type.  if kind is 'all', then list all.
-func Show(kind string, out io.Writer) {
-
-	if "all" == kind {
-
-		fmt.Fprintf(out, `
+// describes the overall structure of the config
+const structure_ = `
 Structure
 =========
 
@@ -53,7 +50,15 @@ The following properties are automatically added:
 Other files can be included with the 'include_' directive, as in:
 
 include_:        /path/to/file.yml
+`
+
+// show info about named component type.  if kind is 'all', then list all.
+func Show(kind string, out io.Writer) {
 
+	if "all" == kind {
+
+		fmt.Fprint(out, structure_)
+		fmt.Fprint(out, `
 
 Available Components
 ====================
@@ -62,27 +67,17 @@ These are the available components.  To see the component specific configuration
 use the -show [component] command line parameter.
 
 `)
-		var kinds []string
-		prototypes_.Range(func(k, v any) (cont bool) {
-			kinds = append(kinds, k.(string))
-			return true
-		})
-		sort.Strings(kinds)
-		for _, n := range kinds {
+		for _, n := range kinds() {
 			fmt.Fprintf(out, "\t%s\n", n)
 		}
 
 	} else {
 
-		it, ok := prototypes_.Load(kind)
-		if !ok {
-			fmt.Fprintf(out, "Unknown component type: %s\n", kind)
+		help, err := helpFor(kind)
+		if err != nil {
+			fmt.Fprintf(out, "%s\n", err)
 			return
 		}
-		prototype := it.(Reloadable)
-
-		help := &uconfig.Help{}
-		prototype.Help(kind, help)
 
 		content, err := help.AsYaml()
 		if err != nil {
@@ -92,3 +87,98 @@ use the -show [component] command line parameter.
 		out.Write(content)
 	}
 }
+
+// the names of all component types, sorted
+func kinds() (rv []string) {
+	prototypes_.Range(func(k, v any) (cont bool) {
+		rv = append(rv, k.(string))
+		return true
+	})
+	sort.Strings(rv)
+	return
+}
+
+// get the help for the named component type
+func helpFor(kind string) (help *uconfig.Help, err error) {
+	it, ok := prototypes_.Load(kind)
+	if !ok {
+		err = fmt.Errorf("Unknown component type: %s", kind)
+		return
+	}
+	help = &uconfig.Help{}
+	r := it.(Reloadable)
+	r.Help(kind, help)
+	if params := help.GetHelp("params"); nil != params {
+		describe(kind, r, params)
+	}
+	return
+}
+
+// reload against an empty config that records into params what the getters
+// know, such as the choices of GetEnum.  the result is discarded.
+func describe(kind string, r Reloadable, params *uconfig.Help) {
+	defer func() { recover() }()
+	r.Reload(kind, params.Chain())
+}
+
+// show info about named component type in the format (yaml, markdown, or
+// man).  if kind is 'all', then markdown and man show all component types,
+// for generating reference docs.
+//
+// program is the name of the program, used for titles.
+func ShowAs(kind, format, program string, out io.Writer) (err error) {
+	var kindList []string
+	if "all" == kind {
+		kindList = kinds()
+	} else {
+		kindList = []string{kind}
+	}
+	switch format {
+	case "", "yaml":
+		Show(kind, out)
+
+	case "markdown", "md":
+		if "all" == kind {
+			fmt.Fprintf(out, "# %s configuration\n\n```\n%s\n```\n\n"+
+				"## Components\n\n", program, strings.TrimSpace(structure_))
+		}
+		for _, k := range kindList {
+			var help *uconfig.Help
+			help, err = helpFor(k)
+			if err != nil {
+				return
+			}
+			level := 1
+			if "all" == kind {
+				level = 3
+			}
+			err = help.WriteMarkdown(out, level)
+			if err != nil {
+				return
+			}
+		}
+
+	case "man":
+		uconfig.WriteManHeader(out, program, 5, "configuration")
+		if "all" == kind {
+			fmt.Fprintf(out, ".SH DESCRIPTION\n.nf\n%s\n.fi\n",
+				strings.ReplaceAll(strings.TrimSpace(structure_), `\`, `\e`))
+		}
+		fmt.Fprint(out, ".SH COMPONENTS\n")
+		for _, k := range kindList {
+			var help *uconfig.Help
+			help, err = helpFor(k)
+			if err != nil {
+				return
+			}
+			err = help.WriteMan(out)
+			if err != nil {
+				return
+			}
+		}
+
+	default:
+		err = fmt.Errorf("Unknown help format: %s", format)
+	}
+	return
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/tredeske/u/uconfig"
 	"github.com/tredeske/u/ulog"
+	"github.com/tredeske/u/umetrics"
 )
 
 var (
@@ -31,6 +32,8 @@ components:
 
 	time.Sleep(50 * time.Millisecond)
 
+	expectMetric(t, `golum_component_state{name="auto",type="auto",state="running"} 1`)
+	expectMetric(t, `golum_component_state{name="auto",type="auto",state="failed"} 0`)
 }
 
 func TestDelayedStart(t *testing.T) {
@@ -57,6 +60,16 @@ components:
 	} else {
 		t.Fatalf("Should have errored out due to delay")
 	}
+	expectMetric(t, `golum_component_state{name="delayed",type="auto",state="failed"} 1`)
+	expectMetric(t, `golum_component_failures_total{name="delayed",type="auto"} 1`)
+}
+
+func expectMetric(t *testing.T, expect string) {
+	var b strings.Builder
+	umetrics.Default.Expose(&b)
+	if !strings.Contains(b.String(), expect+"\n") {
+		t.Fatalf("Missing %s in:\n%s", expect, b.String())
+	}
 }
 
 type auto_ struct {
@@ -124,7 +124,7 @@ func TestTesting(t *testing.T) {
 	}
 
 	time.Sleep(time.Second)
-	if !ulog.DebugEnabled {
+	if !ulog.IsDebugEnabled() {
 		t.Fatalf("debug not enabled for all")
 	}
 
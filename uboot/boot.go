@@ -20,16 +20,49 @@
 //	program -show all
 //	program -show [component]
 //
+// Reference docs for all components can be generated as Markdown or man pages:
+//
+//	program -show all -show-format markdown > config.md
+//	program -show all -show-format man > program.5
+//
 // To run program:
 //
-//	program -config config.yml -log [stdout|logfile]
+//	program -config config.yml -log [stdout|syslog|journald|logfile]
+//
+// Log files rotate by size, and optionally by time, with retention by count,
+// age and total size:
+//
+//	program -log-daily -log-compress -log-max-days 90
+//
+// Debug may be toggled at runtime without a config change.  When turned on,
+// it turns back off after -debug-revert (default 5m):
+//
+//	kill -USR1 $pid
+//
+// Each config that is successfully loaded is kept as a snapshot (see
+// uconfig.History), so that it is possible to see what changed, and to
+// return to a config that worked:
+//
+//	program -config-list                  # list snapshots
+//	program -config-diff previous         # what changed in latest?
+//	program -config-snapshot previous     # boot from prior config
+//
+// Redacted secrets in a snapshot are taken from the properties of -config.
+//
+// Secrets in config may be encrypted (see uconfig.Encrypt):
+//
+//	program -gen-secret-key > secret.key  # once
+//	program -config-secret-key secret.key -encrypt < password.txt
+//	program -config-secret-key secret.key -encrypt-config properties.dbPass
 package uboot
 
 import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -40,11 +73,13 @@ import (
 	"time"
 
 	"github.com/tredeske/u/golum"
+	"github.com/tredeske/u/ucerts"
 	"github.com/tredeske/u/uconfig"
 	"github.com/tredeske/u/uerr"
 	"github.com/tredeske/u/uexit"
 	"github.com/tredeske/u/uinit"
 	"github.com/tredeske/u/ulog"
+	"github.com/tredeske/u/urest"
 )
 
 var (
@@ -75,12 +110,45 @@ type Boot struct {
 	DryRun    bool             // is this a dry run (config check)?
 	RedirectF string           // file to redirect stderr to
 
+	//
+	// for log rotation and retention in addition to LogSize and LogKeep
+	//
+	LogPolicy ulog.RotatePolicy
+
+	//
+	// for debug toggled by SIGUSR1 (see ulog.ToggleDebugOnSignal)
+	//
+	DebugRevert time.Duration // turn debug back off after this long (0: never)
+
+	//
+	// for remote (http, https) config includes
+	//
+	ConfigCacheD string // where to cache remote includes
+	ConfigCaF    string // CA certs PEM to verify config server
+	ConfigCertF  string // client cert PEM to present to config server
+	ConfigKeyF   string // client key PEM to present to config server
+
+	//
+	// for encrypted config values
+	//
+	ConfigSecretKeyF string // key file for encrypted values
+
+	//
+	// for snapshots of accepted config
+	//
+	ConfigHistoryD string // where to keep snapshots
+	ConfigKeep     int    // number of snapshots to keep
+	ConfigSnapshot string // if set, boot from this snapshot instead of ConfigF
+	liveConfigF    string // if booting from snapshot, config for redacted props
+
 	//
 	// set by build system.  examples:
 	// go build -ldflags '-X /import/path.Version=#{$stamp}-#{REV}'
 	// go build -ldflags '-X main.Version=#{$stamp}-#{REV}'
 	//
 	Version string
+
+	cspec string // config key of components
 }
 
 // simple boot using supplied Boot
@@ -116,8 +184,19 @@ func (this *Boot) Boot() (err error) {
 
 	version := false
 	show := ""
+	showFormat := "yaml"
+	listSnapshots := false
+	diffSnapshots := ""
+	encrypt := false
+	encryptPath := ""
+	genKey := false
 	logSzStr := "40Mi"
 	logKeep := 4
+	logMaxDays := 0
+	logMaxTotalStr := ""
+	if 0 == this.DebugRevert {
+		this.DebugRevert = 5 * time.Minute
+	}
 
 	flag.BoolVar(&this.DryRun, "dry-run", this.DryRun,
 		"Load but do not start components.  Useful to check config.")
@@ -125,11 +204,53 @@ func (this *Boot) Boot() (err error) {
 	flag.StringVar(&this.ConfigF, "config", this.ConfigF,
 		"Config file (config/[NAME].yml)")
 
+	flag.StringVar(&this.ConfigCacheD, "config-cache", this.ConfigCacheD,
+		"Dir to cache remote config includes (default: cache/config)")
+
+	flag.StringVar(&this.ConfigCaF, "config-ca", this.ConfigCaF,
+		"CA certs PEM file for verifying remote config server")
+
+	flag.StringVar(&this.ConfigCertF, "config-cert", this.ConfigCertF,
+		"Client cert PEM file for remote config server")
+
+	flag.StringVar(&this.ConfigKeyF, "config-key", this.ConfigKeyF,
+		"Client key PEM file for remote config server")
+
+	flag.StringVar(&this.ConfigSecretKeyF, "config-secret-key", this.ConfigSecretKeyF,
+		"Key file for encrypted config values (default: $UCONFIG_KEY_FILE, $UCONFIG_KEY)")
+
+	flag.BoolVar(&encrypt, "encrypt", encrypt,
+		"Encrypt value read from stdin using -config-secret-key, print it, and exit")
+
+	flag.StringVar(&encryptPath, "encrypt-config", encryptPath,
+		"Encrypt values at key path (a.b.c) in -config file in place, and exit")
+
+	flag.BoolVar(&genKey, "gen-secret-key", genKey,
+		"Generate a key for encrypting config values, print it, and exit")
+
+	flag.StringVar(&this.ConfigHistoryD, "config-history", this.ConfigHistoryD,
+		"Dir to keep snapshots of accepted config (default: history/config)")
+
+	flag.IntVar(&this.ConfigKeep, "config-keep", this.ConfigKeep,
+		"Number of config snapshots to keep (default: 20)")
+
+	flag.StringVar(&this.ConfigSnapshot, "config-snapshot", this.ConfigSnapshot,
+		"Boot from config snapshot (latest, previous, N, or hash) instead of -config")
+
+	flag.BoolVar(&listSnapshots, "config-list", listSnapshots,
+		"List config snapshots and exit")
+
+	flag.StringVar(&diffSnapshots, "config-diff", diffSnapshots,
+		"Show diff of config snapshots (FROM[,TO], TO defaults to latest) and exit")
+
 	flag.BoolVar(&ulog.DebugEnabled, "debug", ulog.DebugEnabled,
 		"Turn on debugging")
 
+	flag.DurationVar(&this.DebugRevert, "debug-revert", this.DebugRevert,
+		"Turn off debug toggled on by SIGUSR1 after this long (0: never)")
+
 	flag.StringVar(&this.LogF, "log", "",
-		"Set to 'stdout' or to path of log file (default: log/[NAME].log)")
+		"Set to 'stdout', 'syslog', 'journald', or to path of log file (default: log/[NAME].log)")
 
 	flag.StringVar(&this.RedirectF, "redirect", "",
 		"Set to path of file to redirect stderr to. (default: no redirect")
@@ -139,6 +260,21 @@ func (this *Boot) Boot() (err error) {
 
 	flag.IntVar(&logKeep, "log-keep", logKeep, "Number of log files to keep around")
 
+	flag.BoolVar(&this.LogPolicy.Daily, "log-daily", this.LogPolicy.Daily,
+		"Rotate log file at local midnight")
+
+	flag.DurationVar(&this.LogPolicy.Every, "log-every", this.LogPolicy.Every,
+		"Rotate log file at this interval (such as 6h), aligned to midnight")
+
+	flag.BoolVar(&this.LogPolicy.Compress, "log-compress", this.LogPolicy.Compress,
+		"Compress rotated log files")
+
+	flag.IntVar(&logMaxDays, "log-max-days", logMaxDays,
+		"Remove rotated log files older than this many days")
+
+	flag.StringVar(&logMaxTotalStr, "log-max-total", logMaxTotalStr,
+		"Remove oldest rotated log files beyond this total size.  K,M,G,Ki,Mi,Gi suffixes supported.")
+
 	flag.StringVar(&this.Name, "name", this.Name, "Name of program")
 
 	flag.BoolVar(&version, "version", version, "Print version and exit")
@@ -146,16 +282,44 @@ func (this *Boot) Boot() (err error) {
 	flag.StringVar(&show, "show", show,
 		"Show settings for named component, or 'all'")
 
+	flag.StringVar(&showFormat, "show-format", showFormat,
+		"Format for -show: yaml, markdown, or man")
+
 	flag.Parse()
 
 	if version {
 		fmt.Printf("Version %s\n", this.Version)
 		os.Exit(0)
 	} else if 0 != len(show) {
-		golum.Show(show, os.Stdout)
+		err = golum.ShowAs(show, showFormat, this.Name, os.Stdout)
+		if nil == err {
+			os.Exit(0)
+		}
+		return
+	} else if genKey {
+		var key string
+		key, err = uconfig.NewSecretKey()
+		if err != nil {
+			return
+		}
+		fmt.Println(key)
 		os.Exit(0)
 	}
 
+	if 0 != len(this.ConfigSecretKeyF) {
+		err = uconfig.LoadSecretKey(this.ConfigSecretKeyF)
+		if err != nil {
+			return
+		}
+	}
+	if encrypt {
+		err = encryptStdin(os.Stdin, os.Stdout)
+		if nil == err {
+			os.Exit(0)
+		}
+		return
+	}
+
 	if 0 == len(this.Name) {
 		err = errors.New("Program name (-name param) not specified")
 		return
@@ -163,12 +327,45 @@ func (this *Boot) Boot() (err error) {
 	uconfig.ThisProcess = this.Name
 	uconfig.DryRun = this.DryRun
 
+	err = this.configHistory()
+	if err != nil {
+		return
+	} else if listSnapshots {
+		err = this.listSnapshots(os.Stdout)
+		if nil == err {
+			os.Exit(0)
+		}
+		return
+	} else if 0 != len(diffSnapshots) {
+		from, to, _ := strings.Cut(diffSnapshots, ",")
+		var diff string
+		diff, err = uinit.ConfigHistory.Diff(from, to)
+		if err != nil {
+			return
+		}
+		fmt.Print(diff)
+		os.Exit(0)
+	}
+
 	//
 	// verify we have a config file
 	//
 	if 0 == len(this.ConfigF) {
 		this.ConfigF = path.Join(this.InstallD, "config", this.Name+".yml")
 	}
+	if 0 != len(this.ConfigSnapshot) {
+		var snap uconfig.Snapshot
+		snap, err = uinit.ConfigHistory.Find(this.ConfigSnapshot)
+		if err != nil {
+			return
+		}
+		log.Printf("Booting from config snapshot %s", snap)
+		this.liveConfigF, err = filepath.Abs(this.ConfigF)
+		if err != nil {
+			return
+		}
+		this.ConfigF = snap.File
+	}
 	this.ConfigF, err = filepath.Abs(this.ConfigF)
 	if err != nil {
 		return
@@ -178,6 +375,19 @@ func (this *Boot) Boot() (err error) {
 		return fmt.Errorf("Config file missing?: %s", err)
 	}
 
+	if 0 != len(encryptPath) {
+		err = this.encryptConfig(encryptPath)
+		if nil == err {
+			os.Exit(0)
+		}
+		return
+	}
+
+	err = this.remoteConfig()
+	if err != nil {
+		return
+	}
+
 	//
 	// Add this dir to PATH
 	//
@@ -209,6 +419,15 @@ func (this *Boot) Boot() (err error) {
 			logKeep = 2
 		}
 		this.LogKeep = logKeep
+		if 0 < logMaxDays {
+			this.LogPolicy.MaxAge = time.Duration(logMaxDays) * 24 * time.Hour
+		}
+		if 0 != len(logMaxTotalStr) {
+			err = uconfig.IntFromSiString(logMaxTotalStr, &this.LogPolicy.MaxTotal)
+			if err != nil {
+				return
+			}
+		}
 	}
 
 	/*
@@ -236,6 +455,107 @@ func (this *Boot) Boot() (err error) {
 	return uconfig.InitEnv()
 }
 
+// set up uconfig for remote (http, https) includes
+func (this *Boot) remoteConfig() (err error) {
+	if 0 == len(this.ConfigCacheD) {
+		this.ConfigCacheD = path.Join(this.InstallD, "cache", "config")
+	}
+	uconfig.RemoteCacheD, err = filepath.Abs(this.ConfigCacheD)
+	if err != nil {
+		return
+	}
+	if 0 == len(this.ConfigCaF) && 0 == len(this.ConfigCertF) &&
+		0 == len(this.ConfigKeyF) {
+		return
+	}
+	tlsc := ucerts.DefaultTlsConfig()
+	err = ucerts.Load(this.ConfigKeyF, this.ConfigCertF, this.ConfigCaF, tlsc)
+	if err != nil {
+		return uerr.Chainf(err, "remote config TLS")
+	}
+	client := urest.DefaultHttpClient()
+	client.Transport.(*http.Transport).TLSClientConfig = tlsc
+	client.Timeout = uconfig.RemoteClient.Timeout
+	uconfig.RemoteClient = client
+	return
+}
+
+// encrypt the value read from in, writing the result to out
+func encryptStdin(in io.Reader, out io.Writer) (err error) {
+	plain, err := io.ReadAll(in)
+	if err != nil {
+		return
+	}
+	encrypted, err := uconfig.Encrypt(strings.TrimRight(string(plain), "\r\n"))
+	if nil == err {
+		_, err = fmt.Fprintln(out, encrypted)
+	}
+	return
+}
+
+// encrypt the values at path in the config file
+func (this *Boot) encryptConfig(path string) (err error) {
+	ed, err := uconfig.EditFile(this.ConfigF)
+	if err != nil {
+		return
+	}
+	count, err := ed.Encrypt(path)
+	if err != nil {
+		return
+	}
+	err = ed.Save()
+	if nil == err {
+		fmt.Printf("Encrypted %d values at %s in %s\n", count, path, this.ConfigF)
+	}
+	return
+}
+
+// set up snapshots of accepted config
+func (this *Boot) configHistory() (err error) {
+	if 0 == len(this.ConfigHistoryD) {
+		this.ConfigHistoryD = path.Join(this.InstallD, "history", "config")
+	}
+	historyD, err := filepath.Abs(this.ConfigHistoryD)
+	if err != nil {
+		return
+	}
+	uinit.ConfigHistory = uconfig.NewHistory(historyD, this.ConfigKeep)
+	return
+}
+
+// output the available config snapshots, newest first
+func (this *Boot) listSnapshots(w io.Writer) (err error) {
+	snaps, err := uinit.ConfigHistory.List()
+	if err != nil {
+		return
+	}
+	for i, snap := range snaps {
+		fmt.Fprintf(w, "%3d  %s  %s\n", i, snap, snap.File)
+	}
+	return
+}
+
+// Reload components from a config snapshot (latest, previous, N, or hash),
+// returning to a config that was previously accepted.
+//
+// invoke after Configure()
+func (this *Boot) Rollback(snapshot string) (err error) {
+	snap, err := uinit.ConfigHistory.Find(snapshot)
+	if err != nil {
+		return
+	}
+	ulog.Printf("Rolling back to config snapshot %s", snap)
+	config, err := uinit.InitSnapshot(snap.File, this.Config)
+	if err != nil {
+		return
+	}
+	err = this.reload(config)
+	if nil == err {
+		this.Config = config
+	}
+	return
+}
+
 // Continue boot process: redirect stdin, stdout, stderr and setup logging
 //
 // if logF is empty, then use configured setting, which may be "stdout"
@@ -256,10 +576,15 @@ func (this *Boot) Redirect() (err error) {
 			this.LogKeep = 2
 		}
 	}
+	ulog.SyslogIdent = this.Name
+	ulog.SetRotatePolicy(this.LogPolicy)
 	err = ulog.Init(this.LogF, this.LogSize, this.LogKeep)
 	if err != nil {
 		return
 	}
+	if !Testing {
+		ulog.ToggleDebugOnSignal(this.DebugRevert, syscall.SIGUSR1)
+	}
 
 	//
 	// redirect stderr to file, if necessary
@@ -391,8 +716,9 @@ func (this *Boot) Configure(
 
 	profile()
 
+	this.cspec = cspec
 	log.Printf("configuring from %s", this.ConfigF)
-	config, err = uinit.InitConfig(this.ConfigF)
+	config, err = this.loadConfig()
 	if err != nil {
 		return
 	}
@@ -424,23 +750,14 @@ func (this *Boot) Configure(
 
 			// always return false - we want to always keep retrying
 			func(file string) (done bool) {
-				config, err := uinit.InitConfig(this.ConfigF)
+				config, err := this.loadConfig()
 				if err != nil {
 					ulog.Errorf("Unable to parse %s: %s", this.ConfigF, err)
 					return false
 				}
-
-				//config.AddProp("logDir", ulog.Dir)
-				config.AddProp("name", this.Name)
-				var gconfig *uconfig.Array
-				err = config.GetArray(cspec, &gconfig)
+				err = this.reload(config)
 				if err != nil {
-					ulog.Errorf("Getting '%s' from %s: %s", cspec, this.ConfigF, err)
-					return false
-				}
-				err = golum.Reload(gconfig)
-				if err != nil {
-					ulog.Errorf("Unable to load components: %s", err)
+					ulog.Errorf("Unable to reload %s: %s", this.ConfigF, err)
 				}
 				return false
 			},
@@ -453,6 +770,41 @@ func (this *Boot) Configure(
 	return
 }
 
+// load ConfigF.  if booting from a snapshot, then redacted properties are
+// taken from the regular config, if it can be loaded.
+func (this *Boot) loadConfig() (config *uconfig.Section, err error) {
+	if 0 == len(this.liveConfigF) {
+		return uinit.InitConfig(this.ConfigF)
+	}
+	live, err := uconfig.NewSection(this.liveConfigF)
+	if err != nil {
+		ulog.Warnf("Unable to load %s for redacted snapshot properties: %s",
+			this.liveConfigF, err)
+		live = nil
+	}
+	return uinit.InitSnapshot(this.ConfigF, live)
+}
+
+// reload the components from config, recording it as accepted if successful
+func (this *Boot) reload(config *uconfig.Section) (err error) {
+	if 0 == len(this.cspec) {
+		return errors.New("no components configured to reload")
+	}
+	//config.AddProp("logDir", ulog.Dir)
+	config.AddProp("name", this.Name)
+	var gconfig *uconfig.Array
+	err = config.GetArray(this.cspec, &gconfig)
+	if err != nil {
+		return uerr.Chainf(err, "getting '%s'", this.cspec)
+	}
+	err = golum.Reload(gconfig)
+	if err != nil {
+		return uerr.Chainf(err, "loading components")
+	}
+	uinit.ConfigAccepted(config)
+	return
+}
+
 func (this *Boot) loadComponents(
 	config *uconfig.Section,
 	cspec string,
@@ -495,6 +847,9 @@ func (this *Boot) loadComponents(
 	}
 
 	err = golum.Start()
+	if nil == err {
+		uinit.ConfigAccepted(config)
+	}
 	return
 }
 
@@ -11,6 +11,7 @@ type Array struct {
 	Context  string
 	expander expander_
 	sections []map[string]any
+	srcs     []*srcPos_ // where each section came from, if known
 }
 
 func ArrayFromSection(s *Section) (rv *Array) {
@@ -18,11 +19,13 @@ func ArrayFromSection(s *Section) (rv *Array) {
 		Context:  s.Context,
 		expander: s.expander,
 		sections: []map[string]any{s.section},
+		srcs:     []*srcPos_{s.src},
 	}
 }
 
 func (this *Array) Append(s *Section) {
 	this.sections = append(this.sections, s.section)
+	this.srcs = append(this.srcs, s.src)
 }
 
 func (this *Array) Len() int {
@@ -42,16 +45,25 @@ func (this *Array) Get(i int) *Section {
 		Context:  this.Context + "." + strconv.Itoa(i),
 		expander: this.expander.clone(),
 		section:  this.sections[i],
+		src:      this.src(i),
 	}
 }
 
+// where the i'th section came from, if known
+func (this *Array) src(i int) *srcPos_ {
+	if len(this.srcs) <= i {
+		return nil
+	}
+	return this.srcs[i]
+}
+
 // iterate through the sections, aborting of visitor returns an error
 func (this *Array) Each(visitor Visitor) (err error) {
 	if nil != this {
 		for i, _ := range this.sections {
 			err = visitor(this.Get(i))
 			if err != nil {
-				err = uerr.Chainf(err, "section %d", i)
+				err = uerr.Chainf(err, "section %d%s", i, this.src(i).suffix())
 				break
 			}
 		}
@@ -3,9 +3,11 @@ package uconfig
 import (
 	"fmt"
 	"math"
+	"net"
 	nurl "net/url"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/tredeske/u/uerr"
@@ -41,9 +43,49 @@ import (
 //	                Done()
 //	        }
 //	    Done()
+//
+// Normally, a Chain stops at the first error, and Error is set to that.
+// A Chain created with Section.ChainAll will instead run every accessor and
+// validator, collecting every error.  Done will then return a
+// *ValidationErrors listing every problem found, which is useful for finding
+// all of the problems in a large config in one go.
 type Chain struct {
 	Section *Section
 	Error   error
+	all     bool              // collect all errors instead of only first
+	errs    *ValidationErrors // collected errors when all is set
+}
+
+// ValidationErrors is produced by a Chain created with Section.ChainAll
+// when there are one or more problems with the config.
+type ValidationErrors struct {
+	Errors []error
+}
+
+// implement error interface, listing each error on its own line
+func (this *ValidationErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d config errors:", len(this.Errors))
+	for i, err := range this.Errors {
+		fmt.Fprintf(&b, "\n  %d: %s", i+1, err)
+	}
+	return b.String()
+}
+
+// support errors.Is and errors.As for each of the errors
+func (this *ValidationErrors) Unwrap() []error {
+	return this.Errors
+}
+
+// is an error with the same message already present?
+func (this *ValidationErrors) contains(err error) bool {
+	msg := err.Error()
+	for _, e := range this.Errors {
+		if msg == e.Error() {
+			return true
+		}
+	}
+	return false
 }
 
 // open file and read config from it
@@ -76,18 +118,71 @@ func (this *Chain) ctx(key string) string {
 	return this.Section.ctx(key)
 }
 
+// should the next accessor in the chain be run?
+func (this *Chain) proceed() bool {
+	return nil == this.Error || (this.all && nil != this.Section)
+}
+
+// record the error (if any) into the chain.
+//
+// normally, the chain keeps only the first error.  if collecting all errors,
+// then err is added to the collected errors.
+func (this *Chain) record(err error) {
+	if nil == err {
+		return
+	} else if !this.all {
+		this.Error = err
+		return
+	}
+	errs := []error{err}
+	if multi, ok := err.(*ValidationErrors); ok {
+		if multi == this.errs {
+			return // already recorded
+		}
+		errs = multi.Errors
+	}
+	if nil == this.errs {
+		this.errs = &ValidationErrors{}
+		this.Error = this.errs
+	}
+	for _, e := range errs {
+		if !this.errs.contains(e) {
+			this.errs.Errors = append(this.errs.Errors, e)
+		}
+	}
+}
+
+// record the error (if any) into the chain, adding the context.
+//
+// if the error is a collection of errors from a sub-chain, then those
+// already have the context, so they are recorded as is.
+func (this *Chain) recordf(err error, format string, args ...any) {
+	if nil == err {
+		return
+	} else if _, ok := err.(*ValidationErrors); ok && this.all {
+		this.record(err)
+	} else {
+		this.record(uerr.Chainf(err, format, args...))
+	}
+}
+
+// create a chain for the sub-section in the same mode as this
+func (this *Chain) sub(s *Section) *Chain {
+	return &Chain{Section: s, all: this.all}
+}
+
 // prefer Each()
 func (this *Chain) GetArray(key string, value **Array) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetArray(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetArray(key, value))
 	}
 	return this
 }
 
 // prefer EachIf()
 func (this *Chain) GetArrayIf(key string, value **Array) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetArrayIf(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetArrayIf(key, value))
 	}
 	return this
 }
@@ -152,8 +247,8 @@ func (this *Chain) IfSection(key string, visitor Visitor) *Chain {
 
 // deprecated
 func (this *Chain) GetSection(key string, value **Section) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetSection(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetSection(key, value))
 	}
 	return this
 }
@@ -169,35 +264,36 @@ func (this *Chain) GetSectionIf(key string, value **Section) *Chain {
 */
 
 func (this *Chain) GetChain(key string, value **Chain) *Chain {
-	if nil == this.Error {
+	if this.proceed() {
 		*value = this.Section.GetChain(key)
-		this.Error = (*value).Error
-		if nil == this.Error && nil == *value {
-			this.Error = fmt.Errorf("Missing '%s' section", this.ctx(key))
+		(*value).all = this.all
+		if nil == (*value).Error && nil == (*value).Section {
+			(*value).Error = fmt.Errorf("Missing '%s' section", this.ctx(key))
 		}
+		this.record((*value).Error)
 	}
 	return this
 }
 
 // does the config contain the named item?
 func (this *Chain) Contains(key string, yes *bool) *Chain {
-	if nil == this.Error {
+	if this.proceed() {
 		*yes = this.Section.Contains(key)
 	}
 	return this
 }
 
 func (this *Chain) GetBool(key string, value *bool) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetBool(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetBool(key, value))
 	}
 	return this
 }
 
 func (this *Chain) GetDuration(key string, value *time.Duration) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetDuration(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetDuration(key, value))
 	}
 	return this
 }
@@ -205,8 +301,8 @@ func (this *Chain) GetDuration(key string, value *time.Duration) *Chain {
 func (this *Chain) GetMillis(key string, value *int64, validators ...IntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetMillis(key, value, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetMillis(key, value, validators...))
 	}
 	return this
 }
@@ -216,8 +312,8 @@ func (this *Chain) GetFloat64(
 	value *float64,
 	validators ...FloatValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetFloat64(key, value, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetFloat64(key, value, validators...))
 	}
 	return this
 }
@@ -227,18 +323,19 @@ func (this *Chain) GetFloat32(
 	value *float32,
 	validators ...FloatValidator,
 ) *Chain {
-	var f64 float64
-	if nil == this.Error {
-		this.Error = this.Section.GetFloat64(key, &f64, validators...)
-	}
-	if nil == this.Error {
-		if math.MaxFloat32 < f64 {
-			this.Error = fmt.Errorf("value of %s too large for float32",
-				this.Section.ctx(key))
-
-		} else {
-			*value = float32(f64)
+	if this.proceed() {
+		var f64 float64
+		err := this.Section.GetFloat64(key, &f64, validators...)
+		if nil == err {
+			if math.MaxFloat32 < f64 {
+				err = fmt.Errorf("value of %s too large for float32",
+					this.Section.ctx(key))
+
+			} else {
+				*value = float32(f64)
+			}
 		}
+		this.record(err)
 	}
 	return this
 }
@@ -249,8 +346,8 @@ func (this *Chain) GetBitRate(
 	validators ...IntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetBitRate(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetBitRate(key, result, validators...))
 	}
 	return this
 }
@@ -261,8 +358,8 @@ func (this *Chain) GetByteSize(
 	validators ...IntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetByteSize(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetByteSize(key, result, validators...))
 	}
 	return this
 }
@@ -273,8 +370,8 @@ func (this *Chain) GetInt(
 	validators ...IntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetInt(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetInt(key, result, validators...))
 	}
 	return this
 }
@@ -285,8 +382,8 @@ func (this *Chain) GetInts(
 	validators ...IntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetInts(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetInts(key, result, validators...))
 	}
 	return this
 }
@@ -297,24 +394,24 @@ func (this *Chain) GetUInt(
 	validators ...UIntValidator,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetUInt(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetUInt(key, result, validators...))
 	}
 	return this
 }
 
 func (this *Chain) GetValidInt(key string, invalid int, value *int) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetValidInt(key, invalid, value)
+	if this.proceed() {
+		this.record(this.Section.GetValidInt(key, invalid, value))
 	}
 	return this
 }
 
 func (this *Chain) GetPosInt(key string, value *int) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetPosInt(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetPosInt(key, value))
 	}
 	return this
 }
@@ -325,22 +422,22 @@ func (this *Chain) GetCreateDir(
 	perm os.FileMode,
 ) *Chain {
 
-	if nil == this.Error {
-		this.Error = this.Section.GetCreateDir(key, value, perm)
+	if this.proceed() {
+		this.record(this.Section.GetCreateDir(key, value, perm))
 	}
 	return this
 }
 
 func (this *Chain) GetRegexp(key string, value **regexp.Regexp) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetRegexp(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetRegexp(key, value))
 	}
 	return this
 }
 
 func (this *Chain) GetRegexpIf(key string, value **regexp.Regexp) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetRegexpIf(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetRegexpIf(key, value))
 	}
 	return this
 }
@@ -350,8 +447,8 @@ func (this *Chain) GetUrl(
 	value **nurl.URL,
 	validators ...StringValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetUrl(key, value, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetUrl(key, value, validators...))
 	}
 	return this
 }
@@ -361,29 +458,113 @@ func (this *Chain) GetUrlIf(
 	value **nurl.URL,
 	validators ...StringValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetUrlIf(key, value, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetUrlIf(key, value, validators...))
+	}
+	return this
+}
+
+func (this *Chain) GetIP(key string, value *net.IP) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetIP(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetCIDR(key string, value **net.IPNet) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetCIDR(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetIPNets(key string, value *[]*net.IPNet) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetIPNets(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetHostPort(key, defaultPort string, value *string) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetHostPort(key, defaultPort, value))
+	}
+	return this
+}
+
+func (this *Chain) GetPortRange(key string, value *PortRange) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetPortRange(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetFileMode(key string, value *os.FileMode) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetFileMode(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetTimeOfDay(key string, value *TimeOfDay) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetTimeOfDay(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetTimeWindow(key string, value *TimeWindow) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetTimeWindow(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetWeekdays(key string, value *Weekdays) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetWeekdays(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetLocation(key string, value **time.Location) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetLocation(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetDscp(key string, value *byte) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetDscp(key, value))
+	}
+	return this
+}
+
+func (this *Chain) GetEnum(key string, value *string, choices ...string) *Chain {
+	if this.proceed() {
+		this.record(this.Section.GetEnum(key, value, choices...))
 	}
 	return this
 }
 
 func (this *Chain) GetPath(key string, value *string) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetPath(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetPath(key, value))
 	}
 	return this
 }
 
 func (this *Chain) GetValidPath(key string, value *string) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetValidPath(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetValidPath(key, value))
 	}
 	return this
 }
 
 // if key resolves to some value, then set value
 func (this *Chain) GetIt(key string, value *any) *Chain {
-	if nil == this.Error {
+	if this.proceed() {
 		this.Section.GetIt(key, value)
 	}
 	return this
@@ -391,8 +572,8 @@ func (this *Chain) GetIt(key string, value *any) *Chain {
 
 // if key resolves to some value, then set value, otherwise error
 func (this *Chain) GetValidIt(key string, value *any) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetValidIt(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetValidIt(key, value))
 	}
 	return this
 }
@@ -403,8 +584,8 @@ func (this *Chain) GetString(
 	result *string,
 	validators ...StringValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetString(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetString(key, result, validators...))
 	}
 	return this
 }
@@ -415,16 +596,16 @@ func (this *Chain) GetStrings(
 	result *[]string,
 	validators ...StringValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetStrings(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetStrings(key, result, validators...))
 	}
 	return this
 }
 
 // if key resolves to map[string]string, then set value
 func (this *Chain) GetStringMap(key string, value *map[string]string) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetStringMap(key, value)
+	if this.proceed() {
+		this.record(this.Section.GetStringMap(key, value))
 	}
 	return this
 }
@@ -435,21 +616,21 @@ func (this *Chain) GetRawString(
 	result *string,
 	validators ...StringValidator,
 ) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.GetRawString(key, result, validators...)
+	if this.proceed() {
+		this.record(this.Section.GetRawString(key, result, validators...))
 	}
 	return this
 }
 
 func (this *Chain) OnlyKeys(allowedKeys ...string) *Chain {
-	if nil == this.Error {
-		this.Error = this.Section.OnlyKeys(allowedKeys...)
+	if this.proceed() {
+		this.record(this.Section.OnlyKeys(allowedKeys...))
 	}
 	return this
 }
 
 func (this *Chain) WarnExtraKeys(allowedKeys ...string) *Chain {
-	if nil == this.Error {
+	if this.proceed() {
 		this.Section.WarnExtraKeys(allowedKeys...)
 	}
 	return this
@@ -463,17 +644,19 @@ func (this *Chain) FailExtraKeys(allowedKeys ...string) *Chain {
 }
 
 // end the accessor chain, detecting invalid config, returning active error (if any)
+//
+// if collecting all errors, then the error will be a *ValidationErrors
 func (this *Chain) Done() error {
-	if nil == this.Error {
-		this.Error = this.Section.OnlyKeys()
+	if this.proceed() {
+		this.record(this.Section.OnlyKeys())
 	}
 	return this.Error
 }
 
 // run the specified checking function as part of the chain
 func (this *Chain) ThenCheck(fn func() (err error)) *Chain {
-	if nil == this.Error {
-		this.Error = fn()
+	if this.proceed() {
+		this.record(fn())
 	}
 	return this
 }
@@ -488,12 +671,13 @@ func (this *Chain) Then(fn func()) *Chain {
 
 // if the section exists, add elements of it as properties to this section
 func (this *Chain) AddPropsIf(key string) *Chain {
-	if nil == this.Error {
+	if this.proceed() {
 		var props map[string]string
-		this.Error = this.Section.GetStringMap(key, &props)
-		if nil == this.Error && 0 != len(props) {
+		err := this.Section.GetStringMap(key, &props)
+		if nil == err && 0 != len(props) {
 			this.Section.AddProps(props)
 		}
+		this.record(err)
 	}
 	return this
 }
@@ -501,14 +685,14 @@ func (this *Chain) AddPropsIf(key string) *Chain {
 // run builder with specified sub-section if section exists
 func (this *Chain) If(key string, builder ChainVisitor) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var s *Section
-		this.Error = this.Section.GetSectionIf(key, &s)
-		if nil == this.Error && nil != s {
-			err := builder(s.Chain())
-			if err != nil {
-				this.Error = uerr.Chainf(err, "Unable to build '%s'", this.ctx(key))
-			}
+		err := this.Section.GetSectionIf(key, &s)
+		if nil == err && nil != s {
+			err = builder(this.sub(s))
+			this.recordf(err, "Unable to build '%s'", this.ctx(key))
+		} else {
+			this.record(err)
 		}
 	}
 	return this
@@ -517,14 +701,12 @@ func (this *Chain) If(key string, builder ChainVisitor) *Chain {
 // run builder with specified sub-section if section exists, fail otherwise
 func (this *Chain) Must(key string, builder ChainVisitor) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var chain *Chain
 		this.GetChain(key, &chain)
-		if nil == this.Error {
+		if nil == chain.Error {
 			err := builder(chain)
-			if err != nil {
-				this.Error = uerr.Chainf(err, "Unable to build '%s'", this.ctx(key))
-			}
+			this.recordf(err, "Unable to build '%s'", this.ctx(key))
 		}
 	}
 	return this
@@ -532,16 +714,16 @@ func (this *Chain) Must(key string, builder ChainVisitor) *Chain {
 
 // if any of the keys are present, call handler
 func (this *Chain) IfHasKeysIn(f ChainVisitor, keys ...string) *Chain {
-	if nil == this.Error && this.Section.AnyKeysIn(keys...) {
-		this.Error = f(this)
+	if this.proceed() && this.Section.AnyKeysIn(keys...) {
+		this.record(f(this))
 	}
 	return this
 }
 
 // if any of the keys are present, call handler
 func (this *Chain) IfHasKeysMatching(f ChainVisitor, r *regexp.Regexp) *Chain {
-	if nil == this.Error && this.Section.AnyKeysMatch(r) {
-		this.Error = f(this)
+	if this.proceed() && this.Section.AnyKeysMatch(r) {
+		this.record(f(this))
 	}
 	return this
 }
@@ -549,14 +731,13 @@ func (this *Chain) IfHasKeysMatching(f ChainVisitor, r *regexp.Regexp) *Chain {
 // run builder against each sub section in named array
 func (this *Chain) Each(key string, builder ChainVisitor) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var arr *Array
-		this.Error = this.Section.GetArray(key, &arr)
-		if nil == this.Error {
-			this.Error = arr.Each(
-				func(s *Section) error {
-					return builder(s.Chain())
-				})
+		err := this.Section.GetArray(key, &arr)
+		if err != nil {
+			this.record(err)
+		} else {
+			this.each(arr, builder)
 		}
 	}
 	return this
@@ -565,19 +746,34 @@ func (this *Chain) Each(key string, builder ChainVisitor) *Chain {
 // run builder against each sub section in named array, if array exists
 func (this *Chain) EachIf(key string, builder ChainVisitor) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var arr *Array
-		this.Error = this.Section.GetArrayIf(key, &arr)
-		if nil == this.Error && nil != arr {
-			this.Error = arr.Each(
-				func(s *Section) error {
-					return builder(s.Chain())
-				})
+		err := this.Section.GetArrayIf(key, &arr)
+		if err != nil {
+			this.record(err)
+		} else if nil != arr {
+			this.each(arr, builder)
 		}
 	}
 	return this
 }
 
+// run builder against each sub section in array.  unless collecting all
+// errors, stop at the first error.
+func (this *Chain) each(arr *Array, builder ChainVisitor) {
+	if !this.all {
+		this.record(arr.Each(
+			func(s *Section) error {
+				return builder(s.Chain())
+			}))
+		return
+	}
+	for i := 0; i < arr.Len(); i++ {
+		err := builder(this.sub(arr.Get(i)))
+		this.recordf(err, "section %d%s", i, arr.src(i).suffix())
+	}
+}
+
 // build value from named config section if it exists
 //
 // if builder returns nil, then no assignment is made to value
@@ -586,14 +782,15 @@ func (this *Chain) EachIf(key string, builder ChainVisitor) *Chain {
 // thing to be built is a pointer, then it must be the addres of the pointer.
 func (this *Chain) BuildIf(key string, value any, builder Builder) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var s *Section
-		this.Error = this.Section.GetSectionIf(key, &s)
-		if nil == this.Error && nil != s {
-			chain := s.Chain()
+		err := this.Section.GetSectionIf(key, &s)
+		if nil == err && nil != s {
+			chain := this.sub(s)
 			chain.Build(value, builder)
-			this.Error = chain.Error
+			err = chain.Error
 		}
+		this.record(err)
 	}
 	return this
 }
@@ -610,11 +807,13 @@ func (this *Chain) BuildFrom(
 	builder Builder,
 ) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var chain *Chain
 		this.GetChain(key, &chain)
-		chain.Build(value, builder)
-		this.Error = chain.Error
+		if nil == chain.Error {
+			chain.Build(value, builder)
+			this.record(chain.Error)
+		}
 	}
 	return this
 }
@@ -627,12 +826,12 @@ func (this *Chain) BuildFrom(
 // thing to be built is a pointer, then it must be the addres of the pointer.
 func (this *Chain) Build(value any, builder Builder) *Chain {
 
-	if nil == this.Error {
-		var it any
-		it, this.Error = builder(this)
-		if nil == this.Error && nil != it {
-			this.Error = Assign(this.Section.Context, value, it)
+	if this.proceed() {
+		it, err := builder(this)
+		if nil == err && nil != it {
+			err = Assign(this.Section.Context, value, it)
 		}
+		this.record(err)
 	}
 	return this
 }
@@ -640,8 +839,8 @@ func (this *Chain) Build(value any, builder Builder) *Chain {
 // Construct target from current config section.
 func (this *Chain) Construct(target Constructable) *Chain {
 
-	if nil == this.Error {
-		this.Error = target.FromConfig(this)
+	if this.proceed() {
+		this.record(target.FromConfig(this))
 	}
 	return this
 }
@@ -649,11 +848,13 @@ func (this *Chain) Construct(target Constructable) *Chain {
 // Construct target from named config sub-section.
 func (this *Chain) ConstructFrom(key string, target Constructable) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var chain *Chain
 		this.GetChain(key, &chain)
-		chain.Construct(target)
-		this.Error = chain.Error
+		if nil == chain.Error {
+			chain.Construct(target)
+			this.record(chain.Error)
+		}
 	}
 	return this
 }
@@ -661,14 +862,15 @@ func (this *Chain) ConstructFrom(key string, target Constructable) *Chain {
 // Construct target from named config sub-section if sub-section exists.
 func (this *Chain) ConstructIf(key string, target Constructable) *Chain {
 
-	if nil == this.Error {
+	if this.proceed() {
 		var s *Section
-		this.Error = this.Section.GetSectionIf(key, &s)
-		if nil == this.Error && nil != s {
-			chain := s.Chain()
+		err := this.Section.GetSectionIf(key, &s)
+		if nil == err && nil != s {
+			chain := this.sub(s)
 			chain.Construct(target)
-			this.Error = chain.Error
+			err = chain.Error
 		}
+		this.record(err)
 	}
 	return this
 }
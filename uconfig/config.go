@@ -56,6 +56,33 @@
 //
 // All golang text template rules apply.
 //
+// # Template Functions
+//
+// In addition to the text/template builtins (if, eq, printf, ...), these
+// functions are available within {{...}}:
+//   - prop "key" "dflt"      - property value, or dflt if property not set
+//   - default "dflt" val     - dflt if val is blank, zero or false
+//   - empty val              - true if val is blank, zero or false
+//   - env "NAME" "dflt"      - ENV var value, or dflt if not set or blank
+//   - upper, lower, trim     - change case, trim whitespace
+//   - trimPrefix, trimSuffix - trimPrefix "prefix" val
+//   - replace "old" "new" val
+//   - split "sep" val, join "sep" list
+//   - add, sub, mul, div, mod - integer arithmetic: add .basePort 10
+//   - hostname, shortHostname - name of this host
+//   - resolveIp "host"       - IP of host
+//   - readFile "/path"       - trimmed contents of a small file
+//   - b64enc, b64dec         - base64 conversion
+//   - ternary "yes" "no" cond - yes if cond true, otherwise no
+//
+// Programs may add their own functions with AddFuncs.
+//
+//	properties:
+//	  basePort:   8000
+//	  adminPort:  "{{add .basePort 1}}"
+//	  logLevel:   '{{env "LOG_LEVEL" "info" | upper}}'
+//	  isProd:     '{{if eq .env "prod"}}true{{else}}false{{end}}'
+//
 // The following properties are automatically added:
 //   - homeDir        - the home dir of the user
 //   - thisUser       - the username of the user
@@ -71,10 +98,38 @@
 //
 // include_:        /path/to/file.yml
 //
+// Includes may also be fetched from a config server:
+//
+// include_:        https://config.example.com/base.yml
+//
+// RemoteClient is used to fetch these, and the last good copy is kept in
+// RemoteCacheD (if set) so that config can be loaded even if the config
+// server is unavailable.  When watching config, remote includes are
+// revalidated (using ETag / If-None-Match) every RemoteRevalidate.
+//
+// # Encrypted Values
+//
+// Secrets such as credentials may be encrypted so that config containing them
+// can be safely kept in a config repository:
+//
+//	dbPass:         ENC[aes256gcm,d2h5IGFyZSB5b3UgcmVhZGluZyB0aGlzPw==]
+//
+// The key is set by SetSecretKey or LoadSecretKey (see uboot -config-secret-key),
+// or else from the UCONFIG_KEY_FILE or UCONFIG_KEY env vars.  NewSection fails
+// if any encrypted value cannot be decrypted.  Values are decrypted when they
+// are accessed, so they remain encrypted in dumps (Log, DumpProps, snapshots).
+// Use Encrypt or Editor.Encrypt to produce encrypted values.
+//
 // # Sections
 //
 // Each component has a config section.  A config section may contain
 // sub-sections and arrays of sub-sections.
+//
+// # Errors
+//
+// When the YAML a value came from is known, errors about the value include
+// the source position (file.yml:42:7) of the value, even if the value came
+// from an included file.  YAML not loaded from a file is reported as <inline>.
 package uconfig
 
 import (
@@ -131,7 +186,9 @@ type Section struct {
 	expander  expander_
 	section   map[string]any
 	trackKeys map[string]struct{} // keys accessed
+	help      *Help               // if set, record what getters know here
 	watch     *Watch
+	src       *srcPos_ // where section came from, if known
 }
 
 // create a new Section from nil, /path/to/yaml/file, YAML string,
@@ -142,7 +199,14 @@ func NewSection(it any) (rv *Section, err error) {
 		expander: newExpander(watch),
 		watch:    watch,
 	}
-	return tmp.NewChild(it)
+	rv, err = tmp.NewChild(it)
+	if nil == err {
+		err = rv.checkEncrypted()
+		if err != nil {
+			rv = nil
+		}
+	}
+	return
 }
 
 // create a new Section as a child of this one from nil, /path/to/yaml/file,
@@ -152,7 +216,7 @@ func (this *Section) NewChild(it any) (rv *Section, err error) {
 		expander: this.expander.clone(),
 		watch:    this.watch,
 	}
-	rv.section, err = rv.getMap(it)
+	rv.section, rv.src, err = rv.getMap(it, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +234,8 @@ func (this *Section) Watch(
 	this.watch.Start(period, onChange, onError)
 }
 
-// dump out the config section as a map, resolving all properties
+// dump out the config section as a map, resolving all properties.  any
+// encrypted values are left encrypted.
 func (this *Section) AsResolvedMap() (rv map[string]any) {
 
 	rv = make(map[string]any)
@@ -183,7 +248,7 @@ func (this *Section) AsResolvedMap() (rv map[string]any) {
 			this.resolveArray(v)
 			rv[k] = v
 		case string:
-			rv[k] = this.expander.expand(v)
+			rv[k] = this.expander.resolve(v)
 		default:
 			rv[k] = it
 		}
@@ -199,7 +264,7 @@ func (this *Section) resolveMap(m map[string]any) {
 		case []any:
 			this.resolveArray(v)
 		case string:
-			m[k] = this.expander.expand(v)
+			m[k] = this.expander.resolve(v)
 		}
 	}
 }
@@ -212,13 +277,15 @@ func (this *Section) resolveArray(a []any) {
 		case []any:
 			this.resolveArray(v)
 		case string:
-			a[i] = this.expander.expand(v)
+			a[i] = this.expander.resolve(v)
 		}
 	}
 }
 
 // allow a map to be enriched by including another from file
-func (this *Section) mapInclude(in map[string]any) (err error) {
+//
+// src, if provided, is updated with the source of included values
+func (this *Section) mapInclude(in map[string]any, src *srcPos_) (err error) {
 
 	include, found := in[include_]
 	if !found {
@@ -229,9 +296,9 @@ func (this *Section) mapInclude(in map[string]any) (err error) {
 		return
 	}
 	var included map[string]any
-	err = YamlLoad(includeF, &included)
+	includedSrc, err := yamlLoadSrc(includeF, &included)
 	if err != nil {
-		return
+		return uerr.Chainf(err, "include_%s", src.key(include_).suffix())
 	}
 	this.watch.Add(includeF)
 
@@ -243,10 +310,13 @@ func (this *Section) mapInclude(in map[string]any) (err error) {
 		_, found = in[k]
 		if !found {
 			in[k] = v
+			if nil != src {
+				src.setKey(k, includedSrc.key(k))
+			}
 		}
 	}
 	if recur {
-		err = this.mapInclude(in)
+		err = this.mapInclude(in, src)
 	}
 	return
 }
@@ -261,20 +331,39 @@ func (this *Section) mapInclude(in map[string]any) (err error) {
 //
 // if it is a string, it it looked up as a filename.  if no such file, then
 // it is parsed as YAML.  otherwise, the file contents are parsed as YAML.
-func (this *Section) getMap(it any) (rv map[string]any, err error) {
-
-	rv, err = this.toMap(it)
+//
+// src is the source of it, if known.  if it is parsed, then the returned
+// source will be from that.
+func (this *Section) getMap(
+	it any,
+	src *srcPos_,
+) (
+	rv map[string]any,
+	rvSrc *srcPos_,
+	err error,
+) {
+	rv, rvSrc, err = this.toMap(it, src)
 	if err != nil {
 		err = uerr.Chainf(err, this.Context)
 		return
 	} else if 0 != len(rv) {
-		err = this.mapInclude(rv)
+		if nil == rvSrc {
+			rvSrc = &srcPos_{}
+		}
+		err = this.mapInclude(rv, rvSrc)
 	}
 	return
 }
 
-func (this *Section) toMap(it any) (rv map[string]any, err error) {
-
+func (this *Section) toMap(
+	it any,
+	src *srcPos_,
+) (
+	rv map[string]any,
+	rvSrc *srcPos_,
+	err error,
+) {
+	rvSrc = src
 	if nil == it {
 		rv = make(map[string]any)
 		return
@@ -284,18 +373,26 @@ func (this *Section) toMap(it any) (rv map[string]any, err error) {
 		rv = val
 	case []byte:
 		err = yaml.Unmarshal(val, &rv)
+		if nil == err {
+			rvSrc = srcFromYaml("", val)
+		}
 	case string:
 		if 0 == len(val) { // empty string: treat same as nil
 			rv = make(map[string]any)
 		} else {
-			_, err = os.Stat(val)
+			if !isRemote(val) {
+				_, err = os.Stat(val)
+			}
 			if nil == err {
-				err = YamlLoad(val, &rv)
+				rvSrc, err = yamlLoadSrc(val, &rv)
 				if nil == err {
 					this.watch.Add(val)
 				}
 			} else {
 				err = yaml.Unmarshal([]byte(val), &rv)
+				if nil == err {
+					rvSrc = srcFromYaml("", []byte(val))
+				}
 			}
 		}
 	case map[any]any:
@@ -331,7 +428,14 @@ func (this *Section) NameContext(key string) {
 	this.Context = this.Context + "." + key
 }
 
+// the key path of key in this section, for use in error messages.  if the
+// source of the value is known, then that is included.
 func (this *Section) ctx(key string) string {
+	return this.path(key) + this.src.key(key).suffix()
+}
+
+// the key path of key in this section
+func (this *Section) path(key string) string {
 	if 0 == len(this.Context) {
 		return key
 	} else {
@@ -339,9 +443,25 @@ func (this *Section) ctx(key string) string {
 	}
 }
 
+// describe the keys, including their sources if known
+func (this *Section) describeKeys(keys []string) string {
+	described := make([]string, len(keys))
+	for i, k := range keys {
+		described[i] = k + this.src.key(k).suffix()
+	}
+	return "[" + strings.Join(described, ", ") + "]"
+}
+
+// describe this section, including its source if known
+func (this *Section) describe() string {
+	return this.Context + this.src.suffix()
+}
+
 // load the YAML file into target, which may be a ptr to map or ptr to struct
+//
+// file may also be a http or https URL.  see RemoteClient, RemoteCacheD.
 func YamlLoad(file string, target any) (err error) {
-	content, err := os.ReadFile(file)
+	content, err := readConfig(file)
 	if err != nil {
 		return err
 	}
@@ -418,7 +538,8 @@ func (this *Section) OnlyKeys(allowedKeys ...string) (err error) {
 	}
 	extra := this.ExtraKeys(allowedKeys)
 	if 0 != len(extra) {
-		err = fmt.Errorf("section %s has extra keys: %v", this.Context, extra)
+		err = fmt.Errorf("section %s has extra keys: %s",
+			this.describe(), this.describeKeys(extra))
 	}
 	return
 }
@@ -431,7 +552,8 @@ func (this *Section) WarnExtraKeys(allowedKeys ...string) {
 	}
 	extra := this.ExtraKeys(allowedKeys)
 	if 0 != len(extra) {
-		ulog.Warnf("section %s has extra keys: %v", this.Context, extra)
+		ulog.Warnf("section %s has extra keys: %s",
+			this.describe(), this.describeKeys(extra))
 	}
 }
 
@@ -443,7 +565,8 @@ func (this *Section) FailExtraKeys(allowedKeys ...string) {
 	}
 	extra := this.ExtraKeys(allowedKeys)
 	if 0 != len(extra) {
-		ulog.Fatalf("section %s has extra keys: %v", this.Context, extra)
+		ulog.Fatalf("section %s has extra keys: %s",
+			this.describe(), this.describeKeys(extra))
 	}
 }
 
@@ -522,12 +645,12 @@ func (this *Section) AddProps(props map[string]string) {
 	}
 }
 
-// get the property
+// get the property, decrypting it if encrypted
 func (this *Section) Prop(key string) string {
-	return this.expander.Get(key)
+	return decryptQuietly(this.expander.Get(key))
 }
 
-// get the property map
+// get the property map.  any encrypted values are left encrypted.
 func (this *Section) Props() map[string]string {
 	return this.expander.mapping
 }
@@ -549,6 +672,20 @@ func (this *Section) track(key string) {
 	this.trackKeys[key] = struct{}{}
 }
 
+// if gathering help (see Help.Chain), then record the choices for key
+func (this *Section) trackChoices(key string, choices []string) {
+	if nil == this.help {
+		return
+	}
+	item := this.help.GetHelp(key)
+	if nil == item {
+		item = this.help.NewItem(key, "string", "")
+	}
+	if !item.Contains("choices") {
+		item.Choices(choices...)
+	}
+}
+
 // Get (using JSON conversion) the specified section into dst (a &struct).
 // If key not found, dst is unmodified.
 // May not be super performant, but ok for config type stuff.
@@ -558,7 +695,7 @@ func (this *Section) GetStruct(key string, dst any) (err error) {
 	if !ok {
 		return
 	}
-	m, err := this.getMap(it)
+	m, _, err := this.getMap(it, this.src.key(key))
 	if err != nil {
 		return uerr.Chainf(err, "GetStruct: value of '%s'", this.ctx(key))
 	}
@@ -566,7 +703,11 @@ func (this *Section) GetStruct(key string, dst any) (err error) {
 	if err != nil {
 		return uerr.Chainf(err, "GetStruct: value of '%s'", this.ctx(key))
 	}
-	return json.Unmarshal(bytes, dst)
+	err = json.Unmarshal(bytes, dst)
+	if err != nil {
+		err = uerr.Chainf(err, "GetStruct: value of '%s'", this.ctx(key))
+	}
+	return
 }
 
 // add any properties for this section in
@@ -577,9 +718,10 @@ func (this *Section) addProps() (err error) {
 		return
 	}
 	var mit map[string]any
-	mit, err = this.toMap(it)
+	mit, _, err = this.toMap(it, nil)
 	if err != nil {
-		return uerr.Chainf(err, "Unable to get '%s", PROPS)
+		return uerr.Chainf(err, "Unable to get '%s'%s", PROPS,
+			this.src.key(PROPS).suffix())
 	} else if 0 == len(mit) {
 		return
 	}
@@ -607,14 +749,15 @@ func (this *Section) GetSectionIf(key string, val **Section) (err error) {
 	this.track(key)
 	it, ok := this.section[key]
 	if ok {
-		m, err := this.getMap(it)
+		m, src, err := this.getMap(it, this.src.key(key))
 		if err != nil {
 			err = uerr.Chainf(err, "GetSection: value of '%s'", this.ctx(key))
 		} else {
 			rv := &Section{
-				Context:  this.ctx(key),
+				Context:  this.path(key),
 				expander: this.expander.clone(),
 				section:  m,
+				src:      src,
 			}
 			err = rv.addProps()
 			if nil == err {
@@ -648,46 +791,54 @@ func (this *Section) GetArrayIf(key string, result **Array) (err error) {
 			this.ctx(key))
 	}
 	rv := &Array{
-		Context:  this.ctx(key),
+		Context:  this.path(key),
 		expander: this.expander.clone(),
 		sections: make([]map[string]any, 0, len(raw)),
+		srcs:     make([]*srcPos_, 0, len(raw)),
 	}
 
 	//
 	// convert to maps and expand includes
 	//
+	arraySrc := this.src.key(key)
 	isInclude := false
 	var children []map[string]any
+	var childSrcs []*srcPos_
 	for i, v := range raw {
 		var child map[string]any
-		child, err = this.toMap(v)
+		var childSrc *srcPos_
+		child, childSrc, err = this.toMap(v, arraySrc.item(i))
 		if err != nil {
-			return uerr.Chainf(err, "parsing config: value %d in %s array",
-				i, this.ctx(key))
+			return uerr.Chainf(err, "parsing config: value %d in %s array%s",
+				i, this.path(key), arraySrc.item(i).suffix())
 		} else if 0 == len(child) {
 			continue
 		}
-		isInclude, err = this.arrayEntryInclude(child, &children)
+		isInclude, err = this.arrayEntryInclude(child, childSrc,
+			&children, &childSrcs)
 		if err != nil {
-			err = uerr.Chainf(err, "parsing config: value %d in %s array",
-				i, this.ctx(key))
+			err = uerr.Chainf(err, "parsing config: value %d in %s array%s",
+				i, this.path(key), arraySrc.item(i).suffix())
 			return
 		} else if !isInclude {
 			children = append(children, child)
+			childSrcs = append(childSrcs, childSrc)
 		}
 	}
 
 	//
 	// convert to section maps
 	//
-	for _, v := range children {
+	for i, v := range children {
 		var section map[string]any
-		section, err = this.getMap(v)
+		var src *srcPos_
+		section, src, err = this.getMap(v, childSrcs[i])
 		if err != nil {
-			return uerr.Chainf(err, "parsing config: value in %s array",
-				this.ctx(key))
+			return uerr.Chainf(err, "parsing config: value in %s array%s",
+				this.path(key), childSrcs[i].suffix())
 		}
 		rv.sections = append(rv.sections, section)
+		rv.srcs = append(rv.srcs, src)
 	}
 	*result = rv
 	return
@@ -700,9 +851,14 @@ func (this *Section) GetArrayIf(key string, result **Array) (err error) {
 //
 // if a child has more than one key/value mapping, and one of them is
 // "include_", then that is included in the child.  This is done elsewhere.
+//
+// src is the source of entry, if known, and addSrcs is updated in parallel
+// with addTo with the sources of the added children.
 func (this *Section) arrayEntryInclude(
 	entry map[string]any,
+	src *srcPos_,
 	addTo *[]map[string]any,
+	addSrcs *[]*srcPos_,
 ) (isInclude bool, err error) {
 
 	if 1 != len(entry) {
@@ -722,24 +878,28 @@ func (this *Section) arrayEntryInclude(
 	includeF = this.Expand(includeF)
 
 	var included []map[string]any
-	err = YamlLoad(includeF, &included)
+	includedSrc, err := yamlLoadSrc(includeF, &included)
 	if err != nil {
+		err = uerr.Chainf(err, "include_%s", src.key(include_).suffix())
 		return
 	}
 	this.watch.Add(includeF)
 
-	for _, v := range included {
+	for i, v := range included {
+		vSrc := includedSrc.item(i)
 		_, found = v[include_]
 		if found {
 			wasInclude := false
-			wasInclude, err = this.arrayEntryInclude(v, addTo)
+			wasInclude, err = this.arrayEntryInclude(v, vSrc, addTo, addSrcs)
 			if err != nil {
 				return
 			} else if !wasInclude {
 				*addTo = append(*addTo, v)
+				*addSrcs = append(*addSrcs, vSrc)
 			}
 		} else {
 			*addTo = append(*addTo, v)
+			*addSrcs = append(*addSrcs, vSrc)
 		}
 	}
 	return
@@ -765,6 +925,9 @@ func (this *Section) GetBool(key string, result *bool) (err error) {
 			*result = actual
 		case string:
 			*result, err = strconv.ParseBool(actual)
+			if err != nil {
+				err = uerr.Chainf(err, "parsing config: %s", this.ctx(key))
+			}
 		default:
 			err = fmt.Errorf("parsing config: value of %s not convertable "+
 				" to bool.  Is %s", this.ctx(key), reflect.TypeOf(it))
@@ -879,7 +1042,8 @@ func (this *Section) GetMillis(
 	case int64:
 		parsed = raw
 	default:
-		err = errors.New("Millis must be a duration string or an int")
+		err = fmt.Errorf("parsing config: value of %s must be a duration "+
+			"string or an int", this.ctx(key))
 		return
 	}
 
@@ -914,6 +1078,9 @@ func (this *Section) GetFloat64(
 		parsed = float64(raw)
 	case string:
 		parsed, err = Float64FromSiString(this.expander.expand(raw))
+		if err != nil {
+			err = uerr.Chainf(err, this.ctx(key))
+		}
 	default:
 		err = fmt.Errorf("parsing config: value of %s not convertable "+
 			" to float64.  Is %s", this.ctx(key), reflect.TypeOf(it))
@@ -1441,7 +1608,7 @@ func (this *Section) GetStringMap(key string, val *map[string]string) (err error
 	it, found := this.section[key]
 	if found {
 		var mit map[string]any
-		mit, err = this.getMap(it)
+		mit, _, err = this.getMap(it, this.src.key(key))
 		if err != nil {
 			return uerr.Chainf(err, "GetStringMap: value of '%s'", this.ctx(key))
 		}
@@ -1670,6 +1837,15 @@ func (this *Section) Chain() *Chain {
 	return &Chain{Section: this}
 }
 
+// Enable chaining of config calls, collecting all errors instead of stopping
+// at the first.  Chain.Done will return a *ValidationErrors if there are any.
+func (this *Section) ChainAll() *Chain {
+	if nil == this {
+		panic("chaining off of nil section")
+	}
+	return &Chain{Section: this, all: true}
+}
+
 // get named subsection as a chain
 func (this *Section) GetChain(key string) (rv *Chain) {
 	this.track(key)
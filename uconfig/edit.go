@@ -0,0 +1,807 @@
+package uconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tredeske/u/uerr"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+const (
+	ErrEditAlias    = uerr.Const("config edit path goes through a YAML alias")
+	ErrEditNotFound = uerr.Const("config edit path not found")
+)
+
+// Editor enables programmatic editing of a YAML config file, such as to
+// persist changes made at runtime back into the operator maintained config,
+// while preserving comments, key order, anchors and formatting.
+//
+// Paths are '.' separated keys.  When the path traverses an array, the path
+// element may be an index, or a key=value selector to choose the array entry
+// that has that value for key:
+//
+//	ed, err := uconfig.EditFile("config/prog.yml")
+//	...
+//	err = ed.Set("components.name=sftpServer.disabled", true)
+//	err = ed.Set("components.name=httpServer.config.port", 8443)
+//	_, err = ed.Delete("properties.oldProp")
+//	err = ed.Save()
+//
+// Where possible, edits change only the affected lines.  When that is not
+// possible, the document is re-rendered, which keeps comments, key order and
+// anchors, but may change indentation and alignment.
+type Editor struct {
+	File    string // where Save writes to
+	content []byte
+}
+
+// load file for editing
+func EditFile(file string) (rv *Editor, err error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	rv, err = EditYaml(content)
+	if err != nil {
+		err = fmt.Errorf("editing %s: %w", file, err)
+		return
+	}
+	rv.File = file
+	return
+}
+
+// prepare YAML content for editing
+func EditYaml(content []byte) (rv *Editor, err error) {
+	_, err = parseEditDoc(content)
+	if err != nil {
+		return
+	}
+	rv = &Editor{content: append([]byte(nil), content...)}
+	return
+}
+
+// get the current YAML content
+func (this *Editor) Bytes() []byte {
+	return this.content
+}
+
+// get the value at path, if it exists.  aliases and merges are resolved.
+func (this *Editor) Get(path string) (value any, found bool, err error) {
+	doc, err := parseEditDoc(this.content)
+	if err != nil {
+		return
+	}
+	err = doc.Decode(&value)
+	if err != nil {
+		return
+	}
+	for _, seg := range editPath(path) {
+		switch v := value.(type) {
+		case map[string]any:
+			value, found = v[seg]
+		case []any:
+			value, found = nil, false
+			if eq := strings.IndexByte(seg, '='); -1 != eq {
+				for _, item := range v {
+					m, ok := item.(map[string]any)
+					if ok && seg[eq+1:] == fmt.Sprint(m[seg[:eq]]) {
+						value, found = item, true
+						break
+					}
+				}
+			} else if i, err := strconv.Atoi(seg); nil == err &&
+				0 <= i && i < len(v) {
+				value, found = v[i], true
+			}
+		default:
+			found = false
+		}
+		if !found {
+			return nil, false, nil
+		}
+	}
+	found = true
+	return
+}
+
+// set the value at path, creating any missing mappings along the way.
+//
+// to append to an array, use the length of the array as the index.
+func (this *Editor) Set(path string, value any) (err error) {
+	segs := editPath(path)
+	if 0 == len(segs) {
+		return errors.New("no config edit path")
+	}
+	var valueN yaml3.Node
+	err = valueN.Encode(value)
+	if err != nil {
+		return
+	}
+	return this.edit(path, func(doc *yaml3.Node, lines []string) (
+		patched []string, err error,
+	) {
+		steps, rest, err := editWalk(doc.Content[0], segs)
+		if err != nil {
+			return
+		}
+		unit := editIndent(doc.Content[0])
+		if 0 == len(rest) {
+			last := steps[len(steps)-1]
+			patched = editReplaceText(lines, last, &valueN, unit)
+			editReplaceNode(last, &valueN)
+		} else {
+			var container *yaml3.Node
+			if 0 == len(steps) {
+				container = doc.Content[0]
+			} else {
+				container = steps[len(steps)-1].node
+			}
+			var key *yaml3.Node
+			var added *yaml3.Node
+			key, added, err = editNested(container, rest, &valueN)
+			if err != nil {
+				return
+			}
+			patched = editInsertText(lines, container, key, added, unit)
+			if nil != key {
+				container.Content = append(container.Content, key, added)
+			} else {
+				container.Content = append(container.Content, added)
+			}
+		}
+		return
+	})
+}
+
+// delete the value at path, returning whether it was found
+func (this *Editor) Delete(path string) (found bool, err error) {
+	segs := editPath(path)
+	if 0 == len(segs) {
+		return false, errors.New("no config edit path")
+	}
+	err = this.edit(path, func(doc *yaml3.Node, lines []string) (
+		patched []string, err error,
+	) {
+		steps, rest, err := editWalk(doc.Content[0], segs)
+		if err != nil {
+			return
+		} else if 0 != len(rest) {
+			return nil, ErrEditNotFound
+		}
+		last := steps[len(steps)-1]
+		patched = editDeleteText(lines, last)
+		if nil != last.key {
+			last.parent.Content = append(last.parent.Content[:last.pos],
+				last.parent.Content[last.pos+2:]...)
+		} else {
+			last.parent.Content = append(last.parent.Content[:last.pos],
+				last.parent.Content[last.pos+1:]...)
+		}
+		return
+	})
+	if errors.Is(err, ErrEditNotFound) {
+		err = nil
+	} else if nil == err {
+		found = true
+	}
+	return
+}
+
+// write the content to File, atomically
+func (this *Editor) Save() (err error) {
+	if 0 == len(this.File) {
+		return errors.New("no file to save config edits to")
+	}
+	return this.SaveAs(this.File)
+}
+
+// write the content to file, atomically, keeping the permissions of any
+// existing file
+func (this *Editor) SaveAs(file string) (err error) {
+	perm := os.FileMode(0664)
+	if stat, err := os.Stat(file); nil == err {
+		perm = stat.Mode().Perm()
+	}
+	return writeAtomic(file, this.content, perm)
+}
+
+// perform the edit.
+//
+// editF changes the node tree of the document, and also returns the patched
+// lines of the document, or nil if a patch is not possible.  if the patched
+// lines are not equivalent to the edited tree, then the edited tree is
+// rendered instead.
+func (this *Editor) edit(
+	path string,
+	editF func(doc *yaml3.Node, lines []string) (patched []string, err error),
+) (err error) {
+	doc, err := parseEditDoc(this.content)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(string(this.content), "\n")
+	patched, err := editF(doc, lines)
+	if err != nil {
+		return fmt.Errorf("editing %s: %w", path, err)
+	}
+
+	var expect any
+	err = doc.Decode(&expect)
+	if err != nil {
+		return
+	}
+	if nil != patched {
+		content := []byte(strings.Join(patched, "\n"))
+		var got any
+		if nil == yaml3.Unmarshal(content, &got) && reflect.DeepEqual(expect, got) {
+			this.content = content
+			return
+		}
+	}
+
+	var buff bytes.Buffer
+	enc := yaml3.NewEncoder(&buff)
+	enc.SetIndent(editIndent(doc.Content[0]))
+	err = enc.Encode(doc)
+	if err != nil {
+		return
+	}
+	err = enc.Close()
+	if err != nil {
+		return
+	}
+	this.content = buff.Bytes()
+	return
+}
+
+// parse the content, ensuring it is a mapping
+func parseEditDoc(content []byte) (doc *yaml3.Node, err error) {
+	doc = &yaml3.Node{}
+	err = yaml3.Unmarshal(content, doc)
+	if err != nil {
+		return
+	}
+	if 0 == len(doc.Content) {
+		doc.Kind = yaml3.DocumentNode
+		doc.Content = []*yaml3.Node{{Kind: yaml3.MappingNode, Tag: "!!map"}}
+	} else if yaml3.MappingNode != doc.Content[0].Kind {
+		err = errors.New("config to edit is not a YAML mapping")
+	}
+	return
+}
+
+func editPath(path string) (rv []string) {
+	if 0 == len(path) {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// where a node is in the tree
+type editStep_ struct {
+	parent *yaml3.Node // mapping or sequence
+	key    *yaml3.Node // key node, if parent is mapping
+	node   *yaml3.Node // value node
+	pos    int         // position of key (or node if sequence) in parent
+}
+
+// follow the path as far as possible, returning the steps found, and the
+// remaining segments of the path that are not present.  segments that can
+// never be present, such as an array selector with no match, produce an
+// error chained to ErrEditNotFound.
+func editWalk(
+	n *yaml3.Node,
+	segs []string,
+) (
+	steps []editStep_,
+	rest []string,
+	err error,
+) {
+	for i, seg := range segs {
+		if yaml3.AliasNode == n.Kind {
+			return nil, nil, ErrEditAlias
+		}
+		step := editStep_{parent: n, pos: -1}
+		switch n.Kind {
+		case yaml3.MappingNode:
+			for j := 0; j+1 < len(n.Content); j += 2 {
+				if seg == n.Content[j].Value {
+					step.key = n.Content[j]
+					step.node = n.Content[j+1]
+					step.pos = j
+					break
+				}
+			}
+		case yaml3.SequenceNode:
+			if eq := strings.IndexByte(seg, '='); -1 != eq {
+				k, v := seg[:eq], seg[eq+1:]
+				for j, item := range n.Content {
+					if yaml3.MappingNode != item.Kind {
+						continue
+					}
+					for m := 0; m+1 < len(item.Content); m += 2 {
+						if k == item.Content[m].Value &&
+							v == item.Content[m+1].Value {
+							step.node = item
+							step.pos = j
+							break
+						}
+					}
+					if nil != step.node {
+						break
+					}
+				}
+				if nil == step.node {
+					return nil, nil, uerr.Chainf(ErrEditNotFound,
+						"no array entry with %s", seg)
+				}
+			} else {
+				index, err := strconv.Atoi(seg)
+				if err != nil {
+					return nil, nil, uerr.Chainf(ErrEditNotFound,
+						"array index %s not a number", seg)
+				} else if 0 > index || len(n.Content) < index {
+					return nil, nil, uerr.Chainf(ErrEditNotFound,
+						"array index %s out of range", seg)
+				} else if len(n.Content) > index {
+					step.node = n.Content[index]
+					step.pos = index
+				}
+			}
+		default:
+			return nil, nil, uerr.Chainf(ErrEditNotFound,
+				"%s is not a mapping or array", strings.Join(segs[:i], "."))
+		}
+		if nil == step.node {
+			return steps, segs[i:], nil
+		}
+		steps = append(steps, step)
+		n = step.node
+	}
+	return
+}
+
+// create the nodes to add to container for the path segments that are not
+// present.  key is nil if container is a sequence.
+func editNested(
+	container *yaml3.Node,
+	rest []string,
+	value *yaml3.Node,
+) (
+	key, added *yaml3.Node,
+	err error,
+) {
+	added = value
+	for i := len(rest) - 1; 0 < i; i-- {
+		added = &yaml3.Node{
+			Kind:    yaml3.MappingNode,
+			Tag:     "!!map",
+			Content: []*yaml3.Node{editKey(rest[i]), added},
+		}
+	}
+	switch container.Kind {
+	case yaml3.MappingNode:
+		key = editKey(rest[0])
+	case yaml3.SequenceNode:
+		if strconv.Itoa(len(container.Content)) != rest[0] {
+			err = fmt.Errorf("array element %s cannot be added", rest[0])
+		}
+	default:
+		err = ErrEditAlias
+	}
+	return
+}
+
+func editKey(key string) *yaml3.Node {
+	return &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key}
+}
+
+// replace the node at step with value, keeping any comments
+func editReplaceNode(step editStep_, value *yaml3.Node) {
+	old := step.node
+	if 0 == len(value.HeadComment) {
+		value.HeadComment = old.HeadComment
+	}
+	if 0 == len(value.LineComment) {
+		value.LineComment = old.LineComment
+	}
+	if 0 == len(value.FootComment) {
+		value.FootComment = old.FootComment
+	}
+	if nil != step.key {
+		step.parent.Content[step.pos+1] = value
+	} else {
+		step.parent.Content[step.pos] = value
+	}
+}
+
+// the indentation used for nested mappings in the document
+func editIndent(n *yaml3.Node) (rv int) {
+	rv = editFindIndent(n)
+	if 0 >= rv {
+		rv = 2
+	}
+	return
+}
+
+func editFindIndent(n *yaml3.Node) (rv int) {
+	if yaml3.MappingNode == n.Kind {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if yaml3.MappingNode == v.Kind && 0 != len(v.Content) &&
+				0 == v.Style&yaml3.FlowStyle && v.Line > k.Line {
+				return v.Content[0].Column - k.Column
+			}
+			if rv = editFindIndent(v); 0 != rv {
+				return
+			}
+		}
+	} else if yaml3.SequenceNode == n.Kind {
+		for _, item := range n.Content {
+			if rv = editFindIndent(item); 0 != rv {
+				return
+			}
+		}
+	}
+	return 0
+}
+
+//
+// text patching
+//
+// these produce the patched lines, or nil if unable to patch.  all line and
+// column values are converted from the 1 based values of yaml3.
+//
+
+func editSpaces(n int) string {
+	return strings.Repeat(" ", n)
+}
+
+func editIndentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// find the last line of an entry that starts on line start and is indented
+// at indent.  lines more indented than that belong to the entry.  if seqOk,
+// then sequence items at the same indent also belong to the entry.
+func editEntryEnd(lines []string, start, indent int, seqOk bool) (end int) {
+	end = start
+	for i := start + 1; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if 0 == len(t) || '#' == t[0] {
+			continue
+		}
+		ind := editIndentOf(lines[i])
+		if ind > indent ||
+			(seqOk && ind == indent && ("-" == t || strings.HasPrefix(t, "- "))) {
+			end = i
+			continue
+		}
+		break
+	}
+	return
+}
+
+// find the end of the scalar that starts at col on the line
+func editScalarEnd(line string, col int, style yaml3.Style) (end int, ok bool) {
+	switch {
+	case 0 != style&yaml3.DoubleQuotedStyle:
+		for i := col + 1; i < len(line); i++ {
+			if '\\' == line[i] {
+				i++
+			} else if '"' == line[i] {
+				return i + 1, true
+			}
+		}
+		return
+	case 0 != style&yaml3.SingleQuotedStyle:
+		for i := col + 1; i < len(line); i++ {
+			if '\'' == line[i] {
+				if i+1 < len(line) && '\'' == line[i+1] {
+					i++
+				} else {
+					return i + 1, true
+				}
+			}
+		}
+		return
+	case 0 == style:
+		end = len(line)
+		for i := col; i < len(line); i++ {
+			if '#' == line[i] && i > col && (' ' == line[i-1] || '\t' == line[i-1]) {
+				end = i
+				break
+			}
+		}
+		end = col + len(strings.TrimRight(line[col:end], " \t"))
+		return end, true
+	}
+	return
+}
+
+// render the node as a single line, if possible
+func editInline(n *yaml3.Node) (rv string, ok bool) {
+	if yaml3.ScalarNode != n.Kind && 0 != len(n.Content) {
+		return
+	}
+	b, err := yaml3.Marshal(n)
+	if err != nil {
+		return
+	}
+	rv = strings.TrimSuffix(string(b), "\n")
+	ok = !strings.Contains(rv, "\n")
+	return
+}
+
+// render the node as lines, indented by indent
+func editBlock(n *yaml3.Node, unit, indent int) (rv []string) {
+	var buff bytes.Buffer
+	enc := yaml3.NewEncoder(&buff)
+	enc.SetIndent(unit)
+	if nil != enc.Encode(n) || nil != enc.Close() {
+		return nil
+	}
+	rv = strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	for i := range rv {
+		rv[i] = editSpaces(indent) + rv[i]
+	}
+	return
+}
+
+// the lines for a mapping entry, where the first line has prefix
+func editEntryLines(
+	prefix, keyText string,
+	value *yaml3.Node,
+	trailing string,
+	unit int,
+) (rv []string) {
+	if inline, ok := editInline(value); ok {
+		return []string{prefix + keyText + " " + inline + trailing}
+	}
+	block := editBlock(value, unit, len(prefix)+unit)
+	if nil == block {
+		return nil
+	}
+	return append([]string{prefix + keyText + trailing}, block...)
+}
+
+// the lines for a sequence item, where the dash is at indent
+func editItemLines(indent int, value *yaml3.Node, unit int) (rv []string) {
+	dash := editSpaces(indent) + "- "
+	if inline, ok := editInline(value); ok {
+		return []string{dash + inline}
+	}
+	block := editBlock(value, unit, indent+2)
+	if nil == block {
+		return nil
+	}
+	block[0] = dash + block[0][indent+2:]
+	return block
+}
+
+// remove lines from through to (exclusive), avoiding leaving behind
+// consecutive blank lines
+func editRemove(lines []string, from, to int) (rv []string) {
+	blank := func(i int) bool {
+		return 0 <= i && i < len(lines) && 0 == len(strings.TrimSpace(lines[i]))
+	}
+	if blank(from-1) && (blank(to) || len(lines) == to ||
+		(len(lines)-1 == to && 0 == len(lines[to]))) {
+		from--
+	}
+	return editSplice(lines, from, to, nil)
+}
+
+func editSplice(lines []string, from, to int, with []string) (rv []string) {
+	rv = make([]string, 0, len(lines)-(to-from)+len(with))
+	rv = append(rv, lines[:from]...)
+	rv = append(rv, with...)
+	return append(rv, lines[to:]...)
+}
+
+// find the end of the key text, including the ':'
+func editKeyEnd(line string, key *yaml3.Node) (colon int, ok bool) {
+	col := key.Column - 1
+	end := col + len(key.Value)
+	if 0 != key.Style&(yaml3.DoubleQuotedStyle|yaml3.SingleQuotedStyle) {
+		end, ok = editScalarEnd(line, col, key.Style)
+		if !ok {
+			return
+		}
+	} else if 0 != key.Style || len(line) < end || key.Value != line[col:end] {
+		return
+	}
+	colon = end + len(line[end:]) - len(strings.TrimLeft(line[end:], " \t"))
+	ok = colon < len(line) && ':' == line[colon]
+	return
+}
+
+// the line and column (0 based) of the dash for the sequence item
+func editDash(lines []string, item *yaml3.Node) (line, col int, ok bool) {
+	line = item.Line - 1
+	if len(lines) <= line {
+		return
+	}
+	before := lines[line][:min(item.Column-1, len(lines[line]))]
+	col = strings.LastIndexByte(before, '-')
+	ok = -1 != col && 0 == len(strings.Trim(before[:col], " ")) &&
+		0 == len(strings.TrimSpace(before[col+1:]))
+	return
+}
+
+func editReplaceText(
+	lines []string,
+	step editStep_,
+	value *yaml3.Node,
+	unit int,
+) []string {
+	if 0 != step.parent.Style&yaml3.FlowStyle ||
+		0 != len(step.node.Anchor) || 0 != len(value.Anchor) ||
+		yaml3.AliasNode == step.node.Kind {
+		return nil
+	}
+	old := step.node
+	if nil == step.key { // sequence item
+		line, col, ok := editDash(lines, old)
+		if !ok {
+			return nil
+		}
+		end := editEntryEnd(lines, line, col, false)
+		if yaml3.ScalarNode == old.Kind && end == line {
+			if inline, ok := editInline(value); ok {
+				start := old.Column - 1
+				if stop, ok := editScalarEnd(lines[line], start, old.Style); ok {
+					l := lines[line]
+					return editSplice(lines, line, line+1,
+						[]string{l[:start] + inline + l[stop:]})
+				}
+			}
+		}
+		with := editItemLines(col, value, unit)
+		if nil == with {
+			return nil
+		}
+		return editSplice(lines, line, end+1, with)
+	}
+
+	key := step.key
+	line := key.Line - 1
+	l := lines[line]
+	colon, ok := editKeyEnd(l, key)
+	if !ok {
+		return nil
+	}
+	keyCol := key.Column - 1
+	end := editEntryEnd(lines, line, keyCol, yaml3.SequenceNode == old.Kind)
+	trailing := ""
+	if yaml3.ScalarNode == old.Kind && old.Line == key.Line &&
+		0 == old.Style&(yaml3.LiteralStyle|yaml3.FoldedStyle) {
+		if end != line {
+			return nil // multi-line scalar
+		}
+		start := old.Column - 1
+		stop, ok := editScalarEnd(l, start, old.Style)
+		if !ok {
+			return nil
+		}
+		if inline, ok := editInline(value); ok { // just change the value
+			return editSplice(lines, line, line+1,
+				[]string{l[:start] + inline + l[stop:]})
+		}
+		if comment := strings.TrimSpace(l[stop:]); 0 != len(comment) {
+			trailing = "  " + comment
+		}
+	} else if rest := strings.TrimSpace(l[colon+1:]); 0 == len(rest) ||
+		'#' == rest[0] {
+		trailing = strings.TrimRight(l[colon+1:], " \t")
+	} else if yaml3.ScalarNode != old.Kind {
+		return nil // inline flow collection, tag, etc
+	}
+	with := editEntryLines(l[:keyCol], l[keyCol:colon+1], value, trailing, unit)
+	if nil == with {
+		return nil
+	}
+	return editSplice(lines, line, end+1, with)
+}
+
+func editInsertText(
+	lines []string,
+	container, key, added *yaml3.Node,
+	unit int,
+) []string {
+	if 0 != container.Style&yaml3.FlowStyle || 0 == len(container.Content) {
+		return nil
+	}
+	if nil != key { // mapping
+		lastK := container.Content[len(container.Content)-2]
+		lastV := container.Content[len(container.Content)-1]
+		indent := container.Content[0].Column - 1
+		end := editEntryEnd(lines, lastK.Line-1, lastK.Column-1,
+			yaml3.SequenceNode == lastV.Kind)
+		keyText, ok := editInline(key)
+		if !ok {
+			return nil
+		}
+		with := editEntryLines(editSpaces(indent), keyText+":", added, "", unit)
+		if nil == with {
+			return nil
+		}
+		return editSplice(lines, end+1, end+1, with)
+	}
+	last := container.Content[len(container.Content)-1]
+	line, col, ok := editDash(lines, last)
+	if !ok {
+		return nil
+	}
+	end := editEntryEnd(lines, line, col, false)
+	with := editItemLines(col, added, unit)
+	if nil == with {
+		return nil
+	}
+	return editSplice(lines, end+1, end+1, with)
+}
+
+// how many comment lines are directly above line that belong to n
+func editHeadLines(lines []string, line int, n *yaml3.Node) (rv int) {
+	if 0 == len(n.HeadComment) {
+		return 0
+	}
+	rv = strings.Count(n.HeadComment, "\n") + 1
+	if line < rv {
+		return 0
+	}
+	for i := line - rv; i < line; i++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+			return 0
+		}
+	}
+	return
+}
+
+func editDeleteText(lines []string, step editStep_) []string {
+	if 0 != step.parent.Style&yaml3.FlowStyle {
+		return nil
+	}
+	if nil == step.key { // sequence item
+		line, col, ok := editDash(lines, step.node)
+		if !ok {
+			return nil
+		}
+		end := editEntryEnd(lines, line, col, false)
+		start := line - editHeadLines(lines, line, step.node)
+		return editRemove(lines, start, end+1)
+	}
+
+	key := step.key
+	line := key.Line - 1
+	keyCol := key.Column - 1
+	prefix := lines[line][:keyCol]
+	end := editEntryEnd(lines, line, keyCol,
+		yaml3.SequenceNode == step.node.Kind)
+	if 0 == len(strings.Trim(prefix, " ")) {
+		start := line - editHeadLines(lines, line, key)
+		return editRemove(lines, start, end+1)
+	}
+
+	//
+	// first key of a sequence item (- key: value), so the next key must
+	// take over the prefix
+	//
+	if len(step.parent.Content) <= step.pos+2 {
+		return nil
+	}
+	next := step.parent.Content[step.pos+2].Line - 1
+	if next <= end || len(lines) <= next ||
+		editSpaces(keyCol) != lines[next][:min(keyCol, len(lines[next]))] {
+		return nil
+	}
+	return editSplice(lines, line, next+1,
+		[]string{prefix + lines[next][keyCol:]})
+}
@@ -24,7 +24,13 @@ func (this expander_) Get(key string) (rv string) {
 	return this.mapping[key]
 }
 
+// expand the value, decrypting any encrypted values
 func (this *expander_) expand(value string) (rv string) {
+	return decryptQuietly(this.resolve(value))
+}
+
+// expand the value, leaving any encrypted values encrypted
+func (this *expander_) resolve(value string) (rv string) {
 	if strings.Contains(value, "${") {
 		value = os.ExpandEnv(value)
 	}
@@ -32,11 +38,11 @@ func (this *expander_) expand(value string) (rv string) {
 		//
 		// any errors - just return the unresolved text
 		//
-		t, err := template.New("").Option("missingkey=error").Parse(value)
+		t, err := this.template().Parse(value)
 		if nil == err {
 			var buff bytes.Buffer
 			buff.Grow(len(value))
-			err = t.Execute(&buff, *this)
+			err = t.Execute(&buff, this.mapping)
 			if err != nil { // try again, more carefully
 				buff.Reset()
 				this.carefully(&buff, value)
@@ -47,6 +53,27 @@ func (this *expander_) expand(value string) (rv string) {
 	return strings.TrimSpace(value)
 }
 
+// create a template with the funcs available
+func (this *expander_) template() *template.Template {
+	return template.New("").
+		Option("missingkey=error").
+		Funcs(templateFuncs()).
+		Funcs(template.FuncMap{
+			// {{ prop "key" "dflt" }} - property value or dflt if not set
+			"prop": func(key string, dflt ...string) (rv string, err error) {
+				rv, found := this.mapping[key]
+				if !found {
+					if 0 == len(dflt) {
+						err = fmt.Errorf("property %s not set", key)
+					} else {
+						rv = dflt[0]
+					}
+				}
+				return
+			},
+		})
+}
+
 // carefully expand each individual {{...}} group.  if one doesn't expand,
 // then put it in unexpanded.
 func (this *expander_) carefully(buff *bytes.Buffer, value string) {
@@ -67,7 +94,7 @@ func (this *expander_) carefully(buff *bytes.Buffer, value string) {
 		buff.WriteString(slice[:beg])
 
 		tstring := slice[beg:end]
-		t, err := template.New("").Option("missingkey=error").Parse(tstring)
+		t, err := this.template().Parse(tstring)
 		if err != nil { // template text not really template text - put it in
 			buff.WriteString(tstring)
 			continue
@@ -159,7 +186,7 @@ func (this *expander_) addAll(m map[string]string) (err error) {
 
 func (this *expander_) expandAll() {
 	for k, v := range this.mapping {
-		this.mapping[k] = this.expand(v)
+		this.mapping[k] = this.resolve(v)
 	}
 }
 
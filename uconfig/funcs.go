@@ -0,0 +1,273 @@
+package uconfig
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tredeske/u/unet"
+)
+
+// largest file that may be read with readFile in a template
+const MaxTemplateFileSize = 64 * 1024
+
+var (
+	funcsLock_ sync.Mutex
+	funcs_     = builtinFuncs()
+)
+
+// Add functions for use in {{...}} expansions of config values.
+//
+// This must be called prior to loading config, such as from an init() func.
+// Functions with the same name as an existing function replace it.
+//
+//	uconfig.AddFuncs(template.FuncMap{
+//	    "region": func() string { return myRegion },
+//	})
+func AddFuncs(funcs template.FuncMap) {
+	funcsLock_.Lock()
+	defer funcsLock_.Unlock()
+	updated := make(template.FuncMap, len(funcs_)+len(funcs))
+	for k, v := range funcs_ {
+		updated[k] = v
+	}
+	for k, v := range funcs {
+		updated[k] = v
+	}
+	funcs_ = updated
+}
+
+// get the current funcs.  the returned map must not be modified.
+func templateFuncs() (rv template.FuncMap) {
+	funcsLock_.Lock()
+	rv = funcs_
+	funcsLock_.Unlock()
+	return
+}
+
+// the functions available by default - see package doc
+func builtinFuncs() template.FuncMap {
+	return template.FuncMap{
+		"default":       tmplDefault,
+		"env":           tmplEnv,
+		"upper":         strings.ToUpper,
+		"lower":         strings.ToLower,
+		"trim":          strings.TrimSpace,
+		"trimPrefix":    func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":    func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":       func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"split":         func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":          tmplJoin,
+		"add":           tmplArith(func(a, b int64) (int64, error) { return a + b, nil }),
+		"sub":           tmplArith(func(a, b int64) (int64, error) { return a - b, nil }),
+		"mul":           tmplArith(func(a, b int64) (int64, error) { return a * b, nil }),
+		"div":           tmplArith(tmplDiv),
+		"mod":           tmplArith(tmplMod),
+		"hostname":      tmplHostname,
+		"shortHostname": tmplShortHostname,
+		"resolveIp":     tmplResolveIp,
+		"readFile":      tmplReadFile,
+		"b64enc":        tmplB64Enc,
+		"b64dec":        tmplB64Dec,
+		"ternary":       tmplTernary,
+		"empty":         tmplEmpty,
+	}
+}
+
+// {{ default "dflt" .maybeBlank }} - produce dflt if value is blank
+func tmplDefault(dflt, value any) any {
+	if tmplEmpty(value) {
+		return dflt
+	}
+	return value
+}
+
+// is the value nil, blank, zero, or false?
+func tmplEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return 0 == len(v)
+	case bool:
+		return !v
+	case int:
+		return 0 == v
+	case int64:
+		return 0 == v
+	case float64:
+		return 0 == v
+	case []string:
+		return 0 == len(v)
+	}
+	return false
+}
+
+// {{ env "NAME" }} or {{ env "NAME" "dflt" }}
+func tmplEnv(name string, dflt ...string) (rv string, err error) {
+	rv, found := os.LookupEnv(name)
+	if !found || 0 == len(rv) {
+		if 0 == len(dflt) {
+			if !found {
+				err = fmt.Errorf("env var %s not set", name)
+			}
+			return
+		}
+		rv = dflt[0]
+	}
+	return
+}
+
+// {{ join "," .list }} - list may be []string or []any
+func tmplJoin(sep string, list any) (rv string, err error) {
+	switch l := list.(type) {
+	case []string:
+		rv = strings.Join(l, sep)
+	case []any:
+		strs := make([]string, len(l))
+		for i, v := range l {
+			strs[i] = fmt.Sprint(v)
+		}
+		rv = strings.Join(strs, sep)
+	case string:
+		rv = l
+	default:
+		err = fmt.Errorf("join: unable to join %T", list)
+	}
+	return
+}
+
+// produce a func that converts its args to ints before applying op, since
+// properties are strings.
+func tmplArith(
+	op func(a, b int64) (int64, error),
+) func(a, b any) (int64, error) {
+	return func(a, b any) (rv int64, err error) {
+		aI, err := tmplInt(a)
+		if err != nil {
+			return
+		}
+		bI, err := tmplInt(b)
+		if err != nil {
+			return
+		}
+		return op(aI, bI)
+	}
+}
+
+func tmplDiv(a, b int64) (int64, error) {
+	if 0 == b {
+		return 0, errors.New("divide by zero")
+	}
+	return a / b, nil
+}
+
+func tmplMod(a, b int64) (int64, error) {
+	if 0 == b {
+		return 0, errors.New("divide by zero")
+	}
+	return a % b, nil
+}
+
+func tmplInt(v any) (rv int64, err error) {
+	switch typed := v.(type) {
+	case int:
+		rv = int64(typed)
+	case int64:
+		rv = typed
+	case string:
+		rv, err = strconv.ParseInt(strings.TrimSpace(typed), 0, 64)
+	default:
+		err = fmt.Errorf("%v (%T) is not an integer", v, v)
+	}
+	return
+}
+
+// {{ hostname }} - the hostname of this host
+func tmplHostname() (rv string, err error) {
+	if 0 != len(ThisHost) {
+		return ThisHost, nil
+	}
+	return os.Hostname()
+}
+
+// {{ shortHostname }} - the hostname of this host up to the first '.'
+func tmplShortHostname() (rv string, err error) {
+	rv, err = tmplHostname()
+	if nil == err {
+		if dot := strings.IndexByte(rv, '.'); -1 != dot {
+			rv = rv[:dot]
+		}
+	}
+	return
+}
+
+// {{ resolveIp "host" }} - the IP of the named host
+func tmplResolveIp(host string) (rv string, err error) {
+	var ip net.IP
+	ip, err = unet.ResolveIp(host, 2*time.Second)
+	if nil == err {
+		rv = ip.String()
+	}
+	return
+}
+
+// {{ readFile "/path/to/file" }} - the trimmed contents of a small file
+func tmplReadFile(path string) (rv string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, MaxTemplateFileSize+1))
+	if err != nil {
+		return
+	} else if MaxTemplateFileSize < len(content) {
+		err = fmt.Errorf("readFile: %s larger than %d bytes",
+			path, MaxTemplateFileSize)
+		return
+	}
+	rv = strings.TrimSpace(string(content))
+	return
+}
+
+func tmplB64Enc(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func tmplB64Dec(s string) (rv string, err error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if nil == err {
+		rv = string(b)
+	}
+	return
+}
+
+// {{ ternary "yes" "no" .cond }} - cond may be a bool or a string (parsed
+// as a bool)
+func tmplTernary(ifTrue, ifFalse, cond any) (rv any, err error) {
+	var b bool
+	switch c := cond.(type) {
+	case bool:
+		b = c
+	case string:
+		b, err = strconv.ParseBool(strings.TrimSpace(c))
+		if err != nil {
+			return
+		}
+	default:
+		b = !tmplEmpty(cond)
+	}
+	if b {
+		return ifTrue, nil
+	}
+	return ifFalse, nil
+}
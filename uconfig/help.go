@@ -51,6 +51,23 @@ func (this *Help) Default(value any) (rv *Help) {
 	return this
 }
 
+// Record the allowed values for this.  Section.GetEnum does this when
+// gathering help (see Chain).
+func (this *Help) Choices(choices ...string) (rv *Help) {
+	*this = append(*this, yaml.MapItem{Key: "choices", Value: choices})
+	return this
+}
+
+// Get a Chain on an empty config that records into this Help what the getters
+// know about the settings, such as the choices of GetEnum, so that settings
+// are documented by the code that reads them.  Errors are collected, not
+// stopped at, so every getter is run.  See golum.Show.
+func (this *Help) Chain() *Chain {
+	s, _ := NewSection(nil)
+	s.help = this
+	return s.ChainAll()
+}
+
 // Set something on the current help
 func (this *Help) Set(key string, value any) (rv *Help) {
 	*this = append(*this, yaml.MapItem{Key: key, Value: value})
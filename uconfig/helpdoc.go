@@ -0,0 +1,249 @@
+package uconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//
+// render Help as reference docs, so docs are generated from the same Help
+// methods the code uses
+//
+
+// the attributes of an item that are rendered specially
+var helpAttrs_ = map[string]bool{
+	"type":     true,
+	"note":     true,
+	"optional": true,
+	"default":  true,
+	"choices":  true,
+}
+
+// is this help for a config item (as opposed to a section)?
+func (this *Help) isItem() bool {
+	return this.Contains("type")
+}
+
+// the help for the params, if this is the top of a component's help
+// (see Init)
+func (this *Help) params() (title, note string, params *Help) {
+	params = this.GetHelp("params")
+	if nil != params {
+		for _, item := range *this {
+			if s, ok := item.Value.(string); ok {
+				title, _ = item.Key.(string)
+				note = s
+				break
+			}
+		}
+	}
+	return
+}
+
+// the items and sub-sections of this help
+func (this *Help) children() (items, subs []helpChild_) {
+	for _, item := range *this {
+		h, ok := item.Value.(*Help)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprint(item.Key)
+		if h.isItem() {
+			items = append(items, helpChild_{key, h})
+		} else {
+			subs = append(subs, helpChild_{key, h})
+		}
+	}
+	return
+}
+
+type helpChild_ struct {
+	key  string
+	help *Help
+}
+
+// the note of the item, including any extra attributes set on it
+func (this *Help) itemNote() string {
+	note := strings.TrimSpace(fmt.Sprint(this.Get("note")))
+	if choices, ok := this.Get("choices").([]string); ok {
+		note += "\n\nOne of: " + strings.Join(choices, ", ")
+	}
+	for _, item := range *this {
+		key := fmt.Sprint(item.Key)
+		if _, isHelp := item.Value.(*Help); !isHelp && !helpAttrs_[key] {
+			note += fmt.Sprintf("\n\n%s: %v", key, item.Value)
+		}
+	}
+	return strings.TrimSpace(note)
+}
+
+// the default value of the item, or "" if none
+func (this *Help) itemDefault() string {
+	if !this.Contains("default") {
+		return ""
+	}
+	return fmt.Sprint(this.Get("default"))
+}
+
+// is the item optional?
+func (this *Help) itemOptional() bool {
+	optional, _ := this.Get("optional").(bool)
+	return optional || this.Contains("default")
+}
+
+func joinHelpPath(path, key string) string {
+	if 0 == len(path) {
+		return key
+	}
+	return path + "." + key
+}
+
+// produce the help contents in Markdown format
+func (this *Help) AsMarkdown() (content []byte, err error) {
+	var b bytes.Buffer
+	err = this.WriteMarkdown(&b, 1)
+	return b.Bytes(), err
+}
+
+// write the help contents as Markdown, with top headings at level
+func (this *Help) WriteMarkdown(w io.Writer, level int) (err error) {
+	var b strings.Builder
+	if title, note, params := this.params(); nil != params {
+		b.WriteString(strings.Repeat("#", level) + " " + title + "\n\n")
+		if note = strings.TrimSpace(note); 0 != len(note) {
+			b.WriteString(note + "\n\n")
+		}
+		params.markdownSection(&b, "", level+1)
+	} else {
+		this.markdownSection(&b, "", level)
+	}
+	_, err = io.WriteString(w, b.String())
+	return
+}
+
+// write a table of the items, followed by sections for any nested items
+func (this *Help) markdownSection(b *strings.Builder, path string, level int) {
+	items, subs := this.children()
+	if 0 != len(items) {
+		b.WriteString("| Key | Type | Default | Optional | Note |\n")
+		b.WriteString("|-----|------|---------|----------|------|\n")
+		for _, item := range items {
+			optional := ""
+			if item.help.itemOptional() {
+				optional = "yes"
+			}
+			dflt := item.help.itemDefault()
+			if 0 != len(dflt) {
+				dflt = "`" + dflt + "`"
+			}
+			fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s |\n",
+				item.key,
+				markdownCell(fmt.Sprint(item.help.Get("type"))),
+				markdownCell(dflt),
+				optional,
+				markdownCell(item.help.itemNote()))
+		}
+		b.WriteString("\n")
+	}
+	heading := strings.Repeat("#", min(level, 6))
+	for _, item := range items {
+		nested, nestedSubs := item.help.children()
+		if 0 != len(nested) || 0 != len(nestedSubs) {
+			itemPath := joinHelpPath(path, item.key)
+			b.WriteString(heading + " " + itemPath + "\n\n")
+			item.help.markdownSection(b, itemPath, level+1)
+		}
+	}
+	for _, sub := range subs {
+		subPath := joinHelpPath(path, sub.key)
+		b.WriteString(heading + " " + subPath + "\n\n")
+		sub.help.markdownSection(b, subPath, level+1)
+	}
+}
+
+// make the text suitable for a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// produce the help contents as a roff man page with the title and manual
+// section (typically 5 for config files)
+func (this *Help) AsMan(title string, section int) (content []byte, err error) {
+	var b bytes.Buffer
+	WriteManHeader(&b, title, section, "configuration")
+	b.WriteString(".SH CONFIGURATION\n")
+	err = this.WriteMan(&b)
+	return b.Bytes(), err
+}
+
+// write the man page header, including the NAME section
+func WriteManHeader(w io.Writer, title string, section int, descr string) {
+	fmt.Fprintf(w, ".TH \"%s\" \"%d\"\n.SH NAME\n%s \\- %s\n",
+		strings.ToUpper(roffEscape(title)), section, roffEscape(title),
+		roffEscape(descr))
+}
+
+// write the help contents as roff for inclusion in a man page, with each
+// component as a sub-section
+func (this *Help) WriteMan(w io.Writer) (err error) {
+	var b strings.Builder
+	if title, note, params := this.params(); nil != params {
+		b.WriteString(".SS " + roffEscape(title) + "\n")
+		if note = strings.TrimSpace(note); 0 != len(note) {
+			b.WriteString(".PP\n" + roffText(note))
+		}
+		params.manItems(&b, "")
+	} else {
+		this.manItems(&b, "")
+	}
+	_, err = io.WriteString(w, b.String())
+	return
+}
+
+// write each item as a tagged paragraph, with nested items by their path
+func (this *Help) manItems(b *strings.Builder, path string) {
+	items, subs := this.children()
+	for _, item := range items {
+		itemPath := joinHelpPath(path, item.key)
+		b.WriteString(".TP\n.B " + roffEscape(itemPath) + "\n")
+		attrs := []string{fmt.Sprint(item.help.Get("type"))}
+		if dflt := item.help.itemDefault(); 0 != len(dflt) {
+			attrs = append(attrs, "default: "+dflt)
+		} else if item.help.itemOptional() {
+			attrs = append(attrs, "optional")
+		}
+		b.WriteString("(" + roffEscape(strings.Join(attrs, ", ")) + ")\n")
+		if note := item.help.itemNote(); 0 != len(note) {
+			b.WriteString(".br\n" + roffText(note))
+		}
+		item.help.manItems(b, itemPath)
+	}
+	for _, sub := range subs {
+		sub.help.manItems(b, joinHelpPath(path, sub.key))
+	}
+}
+
+// escape text for roff
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	return strings.ReplaceAll(s, "-", `\-`)
+}
+
+// convert the text to roff lines, with blank lines as paragraph breaks
+func roffText(s string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if 0 == len(line) {
+			b.WriteString(".sp\n")
+			continue
+		} else if '.' == line[0] || '\'' == line[0] {
+			b.WriteString(`\&`)
+		}
+		b.WriteString(roffEscape(line) + "\n")
+	}
+	return b.String()
+}
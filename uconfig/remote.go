@@ -0,0 +1,224 @@
+package uconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tredeske/u/uerr"
+	"github.com/tredeske/u/ulog"
+)
+
+var (
+	// client used to fetch remote (http, https) includes.  uboot sets this
+	// up with TLS settings if configured to do so.
+	RemoteClient = &http.Client{Timeout: 15 * time.Second}
+
+	// where the last good copy of remote includes are kept so that config
+	// can still be loaded if the config server is unavailable.  if not set,
+	// then no cache is kept.
+	RemoteCacheD = ""
+
+	// how often to check remote includes for changes when watching config
+	RemoteRevalidate = time.Minute
+
+	// largest remote include allowed
+	RemoteMaxSize int64 = 4 * 1024 * 1024
+
+	remoteLock_ sync.Mutex
+	remoteSeen_ = make(map[string][sha256.Size]byte) // url -> content hash
+)
+
+// is the include a remote include?
+func isRemote(file string) bool {
+	return strings.HasPrefix(file, "https://") ||
+		strings.HasPrefix(file, "http://")
+}
+
+// read the content of the config file, which may be a local file or a
+// remote (http, https) URL
+func readConfig(file string) (content []byte, err error) {
+	if isRemote(file) {
+		content, _, err = fetchRemote(file)
+		return
+	}
+	return os.ReadFile(file)
+}
+
+// where the cached copy of the URL is kept
+func remoteCacheF(url string) (contentF, etagF string) {
+	sum := sha256.Sum256([]byte(url))
+	base := filepath.Join(RemoteCacheD, hex.EncodeToString(sum[:12]))
+	return base + ".yml", base + ".etag"
+}
+
+// get the content at URL, revalidating any cached copy.
+//
+// if the URL cannot be fetched, but there is a cached copy, then that is used.
+//
+// changed will be true if the content differs from the last time it was
+// fetched
+func fetchRemote(url string) (content []byte, changed bool, err error) {
+
+	remoteLock_.Lock()
+	defer remoteLock_.Unlock()
+
+	defer func() {
+		if nil == err {
+			sum := sha256.Sum256(content)
+			prev, seen := remoteSeen_[url]
+			changed = seen && prev != sum
+			remoteSeen_[url] = sum
+		}
+	}()
+
+	var cached []byte
+	var etag string
+	var contentF, etagF string
+	if 0 != len(RemoteCacheD) {
+		contentF, etagF = remoteCacheF(url)
+		cached, err = os.ReadFile(contentF)
+		if nil == err {
+			var etagB []byte
+			etagB, err = os.ReadFile(etagF)
+			if nil == err {
+				etag = strings.TrimSpace(string(etagB))
+			}
+		} else {
+			cached = nil
+		}
+		err = nil
+	}
+
+	var newEtag string
+	content, newEtag, err = getRemote(url, etag, nil != cached)
+	if err != nil {
+		if nil == cached {
+			err = uerr.Chainf(err, "fetching %s", url)
+			return
+		}
+		ulog.Warnf("Unable to fetch %s - using cached copy: %s", url, err)
+		content = cached
+		err = nil
+		return
+
+	} else if nil == content { // not modified
+		content = cached
+		return
+	}
+
+	if 0 != len(RemoteCacheD) &&
+		(!bytes.Equal(cached, content) || etag != newEtag) {
+		werr := writeCache(contentF, etagF, content, newEtag)
+		if werr != nil {
+			ulog.Warnf("Unable to cache %s: %s", url, werr)
+		}
+	}
+	return
+}
+
+// GET the URL.  if conditional, then send If-None-Match with etag, and
+// return nil content if not modified.
+func getRemote(
+	url, etag string,
+	conditional bool,
+) (
+	content []byte,
+	newEtag string,
+	err error,
+) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	if conditional && 0 != len(etag) {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := RemoteClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if conditional {
+			newEtag = etag
+			return
+		}
+		err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case http.StatusOK:
+		content, err = io.ReadAll(io.LimitReader(resp.Body, RemoteMaxSize+1))
+		if nil == err && RemoteMaxSize < int64(len(content)) {
+			err = fmt.Errorf("content larger than %d bytes", RemoteMaxSize)
+		}
+		if err != nil {
+			content = nil
+			return
+		} else if nil == content {
+			content = []byte{}
+		}
+		newEtag = resp.Header.Get("ETag")
+	default:
+		err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return
+}
+
+// atomically update the cached copy
+func writeCache(contentF, etagF string, content []byte, etag string) (err error) {
+	err = os.MkdirAll(filepath.Dir(contentF), 0775)
+	if err != nil {
+		return
+	}
+	err = writeAtomic(contentF, content, 0664)
+	if err != nil {
+		return
+	} else if 0 == len(etag) {
+		err = os.Remove(etagF)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	return writeAtomic(etagF, []byte(etag), 0664)
+}
+
+// write the file by writing a temp file and renaming it into place
+func writeAtomic(file string, content []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	_, err = tmp.Write(content)
+	if err != nil {
+		return
+	}
+	err = tmp.Chmod(perm)
+	if err != nil {
+		return
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return
+	}
+	err = tmp.Close()
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), file)
+}
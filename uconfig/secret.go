@@ -0,0 +1,346 @@
+package uconfig
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tredeske/u/uerr"
+	"github.com/tredeske/u/ulog"
+)
+
+const (
+	// env var that may contain the base64 secret key
+	SecretKeyEnv = "UCONFIG_KEY"
+
+	// env var that may contain the path to the secret key file
+	SecretKeyFileEnv = "UCONFIG_KEY_FILE"
+
+	// size of the secret key, in bytes
+	SecretKeySize = 32
+
+	ErrNoSecretKey = uerr.Const("encrypted config value, but no secret key set")
+
+	encPrefix_ = "ENC[aes256gcm,"
+	encSuffix_ = "]"
+)
+
+var (
+	secretLock_  sync.Mutex
+	secretAead_  cipher.AEAD
+	secretTried_ bool // have we tried to get the key from the env?
+)
+
+// Generate a new secret key for encrypting config values, in the base64 form
+// used in secret key files and in the UCONFIG_KEY env var
+func NewSecretKey() (encoded string, err error) {
+	key := make([]byte, SecretKeySize)
+	_, err = rand.Read(key)
+	if nil == err {
+		encoded = base64.StdEncoding.EncodeToString(key)
+	}
+	return
+}
+
+// Set the key used to encrypt and decrypt config values.  key may be the
+// raw key, or the base64 encoding of it.
+func SetSecretKey(key []byte) (err error) {
+	aead, err := newSecretAead(key)
+	if err != nil {
+		return
+	}
+	secretLock_.Lock()
+	secretAead_ = aead
+	secretTried_ = true
+	secretLock_.Unlock()
+	return
+}
+
+// Load the key used to encrypt and decrypt config values from the file.
+// see SetSecretKey.
+func LoadSecretKey(file string) (err error) {
+	key, err := readSecretKey(file)
+	if nil == err {
+		err = SetSecretKey(key)
+		if err != nil {
+			err = uerr.Chainf(err, "loading secret key from %s", file)
+		}
+	}
+	return
+}
+
+func readSecretKey(file string) (key []byte, err error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return
+	} else if 0 != info.Mode().Perm()&0007 {
+		ulog.Warnf("Secret key file %s is readable by others", file)
+	}
+	return os.ReadFile(file)
+}
+
+func newSecretAead(key []byte) (rv cipher.AEAD, err error) {
+	if SecretKeySize != len(key) {
+		decoded, decodeErr := base64.StdEncoding.DecodeString(
+			strings.TrimSpace(string(key)))
+		if decodeErr != nil || SecretKeySize != len(decoded) {
+			err = fmt.Errorf("secret key must be %d bytes, or base64 of that",
+				SecretKeySize)
+			return
+		}
+		key = decoded
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// get the secret key cipher, trying the env if no key set
+func secretAead() (rv cipher.AEAD, err error) {
+	secretLock_.Lock()
+	defer secretLock_.Unlock()
+	if nil == secretAead_ && !secretTried_ {
+		secretTried_ = true
+		var key []byte
+		if f := os.Getenv(SecretKeyFileEnv); 0 != len(f) {
+			key, err = readSecretKey(f)
+			if err != nil {
+				return
+			}
+			secretAead_, err = newSecretAead(key)
+			if err != nil {
+				err = uerr.Chainf(err, "loading secret key from %s", f)
+			}
+		} else if encoded := os.Getenv(SecretKeyEnv); 0 != len(encoded) {
+			secretAead_, err = newSecretAead([]byte(encoded))
+			if err != nil {
+				err = uerr.Chainf(err, "loading secret key from %s", SecretKeyEnv)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	rv = secretAead_
+	if nil == rv {
+		err = ErrNoSecretKey
+	}
+	return
+}
+
+// is the value (or part of it) encrypted?
+func IsEncrypted(value string) bool {
+	return strings.Contains(value, encPrefix_)
+}
+
+// Encrypt the value, producing ENC[aes256gcm,...] for use in config
+func Encrypt(plain string) (rv string, err error) {
+	aead, err := secretAead()
+	if err != nil {
+		return
+	}
+	sealed := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	_, err = rand.Read(sealed)
+	if err != nil {
+		return
+	}
+	sealed = aead.Seal(sealed, sealed, []byte(plain), nil)
+	rv = encPrefix_ + base64.StdEncoding.EncodeToString(sealed) + encSuffix_
+	return
+}
+
+// Decrypt any ENC[aes256gcm,...] in value
+func Decrypt(value string) (rv string, err error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	aead, err := secretAead()
+	if err != nil {
+		return
+	}
+	var b strings.Builder
+	b.Grow(len(value))
+	for {
+		beg := strings.Index(value, encPrefix_)
+		if -1 == beg {
+			break
+		}
+		end := strings.Index(value[beg:], encSuffix_)
+		if -1 == end {
+			err = errors.New("encrypted value missing closing " + encSuffix_)
+			return
+		}
+		end += beg
+		var sealed, plain []byte
+		sealed, err = base64.StdEncoding.DecodeString(
+			value[beg+len(encPrefix_) : end])
+		if err != nil {
+			err = uerr.Chainf(err, "decoding encrypted value")
+			return
+		} else if len(sealed) < aead.NonceSize() {
+			err = errors.New("encrypted value too short")
+			return
+		}
+		nonce := sealed[:aead.NonceSize()]
+		plain, err = aead.Open(nil, nonce, sealed[aead.NonceSize():], nil)
+		if err != nil {
+			err = uerr.Chainf(err, "decrypting value (wrong key?)")
+			return
+		}
+		b.WriteString(value[:beg])
+		b.Write(plain)
+		value = value[end+len(encSuffix_):]
+	}
+	b.WriteString(value)
+	rv = b.String()
+	return
+}
+
+// decrypt the value, or leave it as is if it cannot be decrypted.  problems
+// are caught at load time by checkEncrypted.
+func decryptQuietly(value string) string {
+	if IsEncrypted(value) {
+		plain, err := Decrypt(value)
+		if nil == err {
+			return plain
+		}
+	}
+	return value
+}
+
+// verify that all encrypted values in the section can be decrypted, so that
+// a missing or wrong key is detected when config is loaded
+func (this *Section) checkEncrypted() (err error) {
+	for k, v := range this.expander.mapping {
+		if IsEncrypted(v) {
+			_, err = Decrypt(v)
+			if err != nil {
+				return uerr.Chainf(err, "%s.%s", PROPS, k)
+			}
+		}
+	}
+	return this.checkEncryptedIn(this.Context, this.section)
+}
+
+// walk the whole tree, including included files, the same way as flatten.
+// includes that cannot be loaded are skipped, as they are reported on use.
+func (this *Section) checkEncryptedIn(path string, it any) (err error) {
+	join := func(k string) string {
+		if 0 == len(path) {
+			return k
+		}
+		return path + "." + k
+	}
+	switch v := it.(type) {
+	case map[string]any, map[any]any:
+		var m map[string]any
+		m, _, err = this.getMap(v, nil)
+		if err != nil {
+			return nil
+		}
+		for k, val := range m {
+			if include_ == k {
+				continue
+			}
+			err = this.checkEncryptedIn(join(k), val)
+			if err != nil {
+				return
+			}
+		}
+	case []any:
+		var entries []map[string]any
+		var srcs []*srcPos_
+		for i, val := range v {
+			if m, isMap := val.(map[string]any); isMap {
+				entries = entries[:0]
+				var isInclude bool
+				isInclude, err = this.arrayEntryInclude(m, nil, &entries, &srcs)
+				if err != nil {
+					return nil
+				} else if isInclude {
+					for j, included := range entries {
+						err = this.checkEncryptedIn(
+							join(strconv.Itoa(i)+"."+strconv.Itoa(j)), included)
+						if err != nil {
+							return
+						}
+					}
+					continue
+				}
+			}
+			err = this.checkEncryptedIn(join(strconv.Itoa(i)), val)
+			if err != nil {
+				return
+			}
+		}
+	case string:
+		if resolved := this.expander.resolve(v); IsEncrypted(resolved) {
+			_, err = Decrypt(resolved)
+			if err != nil {
+				err = uerr.Chainf(err, "%s", path)
+			}
+		}
+	}
+	return
+}
+
+// Encrypt all of the plain values at path (see Editor), which may be a single
+// value, or a mapping or sequence of values.  Values that are already
+// encrypted are left as is.  The number of values encrypted is returned.
+func (this *Editor) Encrypt(path string) (count int, err error) {
+	value, found, err := this.Get(path)
+	if err != nil {
+		return
+	} else if !found {
+		err = uerr.Chainf(ErrEditNotFound, "encrypting %s", path)
+		return
+	}
+	var leaves []string
+	var visit func(path string, it any)
+	visit = func(path string, it any) {
+		switch v := it.(type) {
+		case map[string]any:
+			for k, val := range v {
+				visit(path+"."+k, val)
+			}
+		case []any:
+			for i, val := range v {
+				visit(path+"."+strconv.Itoa(i), val)
+			}
+		case nil:
+		case string:
+			if !IsEncrypted(v) {
+				leaves = append(leaves, path)
+			}
+		default:
+			leaves = append(leaves, path)
+		}
+	}
+	visit(path, value)
+	for _, leaf := range leaves {
+		value, _, err = this.Get(leaf)
+		if err != nil {
+			return
+		}
+		var encrypted string
+		encrypted, err = Encrypt(fmt.Sprint(value))
+		if err != nil {
+			return
+		}
+		err = this.Set(leaf, encrypted)
+		if err != nil {
+			return
+		}
+		count++
+	}
+	return
+}
@@ -0,0 +1,477 @@
+package uconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tredeske/u/uerr"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the value recorded in snapshots in place of secrets
+const Redacted = "REDACTED"
+
+// by default, values of keys that match this are redacted from snapshots
+var DefaultRedact = regexp.MustCompile(
+	`(?i)(passw(or)?d|secret|token|credential|private_?key|api_?key)`)
+
+// History keeps a bounded set of snapshots of config that was accepted, so
+// that it is possible to see what changed, and to return to a config that
+// worked.
+//
+// Each snapshot is the fully resolved config: properties are expanded,
+// includes are flattened, and secrets are redacted.  Secret properties are
+// those with keys matching Redact, and those built from them.  Values that
+// reference secrets keep the reference rather than being expanded, so that
+// LoadSnapshot can resolve them again from the properties of the config in
+// use.  Any other redacted values will need to be supplied some other way
+// (such as by env var).  Encrypted values (see Encrypt) are kept encrypted
+// rather than redacted.
+type History struct {
+	Dir    string         // where snapshots are kept
+	Keep   int            // max number of snapshots to keep
+	Redact *regexp.Regexp // redact values of keys that match, if set
+}
+
+// a config snapshot
+type Snapshot struct {
+	File string    // path to snapshot file
+	Time time.Time // when the snapshot was taken
+	Hash string    // hash of the snapshot content
+}
+
+func (this Snapshot) String() string {
+	return this.Time.Format(time.RFC3339) + " " + this.Hash
+}
+
+const snapTimeFmt_ = "20060102T150405.000000Z"
+
+// create a History that keeps up to keep snapshots in dir
+func NewHistory(dir string, keep int) (rv *History) {
+	if 0 >= keep {
+		keep = 20
+	}
+	return &History{
+		Dir:    dir,
+		Keep:   keep,
+		Redact: DefaultRedact,
+	}
+}
+
+// save a snapshot of the config if it differs from the latest snapshot,
+// pruning older snapshots.  the latest snapshot is returned.
+func (this *History) Save(s *Section) (snap Snapshot, err error) {
+	content, err := this.Snapshot(s)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:6])
+
+	snaps, err := this.List()
+	if err != nil {
+		return
+	} else if 0 != len(snaps) && hash == snaps[0].Hash {
+		snap = snaps[0]
+		return
+	}
+
+	err = os.MkdirAll(this.Dir, 0775)
+	if err != nil {
+		return
+	}
+	snap.Time = time.Now().UTC().Truncate(time.Microsecond)
+	snap.Hash = hash
+	snap.File = filepath.Join(this.Dir,
+		snap.Time.Format(snapTimeFmt_)+"-"+hash+".yml")
+	header := fmt.Sprintf("# config snapshot %s\n", snap)
+	err = writeAtomic(snap.File, append([]byte(header), content...), 0660)
+	if err != nil {
+		err = uerr.Chainf(err, "saving config snapshot")
+		return
+	}
+
+	snaps = append([]Snapshot{snap}, snaps...)
+	for _, old := range snaps[min(len(snaps), this.Keep):] {
+		err = os.Remove(old.File)
+		if err != nil && !os.IsNotExist(err) {
+			err = uerr.Chainf(err, "pruning config snapshot")
+			return
+		}
+		err = nil
+	}
+	return
+}
+
+// produce the snapshot content for the section without saving it
+func (this *History) Snapshot(s *Section) (content []byte, err error) {
+	secrets, secretVals := this.secretProps(s)
+	it, err := s.flatten(s.section, this.Redact, secretVals)
+	if err != nil {
+		err = uerr.Chainf(err, "flattening config for snapshot")
+		return
+	}
+	flat := it.(map[string]any)
+	//
+	// leave out built in properties (thisHost, ...) so they are set by the
+	// environment the snapshot is loaded in
+	//
+	builtin := newExpander(nil)
+	props := make(map[string]any, len(s.expander.mapping))
+	for k, v := range s.expander.mapping {
+		if bv, isBuiltin := builtin.mapping[k]; isBuiltin && bv == v {
+			continue
+		} else if secrets[k] || this.redactKey(k, v) {
+			props[k] = Redacted
+		} else {
+			props[k] = v
+		}
+	}
+	if 0 != len(props) {
+		flat[PROPS] = props
+	} else {
+		delete(flat, PROPS)
+	}
+	return yaml.Marshal(flat)
+}
+
+func (this *History) redactKey(key string, value string) bool {
+	return nil != this.Redact && this.Redact.MatchString(key) && !IsEncrypted(value)
+}
+
+// get the properties that must not appear in a snapshot, and their values:
+// those with keys matching Redact, and those with values built from them
+func (this *History) secretProps(s *Section) (keys map[string]bool, vals []string) {
+	keys = make(map[string]bool)
+	for k, v := range s.expander.mapping {
+		if 0 != len(v) && this.redactKey(k, v) {
+			keys[k] = true
+			vals = append(vals, v)
+		}
+	}
+	for added := 0 != len(vals); added; {
+		added = false
+		for k, v := range s.expander.mapping {
+			if !keys[k] && hasSecret(v, vals) {
+				keys[k] = true
+				vals = append(vals, v)
+				added = true
+			}
+		}
+	}
+	return
+}
+
+func hasSecret(value string, secrets []string) bool {
+	for _, secret := range secrets {
+		if strings.Contains(value, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// get the snapshots, newest first
+func (this *History) List() (snaps []Snapshot, err error) {
+	entries, err := os.ReadDir(this.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".yml") {
+			continue
+		}
+		stamp, hash, found := strings.Cut(strings.TrimSuffix(name, ".yml"), "-")
+		if !found {
+			continue
+		}
+		t, perr := time.Parse(snapTimeFmt_, stamp)
+		if perr != nil {
+			continue
+		}
+		snaps = append(snaps, Snapshot{
+			File: filepath.Join(this.Dir, name),
+			Time: t,
+			Hash: hash,
+		})
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Time.After(snaps[j].Time)
+	})
+	return
+}
+
+// find the snapshot identified by id, which may be:
+//   - "latest" or "" - the newest snapshot
+//   - "previous" - the one before that
+//   - N - the Nth newest snapshot, with 0 being the newest
+//   - a hash, or unique prefix of a hash
+//   - the path or name of the snapshot file
+func (this *History) Find(id string) (snap Snapshot, err error) {
+	snaps, err := this.List()
+	if err != nil {
+		return
+	}
+	index := -1
+	switch id {
+	case "", "latest":
+		index = 0
+	case "previous":
+		index = 1
+	default:
+		if n, aerr := strconv.Atoi(id); nil == aerr && 0 <= n {
+			index = n
+		}
+	}
+	if -1 != index {
+		if index >= len(snaps) {
+			err = fmt.Errorf("config snapshot %s not found: only %d snapshots",
+				id, len(snaps))
+			return
+		}
+		return snaps[index], nil
+	}
+	found := 0
+	for _, s := range snaps {
+		if strings.HasPrefix(s.Hash, id) || filepath.Base(id) == filepath.Base(s.File) {
+			snap = s
+			found++
+		}
+	}
+	if 0 == found {
+		err = fmt.Errorf("config snapshot %s not found", id)
+	} else if 1 < found {
+		err = fmt.Errorf("config snapshot %s is ambiguous", id)
+	}
+	return
+}
+
+// load the config snapshot identified by id, taking redacted properties from
+// live.  see Find and LoadSnapshot.
+func (this *History) Load(id string, live *Section) (s *Section, err error) {
+	snap, err := this.Find(id)
+	if err != nil {
+		return
+	}
+	return LoadSnapshot(snap.File, live)
+}
+
+// load the config snapshot file, taking the value of each redacted property
+// from live (such as the config in use), so that values referencing secrets
+// resolve as they did when the snapshot was taken.  live may be nil.
+func LoadSnapshot(file string, live *Section) (s *Section, err error) {
+	s, err = NewSection(file)
+	if err != nil || nil == live {
+		return
+	}
+	for k, v := range s.expander.mapping {
+		if Redacted == v {
+			if liveV, found := live.expander.mapping[k]; found {
+				s.expander.Set(k, liveV)
+			}
+		}
+	}
+	return
+}
+
+// produce a line by line diff of the snapshots identified by from and to.
+// see Find.
+func (this *History) Diff(from, to string) (diff string, err error) {
+	fromSnap, err := this.Find(from)
+	if err != nil {
+		return
+	}
+	toSnap, err := this.Find(to)
+	if err != nil {
+		return
+	}
+	fromC, err := os.ReadFile(fromSnap.File)
+	if err != nil {
+		return
+	}
+	toC, err := os.ReadFile(toSnap.File)
+	if err != nil {
+		return
+	}
+	diff = "--- " + fromSnap.String() + "\n+++ " + toSnap.String() + "\n" +
+		diffLines(snapLines(fromC), snapLines(toC))
+	return
+}
+
+// the lines of the snapshot, minus the header
+func snapLines(content []byte) []string {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if 0 != len(lines) && strings.HasPrefix(lines[0], "#") {
+		lines = lines[1:]
+	}
+	return lines
+}
+
+// produce a diff of the lines, showing changed lines with 2 lines of context
+func diffLines(a, b []string) string {
+	const context = 2
+	//
+	// longest common subsequence
+	//
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; 0 <= i; i-- {
+		for j := len(b) - 1; 0 <= j; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	type op_ struct {
+		kind byte
+		line string
+	}
+	var ops []op_
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			ops = append(ops, op_{' ', a[i]})
+			i++
+			j++
+		} else if i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]) {
+			ops = append(ops, op_{'-', a[i]})
+			i++
+		} else {
+			ops = append(ops, op_{'+', b[j]})
+			j++
+		}
+	}
+	//
+	// output changes with context
+	//
+	show := make([]bool, len(ops))
+	for k, op := range ops {
+		if ' ' != op.kind {
+			for n := max(0, k-context); n <= k+context && n < len(ops); n++ {
+				show[n] = true
+			}
+		}
+	}
+	var rv strings.Builder
+	for k, op := range ops {
+		if !show[k] {
+			continue
+		} else if 0 != k && !show[k-1] {
+			rv.WriteString("@@\n")
+		}
+		rv.WriteByte(op.kind)
+		rv.WriteString(op.line)
+		rv.WriteByte('\n')
+	}
+	return rv.String()
+}
+
+// produce a deep copy of it with properties expanded, includes flattened,
+// values of keys matching redact redacted, and values containing secrets left
+// unexpanded (or redacted, if they are not expansions)
+func (this *Section) flatten(
+	it any,
+	redact *regexp.Regexp,
+	secrets []string,
+) (
+	rv any,
+	err error,
+) {
+	switch v := it.(type) {
+	case map[string]any, map[any]any:
+		var m map[string]any
+		m, _, err = this.getMap(v, nil)
+		if err != nil {
+			return
+		}
+		flat := make(map[string]any, len(m))
+		for k, val := range m {
+			if include_ == k {
+				continue
+			} else if nil != redact && redact.MatchString(k) {
+				switch v := val.(type) {
+				case map[string]any, map[any]any, []any:
+				case string:
+					if resolved := this.expander.resolve(v); !IsEncrypted(resolved) {
+						flat[k] = snapSecret(v, resolved)
+						continue
+					}
+				default:
+					flat[k] = Redacted
+					continue
+				}
+			}
+			flat[k], err = this.flatten(val, redact, secrets)
+			if err != nil {
+				return
+			}
+		}
+		rv = flat
+	case []any:
+		var entries []map[string]any
+		var srcs []*srcPos_
+		flat := make([]any, 0, len(v))
+		for _, entry := range v {
+			if m, isMap := entry.(map[string]any); isMap {
+				entries = entries[:0]
+				var isInclude bool
+				isInclude, err = this.arrayEntryInclude(m, nil, &entries, &srcs)
+				if err != nil {
+					return
+				} else if isInclude {
+					for _, included := range entries {
+						var flatEntry any
+						flatEntry, err = this.flatten(included, redact, secrets)
+						if err != nil {
+							return
+						}
+						flat = append(flat, flatEntry)
+					}
+					continue
+				}
+			}
+			var flatEntry any
+			flatEntry, err = this.flatten(entry, redact, secrets)
+			if err != nil {
+				return
+			}
+			flat = append(flat, flatEntry)
+		}
+		rv = flat
+	case string:
+		rv = this.expander.resolve(v)
+		if hasSecret(rv.(string), secrets) {
+			rv = snapSecret(v, rv.(string))
+		}
+	default:
+		rv = it
+	}
+	return
+}
+
+// the snapshot value of a secret config value: the unexpanded value if it is
+// an expansion (such as '{{.dbPassword}}'), so that it is resolved again when
+// loaded, otherwise redacted
+func snapSecret(raw, resolved string) string {
+	if raw = strings.TrimSpace(raw); raw != resolved {
+		return raw
+	}
+	return Redacted
+}
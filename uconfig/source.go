@@ -0,0 +1,168 @@
+package uconfig
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tredeske/u/uerr"
+
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// name used for the source of YAML that was not loaded from a file
+const inlineSrc_ = "<inline>"
+
+// where in the YAML a config value came from
+//
+// this forms a tree that parallels the config maps and arrays so that
+// sub-sections and array entries can know where they came from
+type srcPos_ struct {
+	file  string
+	line  int
+	col   int
+	text  string              // if a scalar, the text as written
+	keys  map[string]*srcPos_ // if a mapping, the source of each member
+	items []*srcPos_          // if a sequence, the source of each member
+}
+
+// render as file:line:col
+func (this *srcPos_) String() string {
+	if nil == this {
+		return ""
+	}
+	var b strings.Builder
+	b.Grow(len(this.file) + 12)
+	b.WriteString(this.file)
+	b.WriteByte(':')
+	b.WriteString(strconv.Itoa(this.line))
+	b.WriteByte(':')
+	b.WriteString(strconv.Itoa(this.col))
+	return b.String()
+}
+
+// get the source of the named mapping member, if known
+func (this *srcPos_) key(k string) *srcPos_ {
+	if nil == this {
+		return nil
+	}
+	return this.keys[k]
+}
+
+// get the source of the i'th sequence member, if known
+func (this *srcPos_) item(i int) *srcPos_ {
+	if nil == this || 0 > i || len(this.items) <= i {
+		return nil
+	}
+	return this.items[i]
+}
+
+// record the source of the named mapping member
+func (this *srcPos_) setKey(k string, src *srcPos_) {
+	if nil == this.keys {
+		this.keys = make(map[string]*srcPos_)
+	}
+	this.keys[k] = src
+}
+
+// get the text of the scalar as written, or "" if not known
+func (this *srcPos_) scalar() string {
+	if nil == this {
+		return ""
+	}
+	return this.text
+}
+
+// produce " (file:line:col)" if the source is known, or "" if not
+func (this *srcPos_) suffix() string {
+	if nil == this {
+		return ""
+	}
+	return " (" + this.String() + ")"
+}
+
+// produce the source tree of the YAML content
+//
+// if the content cannot be parsed, then nil is returned, as it is expected
+// the caller will also parse the content and report on the problem
+func srcFromYaml(file string, content []byte) (rv *srcPos_) {
+	var doc yaml3.Node
+	err := yaml3.Unmarshal(content, &doc)
+	if err != nil || 0 == len(doc.Content) {
+		return nil
+	}
+	if 0 == len(file) {
+		file = inlineSrc_
+	}
+	return srcFromNode(file, doc.Content[0], doc.Content[0])
+}
+
+// convert the yaml.v3 node tree to a source tree
+//
+// at is the node that determines the reported position, which for mapping
+// members is the key rather than the value
+func srcFromNode(file string, at, n *yaml3.Node) (rv *srcPos_) {
+	rv = &srcPos_{
+		file: file,
+		line: at.Line,
+		col:  at.Column,
+	}
+	for yaml3.AliasNode == n.Kind && nil != n.Alias {
+		n = n.Alias
+	}
+	switch n.Kind {
+	case yaml3.MappingNode:
+		rv.keys = make(map[string]*srcPos_, len(n.Content)/2)
+		var merges []*yaml3.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if "!!merge" == k.Tag || "<<" == k.Value {
+				merges = append(merges, v)
+				continue
+			}
+			rv.keys[k.Value] = srcFromNode(file, k, v)
+		}
+		//
+		// merged in values do not override local ones
+		//
+		for _, m := range merges {
+			merged := srcFromNode(file, m, m)
+			for k, v := range merged.keys {
+				if _, found := rv.keys[k]; !found {
+					rv.keys[k] = v
+				}
+			}
+			for _, item := range merged.items { // <<: [*a, *b]
+				for k, v := range item.keys {
+					if _, found := rv.keys[k]; !found {
+						rv.keys[k] = v
+					}
+				}
+			}
+		}
+	case yaml3.SequenceNode:
+		rv.items = make([]*srcPos_, len(n.Content))
+		for i, item := range n.Content {
+			rv.items[i] = srcFromNode(file, item, item)
+		}
+	case yaml3.ScalarNode:
+		rv.text = n.Value
+	}
+	return
+}
+
+// load the YAML file into target, which may be a ptr to map, slice or
+// struct, also producing the source tree for the file
+func yamlLoadSrc(file string, target any) (src *srcPos_, err error) {
+	content, err := readConfig(file)
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(content, target)
+	if err != nil {
+		err = uerr.Chainf(err, "loading %s", file)
+		return
+	}
+	src = srcFromYaml(file, content)
+	return
+}
@@ -1,12 +1,14 @@
 package uconfig
 
 import (
+	"errors"
 	"fmt"
 	nurl "net/url"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/tredeske/u/ulog"
@@ -795,3 +797,242 @@ func TestPath(t *testing.T) {
 		t.Fatalf("Should have errored since '%s' empty", doesNotExist)
 	}
 }
+
+func TestSourcePositions(t *testing.T) {
+	dir := t.TempDir()
+	mainF := dir + "/main.yml"
+	includeF := dir + "/included.yml"
+	arrayF := dir + "/array.yml"
+
+	err := os.WriteFile(includeF, []byte(`
+fromInclude:    notAnInt
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(arrayF, []byte(`
+- name:         one
+- name:         two
+  port:         notAPort
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(mainF, []byte(`
+properties:
+    badPort:    "{{.port}}x"
+    port:       80
+include_:       `+includeF+`
+sub:
+    int:        1
+    bad:        "{{.badPort}}"
+    extra:      true
+array:
+- name:         zero
+- include_:     `+arrayF+`
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSection(mainF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectErr := func(err error, where string) {
+		t.Helper()
+		if nil == err {
+			t.Fatalf("should have failed at %s", where)
+		} else if !strings.Contains(err.Error(), where) {
+			t.Fatalf("error should contain %s: %s", where, err)
+		}
+	}
+
+	var i int
+	err = s.GetInt("fromInclude", &i)
+	expectErr(err, "included.yml:2:1")
+
+	var sub *Section
+	err = s.GetSection("sub", &sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sub.GetInt("int", &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sub.GetInt("bad", &i, IntPos()) // through property expansion
+	expectErr(err, "main.yml:8:5")
+	err = sub.OnlyKeys("bad")
+	expectErr(err, "extra (")
+	expectErr(err, "main.yml:9:5")
+
+	var array *Array
+	err = s.GetArray("array", &array)
+	if err != nil {
+		t.Fatal(err)
+	} else if 3 != array.Len() {
+		t.Fatalf("array should have 3 entries, has %d", array.Len())
+	}
+	err = array.Get(2).GetInt("port", &i)
+	expectErr(err, "array.yml:4:3")
+
+	err = array.Each(func(s *Section) error {
+		var name string
+		var port int
+		return s.Chain().
+			GetString("name", &name).
+			GetInt("port", &port).
+			Done()
+	})
+	expectErr(err, "section 2 (")
+	expectErr(err, "array.yml:3:3")
+}
+
+func TestChainAll(t *testing.T) {
+	s, err := NewSection(`
+int:            notAnInt
+pos:            -1
+str:            ""
+sub:
+    a:          1
+    b:          notAnInt
+array:
+- a:            notAnInt
+- a:            2
+- a:            -2
+extra:          true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i, pos, subA, subB int
+	var str string
+	err = s.ChainAll().
+		GetInt("int", &i).
+		GetInt("pos", &pos, IntPos()).
+		GetString("str", &str, StringNotBlank()).
+		If("sub", func(c *Chain) error {
+			return c.
+				GetInt("a", &subA).
+				GetInt("b", &subB).
+				Done()
+		}).
+		Each("array", func(c *Chain) error {
+			var a int
+			return c.GetInt("a", &a, IntPos()).Done()
+		}).
+		Done()
+	if nil == err {
+		t.Fatalf("should have failed")
+	}
+	var errs *ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("should be ValidationErrors: %T", err)
+	}
+	for _, expect := range []string{
+		"int", "pos", "String value empty", "sub.b", "array.0.a",
+		"array.2.a", "extra",
+	} {
+		found := false
+		for _, e := range errs.Errors {
+			if strings.Contains(e.Error(), expect) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("no error about %s in: %s", expect, err)
+		}
+	}
+	if 7 != len(errs.Errors) {
+		t.Fatalf("should be 7 errors, got %d: %s", len(errs.Errors), err)
+	} else if 1 != subA {
+		t.Fatalf("sub.a should have been set even though sub.b failed")
+	} else if !errors.Is(err, ErrStringBlank) {
+		t.Fatalf("errors.Is should find ErrStringBlank")
+	}
+
+	//
+	// normal chain stops at first
+	//
+	err = s.Chain().
+		GetInt("int", &i).
+		GetInt("pos", &pos, IntPos()).
+		Done()
+	if nil == err {
+		t.Fatalf("should have failed")
+	} else if errors.As(err, &errs) {
+		t.Fatalf("should not be ValidationErrors")
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Setenv("U_TEST_FUNCS", "fromEnv")
+	saved := templateFuncs()
+	t.Cleanup(func() {
+		funcsLock_.Lock()
+		funcs_ = saved
+		funcsLock_.Unlock()
+	})
+	AddFuncs(template.FuncMap{
+		"region": func() string { return "west" },
+	})
+	f := t.TempDir() + "/secret"
+	err := os.WriteFile(f, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSection(`
+properties:
+    basePort:   8000
+    env:        prod
+    secretF:    ` + f + `
+    adminPort:  "{{add .basePort 1}}"
+    blank:      ""
+env:            '{{env "U_TEST_FUNCS"}}'
+envDefault:     '{{env "U_TEST_NO_SUCH_VAR" "dflt"}}'
+upper:          '{{.env | upper}}'
+port:           "{{.adminPort}}"
+offset:         '{{mul (sub .basePort 7000) 2}}'
+isProd:         '{{if eq .env "prod"}}yes{{else}}no{{end}}'
+ternary:        '{{ternary "on" "off" true}}'
+dflt:           '{{default "filled" .blank}}'
+prop:           '{{prop "noSuchProp" "propDflt"}}'
+joined:         '{{split "," "a,b,c" | join ":"}}'
+secret:         '{{readFile .secretF}}'
+b64:            '{{b64enc "hello" | b64dec}}'
+custom:         '{{region}}'
+missing:        '{{readFile "/no/such/file"}}'
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expect := range map[string]string{
+		"env":        "fromEnv",
+		"envDefault": "dflt",
+		"upper":      "PROD",
+		"port":       "8001",
+		"offset":     "2000",
+		"isProd":     "yes",
+		"ternary":    "on",
+		"dflt":       "filled",
+		"prop":       "propDflt",
+		"joined":     "a:b:c",
+		"secret":     "s3cret",
+		"b64":        "hello",
+		"custom":     "west",
+		"missing":    `{{readFile "/no/such/file"}}`, // errors leave it as is
+	} {
+		var v string
+		err = s.GetString(key, &v)
+		if err != nil {
+			t.Fatal(err)
+		} else if expect != v {
+			t.Fatalf("%s: expected '%s', got '%s'", key, expect, v)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package uconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const editYaml_ = `# top comment
+
+properties:
+    basePort:   8000        # the base
+    oldProp:    remove me
+
+defaults: &defaults
+    timeout:    5s
+
+components:
+  # the http server
+  - name:       httpServer
+    type:       http
+    config:
+        <<:         *defaults
+        port:       8080    # listen here
+        hosts:
+        - a
+        - b
+
+  - name:       sftpServer
+    type:       sftp
+    config:
+        port:       "22"
+`
+
+func TestEdit(t *testing.T) {
+	f := t.TempDir() + "/edit.yml"
+	err := os.WriteFile(f, []byte(editYaml_), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := EditFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectContains := func(what ...string) {
+		t.Helper()
+		content := string(ed.Bytes())
+		for _, w := range what {
+			if !strings.Contains(content, w) {
+				t.Fatalf("edited config missing '%s':\n%s", w, content)
+			}
+		}
+	}
+	expectValue := func(path string, expect any) {
+		t.Helper()
+		v, found, err := ed.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		} else if !found {
+			t.Fatalf("%s not found", path)
+		} else if expect != v {
+			t.Fatalf("%s: expected %#v, got %#v", path, expect, v)
+		}
+	}
+
+	//
+	// change scalar in place
+	//
+	err = ed.Set("components.name=httpServer.config.port", 8443)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContains("        port:       8443    # listen here\n",
+		"# top comment", "# the http server", "&defaults", "*defaults")
+	expectValue("components.0.config.port", 8443)
+	expectValue("components.0.config.timeout", "5s")
+
+	err = ed.Set("components.1.config.port", "2222")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContains(`        port:       "2222"` + "\n")
+
+	//
+	// add new keys, including nested ones
+	//
+	err = ed.Set("components.name=sftpServer.disabled", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContains("    type:       sftp\n", "\n    disabled: true\n")
+	expectValue("components.1.disabled", true)
+
+	err = ed.Set("components.name=httpServer.config.tls.cert", "my-cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContains("\n        tls:\n            cert: my-cert\n")
+	expectValue("components.0.config.tls.cert", "my-cert")
+
+	//
+	// replace scalar with a sequence, and append to sequence
+	//
+	err = ed.Set("components.0.config.hosts.2", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContains("        - b\n        - c\n")
+
+	//
+	// delete
+	//
+	found, err := ed.Delete("properties.oldProp")
+	if err != nil {
+		t.Fatal(err)
+	} else if !found {
+		t.Fatalf("should have found oldProp")
+	} else if strings.Contains(string(ed.Bytes()), "oldProp") {
+		t.Fatalf("oldProp not deleted:\n%s", ed.Bytes())
+	}
+	expectContains("    basePort:   8000        # the base\n")
+	found, err = ed.Delete("properties.noSuchProp")
+	if err != nil {
+		t.Fatal(err)
+	} else if found {
+		t.Fatalf("should not have found noSuchProp")
+	}
+	for _, path := range []string{
+		"components.name=noSuchServer",
+		"components.99",
+		"components.-1",
+		"components.first",
+		"properties.basePort.sub",
+	} {
+		found, err = ed.Delete(path)
+		if err != nil {
+			t.Fatalf("delete %s: %s", path, err)
+		} else if found {
+			t.Fatalf("should not have found %s", path)
+		}
+	}
+
+	_, err = ed.Delete("components.name=sftpServer")
+	if err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(ed.Bytes()), "sftp") {
+		t.Fatalf("sftpServer not deleted:\n%s", ed.Bytes())
+	}
+
+	//
+	// cannot edit through alias
+	//
+	err = ed.Set("components.0.config.<<.timeout", "1s")
+	if nil == err {
+		t.Fatalf("should not be able to edit through alias")
+	}
+
+	//
+	// save and verify it loads as config
+	//
+	err = ed.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	} else if 0640 != stat.Mode().Perm() {
+		t.Fatalf("perms not preserved: %o", stat.Mode().Perm())
+	}
+	s, err := NewSection(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var array *Array
+	err = s.GetArray("components", &array)
+	if err != nil {
+		t.Fatal(err)
+	} else if 1 != array.Len() {
+		t.Fatalf("should be 1 component, got %d:\n%s", array.Len(), ed.Bytes())
+	}
+	var port int
+	err = array.Get(0).GetChain("config").GetInt("port", &port).Error
+	if err != nil {
+		t.Fatal(err)
+	} else if 8443 != port {
+		t.Fatalf("port should be 8443, is %d", port)
+	}
+}
@@ -0,0 +1,60 @@
+package uconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHelpDoc(t *testing.T) {
+	help := &Help{}
+	p := help.Init("server", "Serves things.\n\nSee also: client")
+	p.NewItem("port", "int", "port to listen on").Default(8080)
+	p.NewItem("mode", "string", "how fast | how slow").Choices("fast", "slow").
+		Optional()
+	p.NewItem("timeout", "duration", `
+How long to wait.
+.  Dots are ok`).Default(3 * time.Second)
+	certs := p.NewItem("certs", "[]cert", "TLS certs")
+	certs.NewItem("name", "string", "name of cert")
+	certs.NewItem("file", "string", "path-to-cert").Set("units", "PEM")
+	limits := p.AddSub("limits")
+	limits.NewItem("rate", "int", "max per second").Optional()
+
+	md, err := help.AsMarkdown()
+	if err != nil {
+		t.Fatalf("Unable to render markdown: %s", err)
+	}
+	for _, expect := range []string{
+		"# server\n\nServes things.\n\nSee also: client\n\n",
+		"| Key | Type | Default | Optional | Note |\n",
+		"| `port` | int | `8080` | yes | port to listen on |\n",
+		"| `mode` | string |  | yes | how fast \\| how slow<br><br>One of: fast, slow |\n",
+		"| `timeout` | duration | `3s` | yes | How long to wait.<br>.  Dots are ok |\n",
+		"## certs\n\n| Key |",
+		"| `file` | string |  |  | path-to-cert<br><br>units: PEM |\n",
+		"## limits\n\n",
+		"| `rate` | int |  | yes | max per second |\n",
+	} {
+		if !strings.Contains(string(md), expect) {
+			t.Fatalf("markdown missing %q:\n%s", expect, md)
+		}
+	}
+
+	man, err := help.AsMan("myprog", 5)
+	if err != nil {
+		t.Fatalf("Unable to render man: %s", err)
+	}
+	for _, expect := range []string{
+		".TH \"MYPROG\" \"5\"\n.SH NAME\nmyprog \\- configuration\n",
+		".SS server\n.PP\nServes things.\n.sp\nSee also: client\n",
+		".TP\n.B port\n(int, default: 8080)\n.br\nport to listen on\n",
+		".TP\n.B certs.file\n(string)\n.br\npath\\-to\\-cert\n",
+		"\\&.  Dots are ok\n",
+		".TP\n.B limits.rate\n(int, optional)\n",
+	} {
+		if !strings.Contains(string(man), expect) {
+			t.Fatalf("man missing %q:\n%s", expect, man)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package uconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRemoteInclude(t *testing.T) {
+	var content atomic.Value
+	content.Store("fromRemote: v1\n")
+	var gets, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			gets.Add(1)
+			if "/array.yml" == r.URL.Path {
+				w.Write([]byte("- name: remote0\n- name: remote1\n"))
+				return
+			}
+			body := content.Load().(string)
+			etag := `"` + body[len(body)-3:len(body)-1] + `"`
+			if etag == r.Header.Get("If-None-Match") {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write([]byte(body))
+		}))
+	defer server.Close()
+
+	RemoteCacheD = t.TempDir()
+	defer func() { RemoteCacheD = "" }()
+
+	config := `
+include_:       ` + server.URL + `/base.yml
+array:
+- name:         local
+- include_:     ` + server.URL + `/array.yml
+`
+	load := func(expect string) {
+		t.Helper()
+		s, err := NewSection(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v string
+		err = s.GetString("fromRemote", &v)
+		if err != nil {
+			t.Fatal(err)
+		} else if expect != v {
+			t.Fatalf("expected %s, got %s", expect, v)
+		}
+		var array *Array
+		err = s.GetArray("array", &array)
+		if err != nil {
+			t.Fatal(err)
+		} else if 3 != array.Len() {
+			t.Fatalf("array should have 3 entries, has %d", array.Len())
+		}
+		err = array.Get(2).GetString("name", &v)
+		if err != nil {
+			t.Fatal(err)
+		} else if "remote1" != v {
+			t.Fatalf("expected remote1, got %s", v)
+		}
+	}
+
+	load("v1")
+	load("v1")
+	if 0 == notModified.Load() {
+		t.Fatalf("second load should have used If-None-Match")
+	}
+
+	//
+	// detect change as the watcher would
+	//
+	url := server.URL + "/base.yml"
+	_, changed, err := fetchRemote(url)
+	if err != nil {
+		t.Fatal(err)
+	} else if changed {
+		t.Fatalf("should not have changed")
+	}
+	content.Store("fromRemote: v2\n")
+	_, changed, err = fetchRemote(url)
+	if err != nil {
+		t.Fatal(err)
+	} else if !changed {
+		t.Fatalf("should have changed")
+	}
+	load("v2")
+
+	//
+	// config server down - use cache
+	//
+	server.Close()
+	load("v2")
+
+	//
+	// no cache and no server - fail
+	//
+	RemoteCacheD = t.TempDir()
+	_, err = NewSection(config)
+	if nil == err {
+		t.Fatalf("should fail with no server and no cache")
+	} else if !strings.Contains(err.Error(), "base.yml") {
+		t.Fatalf("error should mention url: %s", err)
+	}
+}
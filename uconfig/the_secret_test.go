@@ -0,0 +1,180 @@
+package uconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	secretLock_.Lock()
+	savedAead, savedTried := secretAead_, secretTried_
+	secretLock_.Unlock()
+	t.Cleanup(func() {
+		secretLock_.Lock()
+		secretAead_, secretTried_ = savedAead, savedTried
+		secretLock_.Unlock()
+	})
+	key, err := NewSecretKey()
+	if err != nil {
+		t.Fatalf("Unable to generate key: %s", err)
+	}
+	keyF := t.TempDir() + "/secret.key"
+	err = os.WriteFile(keyF, []byte(key+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = LoadSecretKey(keyF)
+	if err != nil {
+		t.Fatalf("Unable to load key: %s", err)
+	}
+
+	encPass, err := Encrypt("hunter2")
+	if err != nil {
+		t.Fatalf("Unable to encrypt: %s", err)
+	} else if !strings.HasPrefix(encPass, "ENC[aes256gcm,") || IsEncrypted("hunter2") {
+		t.Fatalf("Encrypted wrong: %s", encPass)
+	}
+	encPort, err := Encrypt("5432")
+	if err != nil {
+		t.Fatalf("Unable to encrypt: %s", err)
+	}
+
+	config := `
+properties:
+    dbPass:     ` + encPass + `
+dsn:            'postgres://me:{{.dbPass}}@db'
+port:           ` + encPort + `
+list:           [plain, '` + encPass + `']
+`
+	s, err := NewSection(config)
+	if err != nil {
+		t.Fatalf("Unable to load config with secrets: %s", err)
+	}
+	var dsn string
+	var port int
+	var list []string
+	err = s.Chain().
+		GetString("dsn", &dsn).
+		GetInt("port", &port).
+		GetStrings("list", &list).
+		Error
+	if err != nil {
+		t.Fatalf("Unable to get secrets: %s", err)
+	} else if "postgres://me:hunter2@db" != dsn {
+		t.Fatalf("dsn not decrypted: %s", dsn)
+	} else if 5432 != port {
+		t.Fatalf("port not decrypted: %d", port)
+	} else if 2 != len(list) || "hunter2" != list[1] {
+		t.Fatalf("list not decrypted: %v", list)
+	} else if "hunter2" != s.Prop("dbPass") {
+		t.Fatalf("prop not decrypted: %s", s.Prop("dbPass"))
+	}
+
+	//
+	// dumps should not reveal secrets
+	//
+	snapshot, err := NewHistory(t.TempDir(), 2).Snapshot(s)
+	if err != nil {
+		t.Fatalf("Unable to snapshot: %s", err)
+	}
+	for what, dump := range map[string]string{
+		"props":    s.DumpProps(),
+		"vals":     s.DumpVals(),
+		"snapshot": string(snapshot),
+	} {
+		if strings.Contains(dump, "hunter2") || strings.Contains(dump, "5432") {
+			t.Fatalf("%s dump reveals secrets: %s", what, dump)
+		}
+	}
+	if !strings.Contains(string(snapshot), encPass) {
+		t.Fatalf("snapshot should keep encrypted value: %s", snapshot)
+	}
+
+	//
+	// editor can encrypt in place
+	//
+	ed, err := EditYaml([]byte(`
+# the db
+db:
+    user:   me      # who
+    pass:   hunter2 # shh
+    port:   5432
+`))
+	if err != nil {
+		t.Fatalf("Unable to create editor: %s", err)
+	}
+	count, err := ed.Encrypt("db")
+	if err != nil {
+		t.Fatalf("Unable to encrypt section: %s", err)
+	} else if 3 != count {
+		t.Fatalf("Should have encrypted 3 values, not %d", count)
+	}
+	edited := string(ed.Bytes())
+	if strings.Contains(edited, "hunter2") || !strings.Contains(edited, "# shh") {
+		t.Fatalf("Section not encrypted correctly:\n%s", edited)
+	}
+	count, err = ed.Encrypt("db")
+	if err != nil {
+		t.Fatalf("Unable to re-encrypt section: %s", err)
+	} else if 0 != count {
+		t.Fatalf("Already encrypted values encrypted again: %d", count)
+	}
+	s, err = NewSection(ed.Bytes())
+	if err != nil {
+		t.Fatalf("Unable to load edited: %s", err)
+	}
+	var db *Section
+	var pass string
+	err = s.Chain().
+		GetSection("db", &db).
+		Error
+	if nil == err {
+		err = db.GetString("pass", &pass)
+	}
+	if err != nil {
+		t.Fatalf("Unable to get pass: %s", err)
+	} else if "hunter2" != pass {
+		t.Fatalf("pass not decrypted: %s", pass)
+	}
+
+	//
+	// the wrong key should be detected at load
+	//
+	other, err := NewSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetSecretKey([]byte(other))
+	if err != nil {
+		t.Fatalf("Unable to set key: %s", err)
+	}
+	_, err = NewSection(config)
+	if nil == err {
+		t.Fatalf("Should fail to load with wrong key")
+	} else if !strings.Contains(err.Error(), "dbPass") &&
+		!strings.Contains(err.Error(), "port") {
+		t.Fatalf("Error should mention key: %s", err)
+	}
+
+	//
+	// malformed values are detected anywhere in the tree, including in
+	// included files
+	//
+	includeF := t.TempDir() + "/include.yml"
+	err = os.WriteFile(includeF, []byte("pass: ENC[aes256gcm,bad]\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		"list: [plain, 'ENC[aes256gcm,bad]']",
+		"list:\n  - pass: ENC[aes256gcm,bad]",
+		"db:\n  sub:\n    include_: " + includeF,
+		"list:\n  - include_: " + includeF,
+	} {
+		_, err = NewSection(bad)
+		if nil == err {
+			t.Fatalf("Should fail to load malformed value in:\n%s", bad)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package uconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	includeF := dir + "/include.yml"
+	err := os.WriteFile(includeF, []byte("fromInclude: included\n"), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configF := dir + "/config.yml"
+	config := func(port string) {
+		err := os.WriteFile(configF, []byte(`
+properties:
+    port:       `+port+`
+    dbPassword: hunter2
+    dbUrl:      "postgres://u:{{.dbPassword}}@h/db"
+include_:       `+includeF+`
+listen:         ":{{.port}}"
+password:       '{{.dbPassword}}'
+dsn:            'postgres://u:{{.dbPassword}}@h/db'
+url:            '{{.dbUrl}}'
+components:
+  - name:       one
+    config:
+        apiToken:   abc123
+        hosts:      [a, b]
+`), 0664)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHistory(dir+"/history", 2)
+	snaps, err := h.List()
+	if err != nil {
+		t.Fatalf("Unable to list empty history: %s", err)
+	} else if 0 != len(snaps) {
+		t.Fatalf("Should be no snapshots, got %d", len(snaps))
+	}
+
+	save := func(port string) (snap Snapshot) {
+		config(port)
+		s, err := NewSection(configF)
+		if err != nil {
+			t.Fatalf("Unable to load config: %s", err)
+		}
+		snap, err = h.Save(s)
+		if err != nil {
+			t.Fatalf("Unable to save snapshot: %s", err)
+		}
+		return
+	}
+
+	first := save("8080")
+	content, err := os.ReadFile(first.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := string(content)
+	for _, expect := range []string{"fromInclude: included", `listen: :8080`,
+		"password: '{{.dbPassword}}'", "apiToken: " + Redacted,
+		"dbPassword: " + Redacted, "dbUrl: " + Redacted,
+		"dsn: postgres://u:{{.dbPassword}}@h/db", "url: '{{.dbUrl}}'"} {
+		if !strings.Contains(snapshot, expect) {
+			t.Fatalf("Snapshot missing '%s':\n%s", expect, snapshot)
+		}
+	}
+	if strings.Contains(snapshot, "hunter2") || strings.Contains(snapshot, "abc123") {
+		t.Fatalf("Snapshot has secrets:\n%s", snapshot)
+	} else if strings.Contains(snapshot, "include_") {
+		t.Fatalf("Snapshot has include:\n%s", snapshot)
+	} else if strings.Contains(snapshot, "thisHost") {
+		t.Fatalf("Snapshot has built in props:\n%s", snapshot)
+	}
+
+	//
+	// unchanged config should not produce a new snapshot
+	//
+	same := save("8080")
+	if same != first {
+		t.Fatalf("Unchanged config produced new snapshot %s", same)
+	}
+
+	second := save("9090")
+	third := save("7070")
+	if second.Hash == first.Hash || third.Hash == second.Hash {
+		t.Fatalf("Changed config did not produce new snapshots")
+	}
+	snaps, err = h.List()
+	if err != nil {
+		t.Fatalf("Unable to list history: %s", err)
+	} else if 2 != len(snaps) {
+		t.Fatalf("Should keep 2 snapshots, got %d", len(snaps))
+	} else if snaps[0] != third || snaps[1] != second {
+		t.Fatalf("Snapshots not newest first: %v", snaps)
+	}
+
+	for id, expect := range map[string]Snapshot{
+		"":             third,
+		"latest":       third,
+		"previous":     second,
+		"1":            second,
+		second.Hash:    second,
+		third.File:     third,
+		third.Hash[:8]: third,
+	} {
+		snap, err := h.Find(id)
+		if err != nil {
+			t.Fatalf("Unable to find snapshot '%s': %s", id, err)
+		} else if snap != expect {
+			t.Fatalf("Find '%s' got %s, expected %s", id, snap, expect)
+		}
+	}
+	_, err = h.Find(first.Hash)
+	if nil == err {
+		t.Fatalf("Pruned snapshot should not be found")
+	}
+
+	diff, err := h.Diff("previous", "latest")
+	if err != nil {
+		t.Fatalf("Unable to diff: %s", err)
+	} else if !strings.Contains(diff, "-listen: :9090\n+listen: :7070\n") {
+		t.Fatalf("Diff wrong:\n%s", diff)
+	}
+
+	//
+	// a snapshot can be loaded as config, with secrets from the live config
+	//
+	live, err := NewSection(configF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := h.Load("previous", live)
+	if err != nil {
+		t.Fatalf("Unable to load snapshot: %s", err)
+	}
+	for key, expect := range map[string]string{
+		"password": "hunter2",
+		"dsn":      "postgres://u:hunter2@h/db",
+		"url":      "postgres://u:hunter2@h/db",
+	} {
+		var v string
+		err = s.GetString(key, &v)
+		if err != nil {
+			t.Fatal(err)
+		} else if expect != v {
+			t.Fatalf("%s: expected '%s', got '%s'", key, expect, v)
+		}
+	}
+	var listen, included string
+	err = s.GetString("listen", &listen)
+	if nil == err {
+		err = s.GetString("fromInclude", &included)
+	}
+	if err != nil {
+		t.Fatalf("Unable to get from snapshot: %s", err)
+	} else if ":9090" != listen || "9090" != s.Prop("port") ||
+		"included" != included {
+		t.Fatalf("Snapshot loaded wrong: listen=%s, port=%s", listen, s.Prop("port"))
+	}
+}
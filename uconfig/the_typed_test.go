@@ -0,0 +1,180 @@
+package uconfig
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(t *testing.T) {
+	s, err := NewSection(`
+ip:         10.1.2.3
+cidr:       10.1.0.0/16
+nets:       [192.168.0.0/24, "fe80::/10", 10.9.9.9]
+netStr:     "1.2.3.0/24, 5.6.7.8"
+hostPort:   myhost
+portRange:  8000-8099
+port:       9000
+mode:       0640
+modeStr:    "2775"
+tod:        "08:30"
+window:     "22:00-06:00"
+weekdays:   mon-fri
+weekend:    [sat, Sunday]
+location:   UTC
+dscp:       AF41
+dscpNum:    0x90
+enum:       fast
+`)
+	if err != nil {
+		t.Fatalf("Unable to create section: %s", err)
+	}
+
+	var ip net.IP
+	var cidr *net.IPNet
+	var nets, netStr []*net.IPNet
+	var hostPort string
+	var dfltHostPort = ":77"
+	var portRange, port PortRange
+	var mode, modeStr os.FileMode
+	var tod TimeOfDay
+	var window TimeWindow
+	var weekdays, weekend Weekdays
+	var location *time.Location
+	var dscp, dscpNum byte
+	enum := "slow"
+	dfltEnum := "slow"
+
+	err = s.Chain().
+		GetIP("ip", &ip).
+		GetCIDR("cidr", &cidr).
+		GetIPNets("nets", &nets).
+		GetIPNets("netStr", &netStr).
+		GetHostPort("hostPort", "80", &hostPort).
+		GetHostPort("noHostPort", "80", &dfltHostPort).
+		GetPortRange("portRange", &portRange).
+		GetPortRange("port", &port).
+		GetFileMode("mode", &mode).
+		GetFileMode("modeStr", &modeStr).
+		GetTimeOfDay("tod", &tod).
+		GetTimeWindow("window", &window).
+		GetWeekdays("weekdays", &weekdays).
+		GetWeekdays("weekend", &weekend).
+		GetLocation("location", &location).
+		GetDscp("dscp", &dscp).
+		GetDscp("dscpNum", &dscpNum).
+		GetEnum("enum", &enum, "fast", "slow").
+		GetEnum("noEnum", &dfltEnum, "fast", "slow").
+		Done()
+	if err != nil {
+		t.Fatalf("Unable to get typed values: %s", err)
+	}
+
+	if !ip.Equal(net.ParseIP("10.1.2.3")) {
+		t.Fatalf("ip is %s", ip)
+	} else if "10.1.0.0/16" != cidr.String() {
+		t.Fatalf("cidr is %s", cidr)
+	} else if 3 != len(nets) || "10.9.9.9/32" != nets[2].String() ||
+		"fe80::/10" != nets[1].String() {
+		t.Fatalf("nets is %v", nets)
+	} else if 2 != len(netStr) || !netStr[0].Contains(net.ParseIP("1.2.3.4")) {
+		t.Fatalf("netStr is %v", netStr)
+	} else if "myhost:80" != hostPort {
+		t.Fatalf("hostPort is %s", hostPort)
+	} else if ":77" != dfltHostPort {
+		t.Fatalf("dfltHostPort is %s", dfltHostPort)
+	} else if 100 != portRange.Len() || !portRange.Contains(8099) ||
+		portRange.Contains(8100) {
+		t.Fatalf("portRange is %s", portRange)
+	} else if 9000 != port.Low || 9000 != port.High {
+		t.Fatalf("port is %s", port)
+	} else if 0640 != mode {
+		t.Fatalf("mode is %s", mode)
+	} else if 0775|os.ModeSetgid != modeStr {
+		t.Fatalf("modeStr is %s", modeStr)
+	} else if TimeOfDay(8*time.Hour+30*time.Minute) != tod || "08:30" != tod.String() {
+		t.Fatalf("tod is %s", tod)
+	} else if "mon,tue,wed,thu,fri" != weekdays.String() || Weekend != weekend {
+		t.Fatalf("weekdays is %s, weekend is %s", weekdays, weekend)
+	} else if time.UTC.String() != location.String() {
+		t.Fatalf("location is %s", location)
+	} else if 0x88 != dscp || 0x90 != dscpNum {
+		t.Fatalf("dscp is %x, dscpNum is %x", dscp, dscpNum)
+	} else if "fast" != enum || "slow" != dfltEnum {
+		t.Fatalf("enum is %s, dfltEnum is %s", enum, dfltEnum)
+	}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !window.Contains(day.Add(23*time.Hour)) ||
+		!window.Contains(day.Add(5*time.Hour)) ||
+		window.Contains(day.Add(12*time.Hour)) {
+		t.Fatalf("window %s wrong", window)
+	}
+
+	//
+	// bad values should get errors that mention the key
+	//
+	bad := []string{
+		"ip: 10.1.2",
+		"cidr: 10.1.0.0/33",
+		"nets: [10.0.0.0/8, nope]",
+		"hostPort: 'host:99999'",
+		"portRange: 9000-8000",
+		"mode: 'rwx'",
+		"mode: 017777",
+		"mode: 644",
+		"tod: '25:00'",
+		"window: '08:00'",
+		"weekdays: mon-funday",
+		"location: Nowhere/Special",
+		"dscp: AF99",
+		"enum: medium",
+	}
+	for _, yaml := range bad {
+		s, err = NewSection(yaml)
+		if err != nil {
+			t.Fatalf("Unable to create section for %s: %s", yaml, err)
+		}
+		key, _, _ := strings.Cut(yaml, ":")
+		err = s.ChainAll().
+			GetIP("ip", &ip).
+			GetCIDR("cidr", &cidr).
+			GetIPNets("nets", &nets).
+			GetHostPort("hostPort", "80", &hostPort).
+			GetPortRange("portRange", &portRange).
+			GetFileMode("mode", &mode).
+			GetTimeOfDay("tod", &tod).
+			GetTimeWindow("window", &window).
+			GetWeekdays("weekdays", &weekdays).
+			GetLocation("location", &location).
+			GetDscp("dscp", &dscp).
+			GetEnum("enum", &enum, "fast", "slow").
+			Done()
+		if nil == err {
+			t.Fatalf("Did not get error for %s", yaml)
+		} else if !strings.Contains(err.Error(), key) {
+			t.Fatalf("Error for %s does not mention key: %s", yaml, err)
+		}
+	}
+
+	//
+	// GetEnum records choices in help
+	//
+	h := &Help{}
+	h.NewItem("enum", "string", "how fast")
+	var speed, power string
+	h.Chain().
+		GetEnum("enum", &speed, "fast", "slow").
+		GetEnum("power", &power, "on", "off")
+	content, err := h.AsYaml()
+	if err != nil {
+		t.Fatalf("Unable to render help: %s", err)
+	} else if !strings.Contains(string(content),
+		"note: how fast\n  choices:\n  - fast\n  - slow\n") ||
+		!strings.Contains(string(content), "power:") ||
+		!strings.Contains(string(content), "- \"off\"") {
+		t.Fatalf("choices not in help:\n%s", content)
+	}
+}
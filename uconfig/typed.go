@@ -0,0 +1,534 @@
+package uconfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tredeske/u/uerr"
+	"github.com/tredeske/u/unet"
+)
+
+//
+// getters for values that are commonly configured as strings but need to be
+// parsed into network and system types
+//
+
+// if found and not blank, parse to an IP (v4 or v6) and set result
+func (this *Section) GetIP(key string, result *net.IP) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found || 0 == len(raw) {
+		return
+	}
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if nil == ip {
+		err = fmt.Errorf("parsing config: %s=%s: not a valid IP", this.ctx(key), raw)
+		return
+	}
+	*result = ip
+	return
+}
+
+// parse the CIDR (1.2.3.0/24, fe80::/10).  a bare IP is treated as a single
+// host network (/32 or /128).
+func ParseCIDR(s string) (rv *net.IPNet, err error) {
+	s = strings.TrimSpace(s)
+	if -1 == strings.IndexByte(s, '/') {
+		ip := net.ParseIP(s)
+		if nil == ip {
+			err = fmt.Errorf("%s is not a valid CIDR or IP", s)
+			return
+		}
+		bits := 128
+		if ip4 := ip.To4(); nil != ip4 {
+			ip, bits = ip4, 32
+		}
+		rv = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return
+	}
+	_, rv, err = net.ParseCIDR(s)
+	return
+}
+
+// if found and not blank, parse to CIDR and set result.  see ParseCIDR.
+func (this *Section) GetCIDR(key string, result **net.IPNet) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found || 0 == len(raw) {
+		return
+	}
+	*result, err = ParseCIDR(raw)
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+	}
+	return
+}
+
+// if found, parse a list of CIDRs and set result.  the value may be a YAML
+// list or a string of comma or space separated CIDRs.  see ParseCIDR.
+func (this *Section) GetIPNets(key string, result *[]*net.IPNet) (err error) {
+	this.track(key)
+	it, found := this.getIt(key, false)
+	if !found {
+		return
+	}
+	strs, err := this.toStrings(key, it, nil)
+	if err != nil {
+		return
+	}
+	var nets []*net.IPNet
+	for _, s := range strs {
+		for _, field := range strings.FieldsFunc(s, isListSep) {
+			var ipNet *net.IPNet
+			ipNet, err = ParseCIDR(field)
+			if err != nil {
+				err = uerr.Chainf(err, "parsing config: %s", this.ctx(key))
+				return
+			}
+			nets = append(nets, ipNet)
+		}
+	}
+	*result = nets
+	return
+}
+
+func isListSep(r rune) bool {
+	return ',' == r || ' ' == r || '\t' == r
+}
+
+// if found, parse to HOST:PORT and set result, filling in defaultPort if no
+// port is specified.  if not found and result is not blank, then result is
+// also filled in.  see EnsureAddr.
+//
+// HOST may be left off (:PORT) to indicate all interfaces, such as for a
+// listen address.
+func (this *Section) GetHostPort(
+	key, defaultPort string,
+	result *string,
+) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil {
+		return
+	} else if !found {
+		if 0 == len(*result) {
+			return
+		}
+		raw = *result
+	}
+	const anyHost = "0.0.0.0"
+	raw = strings.TrimSpace(raw)
+	addr, err := EnsureAddr(anyHost, defaultPort, raw)
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+		return
+	} else if !strings.HasPrefix(raw, anyHost) {
+		addr = strings.TrimPrefix(addr, anyHost)
+	}
+	*result = addr
+	return
+}
+
+// an inclusive range of ports
+type PortRange struct {
+	Low, High int
+}
+
+// parse LOW-HIGH or PORT
+func ParsePortRange(s string) (rv PortRange, err error) {
+	s = strings.TrimSpace(s)
+	low, high, isRange := strings.Cut(s, "-")
+	rv.Low, err = parsePort(low)
+	if err != nil {
+		return
+	} else if !isRange {
+		rv.High = rv.Low
+		return
+	}
+	rv.High, err = parsePort(high)
+	if nil == err && rv.High < rv.Low {
+		err = fmt.Errorf("port range %s is backwards", s)
+	}
+	return
+}
+
+func parsePort(s string) (port int, err error) {
+	port, err = strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || 0 >= port || 65535 < port {
+		err = fmt.Errorf("port (%s) not a number from 1 to 65535", s)
+	}
+	return
+}
+
+// is the port in the range?
+func (this PortRange) Contains(port int) bool {
+	return this.Low <= port && port <= this.High
+}
+
+// the number of ports in the range
+func (this PortRange) Len() int {
+	if 0 == this.Low {
+		return 0
+	}
+	return this.High - this.Low + 1
+}
+
+func (this PortRange) String() string {
+	if this.Low == this.High {
+		return strconv.Itoa(this.Low)
+	}
+	return strconv.Itoa(this.Low) + "-" + strconv.Itoa(this.High)
+}
+
+// if found, parse to a port range (8000-8099, or just 8000) and set result
+func (this *Section) GetPortRange(key string, result *PortRange) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found {
+		return
+	}
+	*result, err = ParsePortRange(raw)
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+	}
+	return
+}
+
+// if found, parse to file permissions and set result.
+//
+// strings are always octal (644 and 0644 are the same).  YAML ints must be
+// written with a leading 0 to be octal, so an unquoted 644 is rejected rather
+// than taken as decimal.
+func (this *Section) GetFileMode(key string, result *os.FileMode) (err error) {
+	this.track(key)
+	it, found := this.getIt(key, false)
+	if !found {
+		return
+	}
+	var mode uint64
+	switch v := it.(type) {
+	case int:
+		if 0 > v {
+			err = errors.New("negative file mode")
+		} else if text := this.src.key(key).scalar(); 0 != v &&
+			0 != len(text) && '0' != text[0] {
+			err = fmt.Errorf("file mode %s is not octal - use 0%s or quote it",
+				text, text)
+		}
+		mode = uint64(v)
+	case string:
+		s := strings.TrimPrefix(strings.TrimSpace(v), "0o")
+		mode, err = strconv.ParseUint(s, 8, 32)
+	default:
+		err = fmt.Errorf("unable to convert %T to file mode", it)
+	}
+	if nil == err && 07777 < mode {
+		err = fmt.Errorf("file mode %o has more than permission bits", mode)
+	}
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%v", this.ctx(key), it)
+		return
+	}
+	*result = os.FileMode(mode & 0777)
+	if 0 != mode&04000 {
+		*result |= os.ModeSetuid
+	}
+	if 0 != mode&02000 {
+		*result |= os.ModeSetgid
+	}
+	if 0 != mode&01000 {
+		*result |= os.ModeSticky
+	}
+	return
+}
+
+// a wall clock time of day, as an offset from midnight
+type TimeOfDay time.Duration
+
+// parse HH:MM or HH:MM:SS (24 hour clock).  24:00 is allowed for end of day.
+func ParseTimeOfDay(s string) (rv TimeOfDay, err error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if 2 != len(parts) && 3 != len(parts) {
+		err = fmt.Errorf("time of day (%s) not HH:MM or HH:MM:SS", s)
+		return
+	}
+	limits := []int{24, 59, 59}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, part := range parts {
+		var n int
+		n, err = strconv.Atoi(part)
+		if err != nil || 0 > n || limits[i] < n || 0 == len(part) {
+			err = fmt.Errorf("time of day (%s) not HH:MM or HH:MM:SS", s)
+			return
+		}
+		d += time.Duration(n) * units[i]
+	}
+	if 24*time.Hour < d {
+		err = fmt.Errorf("time of day (%s) past end of day", s)
+		return
+	}
+	rv = TimeOfDay(d)
+	return
+}
+
+// the time of day of t, in t's location
+func TimeOfDayOf(t time.Time) TimeOfDay {
+	h, m, s := t.Clock()
+	return TimeOfDay(time.Duration(h)*time.Hour +
+		time.Duration(m)*time.Minute + time.Duration(s)*time.Second +
+		time.Duration(t.Nanosecond()))
+}
+
+// the time on the same day as t (in t's location) at this time of day
+func (this TimeOfDay) On(t time.Time) time.Time {
+	y, mon, d := t.Date()
+	return time.Date(y, mon, d, 0, 0, 0, 0, t.Location()).
+		Add(time.Duration(this))
+}
+
+func (this TimeOfDay) String() string {
+	d := time.Duration(this)
+	h, m, s := d/time.Hour, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second
+	if 0 == s {
+		return fmt.Sprintf("%02d:%02d", h, m)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+}
+
+// a daily window of time from Start up to (not including) End.  if End is
+// before Start, then the window wraps past midnight.  if they are the same,
+// then the window is the whole day.
+type TimeWindow struct {
+	Start, End TimeOfDay
+}
+
+// parse HH:MM-HH:MM
+func ParseTimeWindow(s string) (rv TimeWindow, err error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		err = fmt.Errorf("time window (%s) not HH:MM-HH:MM", s)
+		return
+	}
+	rv.Start, err = ParseTimeOfDay(start)
+	if nil == err {
+		rv.End, err = ParseTimeOfDay(end)
+	}
+	return
+}
+
+// is t within the window?
+func (this TimeWindow) Contains(t time.Time) bool {
+	tod := TimeOfDayOf(t)
+	if this.Start == this.End {
+		return true
+	} else if this.Start < this.End {
+		return this.Start <= tod && tod < this.End
+	}
+	return this.Start <= tod || tod < this.End
+}
+
+func (this TimeWindow) String() string {
+	return this.Start.String() + "-" + this.End.String()
+}
+
+// if found, parse to time of day (HH:MM or HH:MM:SS) and set result
+func (this *Section) GetTimeOfDay(key string, result *TimeOfDay) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found {
+		return
+	}
+	*result, err = ParseTimeOfDay(raw)
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+	}
+	return
+}
+
+// if found, parse to daily time window (HH:MM-HH:MM) and set result
+func (this *Section) GetTimeWindow(key string, result *TimeWindow) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found {
+		return
+	}
+	*result, err = ParseTimeWindow(raw)
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+	}
+	return
+}
+
+// a set of days of the week
+type Weekdays uint8
+
+const (
+	AllWeekdays Weekdays = 0x7f
+	WorkWeek    Weekdays = 0x3e // mon-fri
+	Weekend     Weekdays = 0x41 // sat,sun
+)
+
+var dayNames_ = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parse comma or space separated days (mon, Tuesday, ...), ranges of days
+// (mon-fri, fri-mon), or one of: all, weekdays, weekends
+func ParseWeekdays(s string) (rv Weekdays, err error) {
+	for _, field := range strings.FieldsFunc(s, isListSep) {
+		switch strings.ToLower(field) {
+		case "all", "*", "daily":
+			rv |= AllWeekdays
+			continue
+		case "weekdays":
+			rv |= WorkWeek
+			continue
+		case "weekends":
+			rv |= Weekend
+			continue
+		}
+		first, last, isRange := strings.Cut(field, "-")
+		var from, to time.Weekday
+		from, err = parseWeekday(first)
+		if err != nil {
+			return
+		}
+		to = from
+		if isRange {
+			to, err = parseWeekday(last)
+			if err != nil {
+				return
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			rv = rv.With(d)
+			if d == to {
+				break
+			}
+		}
+	}
+	if 0 == rv {
+		err = fmt.Errorf("no days of week in '%s'", s)
+	}
+	return
+}
+
+func parseWeekday(s string) (rv time.Weekday, err error) {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	if 3 <= len(lower) {
+		for i, name := range dayNames_ {
+			if strings.HasPrefix(lower, name) &&
+				strings.HasPrefix(strings.ToLower(time.Weekday(i).String()), lower) {
+				return time.Weekday(i), nil
+			}
+		}
+	}
+	err = fmt.Errorf("day of week (%s) not recognized", s)
+	return
+}
+
+// add the day to the set
+func (this Weekdays) With(d time.Weekday) Weekdays {
+	return this | 1<<uint(d)
+}
+
+// is the day in the set?
+func (this Weekdays) Contains(d time.Weekday) bool {
+	return 0 != this&(1<<uint(d))
+}
+
+func (this Weekdays) String() string {
+	var days []string
+	for i, name := range dayNames_ {
+		if this.Contains(time.Weekday(i)) {
+			days = append(days, name)
+		}
+	}
+	return strings.Join(days, ",")
+}
+
+// if found, parse to set of days of week and set result.  the value may be a
+// YAML list or a string.  see ParseWeekdays.
+func (this *Section) GetWeekdays(key string, result *Weekdays) (err error) {
+	this.track(key)
+	it, found := this.getIt(key, false)
+	if !found {
+		return
+	}
+	strs, err := this.toStrings(key, it, nil)
+	if err != nil {
+		return
+	}
+	*result, err = ParseWeekdays(strings.Join(strs, ","))
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s", this.ctx(key))
+	}
+	return
+}
+
+// if found and not blank, load the time zone (UTC, Local, America/New_York)
+// and set result
+func (this *Section) GetLocation(key string, result **time.Location) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found || 0 == len(raw) {
+		return
+	}
+	*result, err = time.LoadLocation(strings.TrimSpace(raw))
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+	}
+	return
+}
+
+// if found, parse to IP DSCP/TOS code and set result.  the value may be one
+// of the names known by unet (AF41, EF, ...) or a number.  see unet.HelpDscp.
+func (this *Section) GetDscp(key string, result *byte) (err error) {
+	this.track(key)
+	raw, found, err := this.getString(key)
+	if err != nil || !found {
+		return
+	}
+	code, err := unet.LookupDscpTos(strings.TrimSpace(raw))
+	if err != nil {
+		err = uerr.Chainf(err, "parsing config: %s=%s", this.ctx(key), raw)
+		return
+	}
+	*result = code
+	return
+}
+
+// if found, set result to the value, which must be one of choices.  if not
+// found, then the existing value of result must be blank or one of choices.
+//
+// the choices are recorded in the Help when gathering help (see Help.Chain).
+func (this *Section) GetEnum(
+	key string,
+	result *string,
+	choices ...string,
+) (err error) {
+	this.track(key)
+	this.trackChoices(key, choices)
+	raw, found, err := this.getString(key)
+	if err != nil {
+		return
+	} else if !found {
+		raw = *result
+		if 0 == len(raw) {
+			return
+		}
+	}
+	for _, choice := range choices {
+		if choice == raw {
+			*result = raw
+			return
+		}
+	}
+	err = fmt.Errorf("parsing config: %s=%s: must be one of: %s",
+		this.ctx(key), raw, strings.Join(choices, ", "))
+	return
+}
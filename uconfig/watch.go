@@ -10,6 +10,8 @@ import (
 )
 
 // used to watch for changes to files
+//
+// remote (http, https) files are revalidated every RemoteRevalidate
 type Watch struct {
 	lock   sync.Mutex
 	files  []string
@@ -60,6 +62,7 @@ func (this *Watch) Start(
 	//
 	go func() {
 		updated := time.Now()
+		revalidated := updated
 		ticker := time.NewTicker(period)
 		files := []string{}
 
@@ -77,9 +80,32 @@ func (this *Watch) Start(
 				ulog.Println("Watching", f)
 				files = append(files, f)
 
-			case <-ticker.C: // time to check
+			case now := <-ticker.C: // time to check
 
+				revalidate := now.Sub(revalidated) >= RemoteRevalidate
+				if revalidate {
+					revalidated = now
+				}
 				for _, f := range files {
+					if isRemote(f) {
+						if !revalidate {
+							continue
+						}
+						_, changed, err := fetchRemote(f)
+						if err != nil {
+							if nil != onError && onError(err) {
+								return ///////////////////////// time to stop
+							}
+							break
+						} else if changed {
+							ulog.Println("Changed:", f)
+							if onChange(files[0]) {
+								return ///////////////////////// time to stop
+							}
+							break
+						}
+						continue
+					}
 					stat, err := os.Stat(f)
 					if err != nil {
 						if nil != onError {
@@ -0,0 +1,296 @@
+package uerr
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Class classifies an error so that code can decide what to do about it
+// (retry it, what HTTP status to respond with, what to exit with) without
+// knowing the specifics of the error.
+type Class int
+
+const (
+	ClassUnknown      Class = iota // no classification available
+	ClassPermanent                 // will not succeed if retried
+	ClassRetriable                 // may succeed if retried
+	ClassTimeout                   // timed out - may succeed if retried
+	ClassNotFound                  // the thing does not exist
+	ClassUnauthorized              // not authenticated
+	ClassForbidden                 // not permitted
+	ClassInvalid                   // the request or input is bad
+	ClassConflict                  // conflicts with existing state
+)
+
+var classNames_ = [...]string{
+	ClassUnknown:      "unknown",
+	ClassPermanent:    "permanent",
+	ClassRetriable:    "retriable",
+	ClassTimeout:      "timeout",
+	ClassNotFound:     "not found",
+	ClassUnauthorized: "unauthorized",
+	ClassForbidden:    "forbidden",
+	ClassInvalid:      "invalid",
+	ClassConflict:     "conflict",
+}
+
+func (this Class) String() string {
+	if 0 <= this && int(this) < len(classNames_) {
+		return classNames_[this]
+	}
+	return "unknown"
+}
+
+// may an operation failing with this class of error succeed if retried?
+func (this Class) Retriable() bool {
+	return ClassRetriable == this || ClassTimeout == this
+}
+
+// the HTTP status a server should respond with for this class of error
+func (this Class) HTTPStatus() int {
+	switch this {
+	case ClassRetriable:
+		return 503
+	case ClassTimeout:
+		return 504
+	case ClassNotFound:
+		return 404
+	case ClassUnauthorized:
+		return 401
+	case ClassForbidden:
+		return 403
+	case ClassInvalid:
+		return 400
+	case ClassConflict:
+		return 409
+	}
+	return 500
+}
+
+// the process exit code for this class of error, following sysexits.h
+func (this Class) ExitCode() int {
+	switch this {
+	case ClassRetriable, ClassTimeout:
+		return 75 // EX_TEMPFAIL
+	case ClassNotFound:
+		return 66 // EX_NOINPUT
+	case ClassUnauthorized, ClassForbidden:
+		return 77 // EX_NOPERM
+	case ClassInvalid:
+		return 65 // EX_DATAERR
+	case ClassConflict:
+		return 73 // EX_CANTCREAT
+	}
+	return 1
+}
+
+// the class corresponding to the HTTP status, such as from a response
+func ClassOfHTTPStatus(status int) Class {
+	switch {
+	case 400 > status:
+		return ClassUnknown
+	case 400 == status || 422 == status:
+		return ClassInvalid
+	case 401 == status:
+		return ClassUnauthorized
+	case 403 == status:
+		return ClassForbidden
+	case 404 == status || 410 == status:
+		return ClassNotFound
+	case 408 == status || 504 == status:
+		return ClassTimeout
+	case 409 == status:
+		return ClassConflict
+	case 429 == status:
+		return ClassRetriable
+	case 500 > status:
+		return ClassPermanent
+	case 501 == status || 505 == status:
+		return ClassPermanent
+	case 600 > status:
+		return ClassRetriable
+	}
+	return ClassUnknown
+}
+
+// a Classifier provides the Class of an error, or ClassUnknown if it does
+// not know about the error.  it is called for each error in the chain, so
+// should not unwrap.
+type Classifier func(err error) Class
+
+var (
+	classLock_   sync.RWMutex
+	classifiers_ []Classifier
+	codeClasses_ = make(map[int]Class)
+	errnoClass_  = map[syscall.Errno]Class{
+		syscall.EAGAIN:       ClassRetriable,
+		syscall.EINTR:        ClassRetriable,
+		syscall.ECONNREFUSED: ClassRetriable,
+		syscall.ECONNRESET:   ClassRetriable,
+		syscall.ECONNABORTED: ClassRetriable,
+		syscall.EPIPE:        ClassRetriable,
+		syscall.EHOSTUNREACH: ClassRetriable,
+		syscall.ENETUNREACH:  ClassRetriable,
+		syscall.ENETDOWN:     ClassRetriable,
+		syscall.EADDRINUSE:   ClassRetriable,
+		syscall.ENOBUFS:      ClassRetriable,
+		syscall.ETIMEDOUT:    ClassTimeout,
+		syscall.ENOENT:       ClassNotFound,
+		syscall.EACCES:       ClassForbidden,
+		syscall.EPERM:        ClassForbidden,
+		syscall.EEXIST:       ClassConflict,
+		syscall.EINVAL:       ClassInvalid,
+	}
+)
+
+// add a Classifier for errors this package does not know about, such as
+// the errors of a driver.  classifiers added later are consulted first.
+func AddClassifier(classifier Classifier) {
+	classLock_.Lock()
+	classifiers_ = append([]Classifier{classifier}, classifiers_...)
+	classLock_.Unlock()
+}
+
+// classify errors with the code (see ChainfCode).  codes without a set class
+// that are in the HTTP error range (400-599) are classified as HTTP statuses.
+func SetCodeClass(code int, class Class) {
+	classLock_.Lock()
+	codeClasses_[code] = class
+	classLock_.Unlock()
+}
+
+// classify errors with the errno
+func SetErrnoClass(errno syscall.Errno, class Class) {
+	classLock_.Lock()
+	errnoClass_[errno] = class
+	classLock_.Unlock()
+}
+
+// mark the error with the class, producing a new error that wraps err,
+// or nil if err is nil
+func Classify(err error, class Class) error {
+	if nil == err {
+		return nil
+	}
+	return Chain(err, "").Classify(class)
+}
+
+// mark this error with the class
+func (this *UError) Classify(class Class) *UError {
+	this.class = class
+	return this
+}
+
+// get the class of the error, checking each error in the chain, outermost
+// first, until one can be classified.  nil errors are ClassUnknown.
+func ClassOf(err error) (rv Class) {
+	if nil == err {
+		return
+	}
+	classLock_.RLock()
+	defer classLock_.RUnlock()
+	CauseMatches(err, func(err error) bool {
+		rv = classOne(err)
+		return ClassUnknown != rv
+	})
+	return
+}
+
+// may the operation that failed with err succeed if retried?
+func IsRetriable(err error) bool {
+	return ClassOf(err).Retriable()
+}
+
+// did the operation time out?
+func IsTimeout(err error) bool {
+	return ClassTimeout == ClassOf(err)
+}
+
+// the HTTP status a server should respond with for err, or 200 if nil
+func HTTPStatus(err error) int {
+	if nil == err {
+		return 200
+	}
+	return ClassOf(err).HTTPStatus()
+}
+
+// the process exit code for err, or 0 if nil
+func ExitCode(err error) int {
+	if nil == err {
+		return 0
+	}
+	return ClassOf(err).ExitCode()
+}
+
+// classify just this error, without unwrapping
+func classOne(err error) (rv Class) {
+	if aware, ok := err.(uerrorAware_); ok {
+		u := aware.uerror()
+		if ClassUnknown != u.class {
+			return u.class
+		} else if 0 != u.Code {
+			if class, found := codeClasses_[u.Code]; found {
+				return class
+			}
+			return ClassOfHTTPStatus(u.Code)
+		}
+		return
+	}
+	for _, classifier := range classifiers_ {
+		rv = classifier(err)
+		if ClassUnknown != rv {
+			return
+		}
+	}
+	switch e := err.(type) {
+	case syscall.Errno:
+		if class, found := errnoClass_[e]; found {
+			return class
+		} else if e.Timeout() {
+			return ClassTimeout
+		} else if e.Temporary() {
+			return ClassRetriable
+		}
+		return
+	case *net.DNSError:
+		if e.IsNotFound {
+			return ClassNotFound
+		} else if e.IsTimeout {
+			return ClassTimeout
+		} else if e.IsTemporary {
+			return ClassRetriable
+		}
+		return ClassPermanent
+	}
+	switch {
+	case is(err, context.DeadlineExceeded), is(err, os.ErrDeadlineExceeded):
+		return ClassTimeout
+	case is(err, context.Canceled), is(err, net.ErrClosed):
+		return ClassPermanent
+	case is(err, os.ErrNotExist):
+		return ClassNotFound
+	case is(err, os.ErrPermission):
+		return ClassForbidden
+	case is(err, os.ErrExist):
+		return ClassConflict
+	case is(err, os.ErrInvalid):
+		return ClassInvalid
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return ClassTimeout
+	}
+	return
+}
+
+// is err the target, without unwrapping?
+func is(err, target error) bool {
+	if err == target {
+		return true
+	} else if isser, ok := err.(interface{ Is(error) bool }); ok {
+		return isser.Is(target)
+	}
+	return false
+}
@@ -47,6 +47,59 @@
 //	- or, if not chaining from a cause -
 //
 //	err := uerr.Cast(&MyError{}, "my message")
+//
+// # Fields
+//
+// Key/value fields can be attached to an error as it propagates, and are
+// rendered in the error message.  Fields retrieves them merged along the chain.
+//
+//	err = uerr.With(err, "component", name, "file", f)
+//	- or -
+//	err = uerr.Chainf(cause, "Nasty problem").With("file", f)
+//
+//	fields := uerr.Fields(err) // map[component:... file:...]
+//
+// # Multiple Errors
+//
+// Errors collects the errors from fan-out work, safely from many goroutines,
+// and presents them as a numbered list.  errors.Is and errors.As check each.
+//
+//	errs := uerr.NewErrors(0)
+//	errs.Addf(err, "processing %s", f)
+//	...
+//	return errs.Err()
+//
+// # Classification
+//
+// ClassOf classifies errors (retriable, timeout, not found, ...) so callers
+// can decide whether to retry, what HTTP status to respond with (HTTPStatus),
+// or what to exit with (ExitCode).  Common net, os and syscall errors are
+// classified by default.  Errors can be marked with a class, and codes
+// (see ChainfCode) in the 400-599 range are treated as HTTP statuses.
+//
+//	err = uerr.Classify(err, uerr.ClassRetriable)
+//	- or -
+//	uerr.SetCodeClass(myCode, uerr.ClassNotFound)
+//
+//	if uerr.IsRetriable(err) {
+//	    ...
+//
+// ToProblem converts an error chain, with its codes, classes and fields, to an
+// RFC 7807 problem+json document, and Problem.Err converts it back.
+//
+// # Stacks
+//
+// To find where an error originated, the stack can be captured when errors
+// are created, either for all errors by setting CaptureStack, or for a
+// particular error with WithStack:
+//
+//	uerr.CaptureStack.Store(true)
+//	- or -
+//	err = uerr.Chainf(cause, "Nasty problem").WithStack()
+//
+// Formatting with %+v (or FormatChain) then shows each link of the chain with
+// where it was created, and DeepestStack renders the stack closest to the
+// origin.
 package uerr
 
 import (
@@ -60,6 +113,11 @@ type UError struct {
 	Message string
 	Code    int
 	Cause   error
+	note    string    // Message without the cause
+	stack   []uintptr // where this was created, if captured
+	fields  []any     // key/value pairs attached by With
+	class   Class     // set by Classify
+	self    error     // the type mixing this in, if made by Cast/Recast
 }
 
 // a chainable error
@@ -79,7 +137,10 @@ type codeAware_ interface {
 //
 // implement error interface
 func (this *UError) Error() string {
-	return this.Message
+	if 0 == len(this.fields) {
+		return this.Message
+	}
+	return this.Message + " " + FormatFields(this.fields...)
 }
 
 // get cause of this error.
@@ -157,7 +218,7 @@ func RecastCode(
 	if isUError {
 		panic("'as' parameter needs to mixin UError struct, not be one")
 	}
-	as.chainf(cause, format, args...).setCode(code)
+	as.chainf(cause, format, args...).setCode(code).self = as
 	return as
 }
 
@@ -199,6 +260,9 @@ func (this *UError) chainf(
 		panic("detected reuse of UError instance - Cast/Recast must use new instance")
 	}
 	this.Cause = cause
+	if CaptureStack.Load() {
+		this.stack = callers()
+	}
 
 	var causeMsg string
 	if nil != cause {
@@ -210,6 +274,7 @@ func (this *UError) chainf(
 
 	if 0 != len(format) {
 		msg := fmt.Sprintf(format, args...)
+		this.note = msg
 		if nil == cause {
 			this.Message = msg
 		} else {
@@ -231,6 +296,9 @@ func (this *UError) chain(
 		panic("detected reuse of UError instance - Cast/Recast must use new instance")
 	}
 	this.Cause = cause
+	if CaptureStack.Load() {
+		this.stack = callers()
+	}
 
 	var causeMsg string
 	if nil != cause {
@@ -241,6 +309,7 @@ func (this *UError) chain(
 	}
 
 	if 0 != len(message) {
+		this.note = message
 		if nil == cause {
 			this.Message = message
 		} else {
@@ -278,16 +347,28 @@ func CausedBy(err, causedBy error) bool {
 }
 
 // Does any error in the chain match criteria?
+//
+// errors that contain multiple errors (such as Errors) have each checked.
 func CauseMatches(err error, criteria func(err error) bool) bool {
-	for {
+	for nil != err {
 		if criteria(err) {
 			return true
 		}
-		err = errors.Unwrap(err)
-		if nil == err {
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, member := range u.Unwrap() {
+				if CauseMatches(member, criteria) {
+					return true
+				}
+			}
+			return false
+		default:
 			return false
 		}
 	}
+	return false
 }
 
 // Does any error in the chain have an Error string containing match?
@@ -0,0 +1,153 @@
+package uerr
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// by default, the max number of errors retained by Errors
+const DefaultMaxErrors = 20
+
+// Errors collects the errors from fan-out work, such as loading many
+// components or operating on many files.  It is safe to add to it from
+// many goroutines.
+//
+// Only the first Max errors are retained, but all are counted.
+//
+// errors.Is and errors.As (and CausedBy) check each retained error.
+//
+//	errs := uerr.NewErrors(0)
+//	for _, f := range files {
+//	    errs.Addf(process(f), "processing %s", f)
+//	}
+//	return errs.Err()
+type Errors struct {
+	Max     int // max errors to retain - if not set, DefaultMaxErrors
+	lock    sync.Mutex
+	errs    []error
+	dropped int
+}
+
+// create a new Errors retaining up to max errors (DefaultMaxErrors if 0)
+func NewErrors(max int) *Errors {
+	return &Errors{Max: max}
+}
+
+// add err to the collection if err is not nil.  returns true if err not nil.
+func (this *Errors) Add(err error) bool {
+	if IsNil(err) {
+		return false
+	}
+	limit := this.Max
+	if 0 >= limit {
+		limit = DefaultMaxErrors
+	}
+	this.lock.Lock()
+	if len(this.errs) < limit {
+		this.errs = append(this.errs, err)
+	} else {
+		this.dropped++
+	}
+	this.lock.Unlock()
+	return true
+}
+
+// add err to the collection with additional context if err is not nil.
+// returns true if err not nil.
+func (this *Errors) Addf(err error, format string, args ...any) bool {
+	if IsNil(err) {
+		return false
+	}
+	return this.Add(Chainf(err, format, args...))
+}
+
+// number of errors added, including those not retained
+func (this *Errors) Len() (rv int) {
+	this.lock.Lock()
+	rv = len(this.errs) + this.dropped
+	this.lock.Unlock()
+	return
+}
+
+// get the retained errors
+func (this *Errors) Errors() (rv []error) {
+	this.lock.Lock()
+	rv = append(rv, this.errs...)
+	this.lock.Unlock()
+	return
+}
+
+// get the result of the collection:
+//   - nil if no errors
+//   - the error if only one error
+//   - this, otherwise
+func (this *Errors) Err() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	switch {
+	case 0 == len(this.errs):
+		return nil
+	case 1 == len(this.errs) && 0 == this.dropped:
+		return this.errs[0]
+	}
+	return this
+}
+
+// support errors.Is and errors.As for each of the retained errors
+func (this *Errors) Unwrap() []error {
+	return this.Errors()
+}
+
+// implement error interface, listing each error on its own line
+func (this *Errors) Error() string {
+	return this.list(func(w io.Writer, err error) {
+		io.WriteString(w, err.Error())
+	})
+}
+
+// implement fmt.Formatter
+//
+// %+v lists each error with the chain of each (see FormatChain)
+func (this *Errors) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			io.WriteString(f, this.list(writeChain))
+			return
+		}
+		io.WriteString(f, this.Error())
+	case 's':
+		io.WriteString(f, this.Error())
+	case 'q':
+		fmt.Fprintf(f, "%q", this.Error())
+	default:
+		fmt.Fprintf(f, "%%!%c(uerr.Errors=%s)", verb, this.Error())
+	}
+}
+
+// produce a numbered list of the errors, indenting the lines of each
+func (this *Errors) list(write func(w io.Writer, err error)) string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	total := len(this.errs) + this.dropped
+	var b, item strings.Builder
+	b.WriteString(strconv.Itoa(total))
+	if 1 == total {
+		b.WriteString(" error:")
+	} else {
+		b.WriteString(" errors:")
+	}
+	for i, err := range this.errs {
+		item.Reset()
+		write(&item, err)
+		fmt.Fprintf(&b, "\n  %d: %s", i+1,
+			strings.ReplaceAll(item.String(), "\n", "\n     "))
+	}
+	if 0 != this.dropped {
+		fmt.Fprintf(&b, "\n  ... and %d more", this.dropped)
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package uerr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attach key/value fields to err as it propagates, producing a new error
+// that wraps err.  Fields are alternating keys and values.
+//
+//	err = uerr.With(err, "component", name, "file", f)
+//
+// The fields are rendered in Error() as a suffix, like:
+//
+//	some problem, caused by: EOF [component=reader file="my file"]
+//
+// Use Fields to get all of the fields along the chain.
+//
+// If err is nil, then nil is returned.
+func With(err error, kv ...any) error {
+	if nil == err {
+		return nil
+	}
+	rv := &UError{Cause: err}
+	rv.Message = err.Error()
+	if 0 == len(rv.Message) {
+		rv.Message = fmt.Sprintf("%T", err)
+	}
+	if CaptureStack.Load() {
+		rv.stack = callers()
+	}
+	return rv.With(kv...)
+}
+
+// attach key/value fields to this error.  see With.
+func (this *UError) With(kv ...any) *UError {
+	this.fields = append(this.fields, kv...)
+	return this
+}
+
+// get the fields attached directly to this error (not the chain)
+func (this *UError) Fields() (rv map[string]any) {
+	if 0 != len(this.fields) {
+		rv = make(map[string]any, (len(this.fields)+1)/2)
+		eachField(this.fields, func(k string, v any) {
+			if _, found := rv[k]; !found {
+				rv[k] = v
+			}
+		})
+	}
+	return
+}
+
+// get all of the fields attached to errors along the chain, merged together.
+// when a key occurs more than once, the outermost value wins.
+//
+// nil is returned if there are no fields.
+func Fields(err error) (rv map[string]any) {
+	for ; nil != err; err = errors.Unwrap(err) {
+		var aware uerrorAware_
+		if !errors.As(err, &aware) {
+			return
+		}
+		u := aware.uerror()
+		if 0 != len(u.fields) && nil == rv {
+			rv = make(map[string]any)
+		}
+		eachField(u.fields, func(k string, v any) {
+			if _, found := rv[k]; !found {
+				rv[k] = v
+			}
+		})
+		err = aware.(error)
+	}
+	return
+}
+
+// call visit with each key/value.  a missing value is nil.
+func eachField(kv []any, visit func(k string, v any)) {
+	for i := 0; i < len(kv); i += 2 {
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		visit(fmt.Sprint(kv[i]), v)
+	}
+}
+
+// render the key/value fields as "[k=v k2=v2]", quoting values as needed, or
+// "" if no fields.  this is the format used by Error() and by ulog.
+func FormatFields(kv ...any) string {
+	if 0 == len(kv) {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	first := true
+	eachField(kv, func(k string, v any) {
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(fieldValue(v))
+	})
+	b.WriteByte(']')
+	return b.String()
+}
+
+// render the field value, quoting if needed so fields can be parsed back
+func fieldValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if 0 == len(s) || strings.ContainsAny(s, " \t\r\n\"=[]") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package uerr
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// the media type of a Problem document
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document, so that errors crossing
+// service boundaries keep their structure (message chain, code, class,
+// fields) instead of degrading to a status code and body text.
+//
+// Standard members are Type, Title, Status, Detail and Instance.  The other
+// members are extensions.
+type Problem struct {
+	Type     string         `json:"type,omitempty"`
+	Title    string         `json:"title,omitempty"`
+	Status   int            `json:"status,omitempty"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     int            `json:"code,omitempty"`
+	Class    string         `json:"class,omitempty"`
+	Fields   map[string]any `json:"fields,omitempty"`
+	Chain    []ProblemLink  `json:"chain,omitempty"`
+}
+
+// a link of the error chain in a Problem, outermost first
+type ProblemLink struct {
+	Message string         `json:"message"`
+	Code    int            `json:"code,omitempty"`
+	Class   string         `json:"class,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// convert err to a Problem, or nil if err is nil.
+//
+// Title is not set, as it is up to the server (typically the HTTP status
+// text).  Detail is the error message.
+func ToProblem(err error) (rv *Problem) {
+	if nil == err {
+		return
+	}
+	class := ClassOf(err)
+	code, _ := GetCode(err)
+	rv = &Problem{
+		Status: class.HTTPStatus(),
+		Detail: err.Error(),
+		Code:   code,
+		Fields: Fields(err),
+	}
+	if ClassUnknown != class {
+		rv.Class = class.String()
+	}
+	for ; nil != err; err = unwrapOne(err) {
+		aware, ok := err.(uerrorAware_)
+		if !ok { // not ours - its message includes the rest of the chain
+			rv.Chain = append(rv.Chain, ProblemLink{Message: err.Error()})
+			break
+		}
+		u := aware.uerror()
+		link := ProblemLink{
+			Message: u.note,
+			Code:    u.Code,
+			Fields:  u.Fields(),
+		}
+		if 0 == len(u.note) && nil == u.Cause {
+			link.Message = u.Message
+		}
+		if ClassUnknown != u.class {
+			link.Class = u.class.String()
+		}
+		rv.Chain = append(rv.Chain, link)
+	}
+	return
+}
+
+// unwrap err if it has a single cause
+func unwrapOne(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// decode a Problem from the JSON document
+func ParseProblem(doc []byte) (rv *Problem, err error) {
+	rv = &Problem{}
+	err = json.Unmarshal(doc, rv)
+	if err != nil {
+		rv = nil
+		err = Chainf(err, "parsing problem document")
+	}
+	return
+}
+
+// encode the Problem as JSON
+func (this *Problem) Marshal() ([]byte, error) {
+	return json.Marshal(this)
+}
+
+// convert the Problem back into a chained error.  if the Problem has no
+// chain, then an error is produced from the Detail or Title.  the outermost
+// error is classified by the Class or Status of the Problem.
+func (this *Problem) Err() error {
+	chain := this.Chain
+	if 0 == len(chain) {
+		msg := this.Detail
+		if 0 == len(msg) {
+			msg = this.Title
+		}
+		chain = []ProblemLink{{
+			Message: msg,
+			Code:    this.Code,
+			Fields:  this.Fields,
+		}}
+	}
+	var err *UError
+	for i := len(chain) - 1; 0 <= i; i-- {
+		link := chain[i]
+		u := (&UError{}).chain(nilIfNil(err), link.Message).
+			setCode(link.Code).
+			Classify(ParseClass(link.Class))
+		keys := make([]string, 0, len(link.Fields))
+		for k := range link.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			u.With(k, link.Fields[k])
+		}
+		err = u
+	}
+	if ClassUnknown == err.class {
+		if class := ParseClass(this.Class); ClassUnknown != class {
+			err.class = class
+		} else {
+			err.class = ClassOfHTTPStatus(this.Status)
+		}
+	}
+	return err
+}
+
+// avoid a typed nil
+func nilIfNil(err *UError) error {
+	if nil == err {
+		return nil
+	}
+	return err
+}
+
+// get the Class named by name (see Class.String), or ClassUnknown
+func ParseClass(name string) Class {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, className := range classNames_ {
+		if className == name {
+			return Class(i)
+		}
+	}
+	return ClassUnknown
+}
@@ -0,0 +1,195 @@
+package uerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// if set, then capture the stack whenever a UError is created.  this has a
+// cost, so is normally only enabled when debugging.
+var CaptureStack atomic.Bool
+
+// max depth of captured stacks
+const maxStack_ = 32
+
+// capture the stack of the caller, leaving out the frames within uerr
+func callers() (rv []uintptr) {
+	var pcs [maxStack_ + 8]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := 0
+	for {
+		frame, more := frames.Next()
+		if !inUerr(frame) {
+			break
+		}
+		skip++
+		if !more {
+			break
+		}
+	}
+	rv = make([]uintptr, min(n-skip, maxStack_))
+	copy(rv, pcs[skip:n])
+	return
+}
+
+// is the frame inside of this package (not including tests)?
+func inUerr(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, "github.com/tredeske/u/uerr.") &&
+		!strings.HasSuffix(frame.File, "_test.go")
+}
+
+// capture the stack where this error was created, if not already captured
+func (this *UError) WithStack() *UError {
+	if nil == this.stack {
+		this.stack = callers()
+	}
+	return this
+}
+
+// get the captured stack frames, or nil if not captured
+func (this *UError) Frames() (rv []runtime.Frame) {
+	if 0 == len(this.stack) {
+		return
+	}
+	frames := runtime.CallersFrames(this.stack)
+	for {
+		frame, more := frames.Next()
+		rv = append(rv, frame)
+		if !more {
+			return
+		}
+	}
+}
+
+// where the error was created (file:line), or "" if not captured
+func (this *UError) Location() string {
+	frames := this.Frames()
+	if 0 == len(frames) {
+		return ""
+	}
+	return frames[0].File + ":" + strconv.Itoa(frames[0].Line)
+}
+
+// get the UError of the error, which may be a type that mixes in UError
+func (this *UError) uerror() *UError { return this }
+
+type uerrorAware_ interface {
+	uerror() *UError
+}
+
+// get the error this UError is part of, which may be a type that mixes it in
+func (this *UError) outer() error {
+	if nil != this.self {
+		return this.self
+	}
+	return this
+}
+
+// implement fmt.Formatter
+//
+// %s, %v: the message
+//
+// %q: the quoted message
+//
+// %+v: each link of the error chain, with where it was created, if known
+// (see FormatChain)
+func (this *UError) Format(f fmt.State, verb rune) {
+	err := this.outer()
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			writeChain(f, err)
+			return
+		} else if f.Flag('#') {
+			fmt.Fprintf(f, "&%#v", *this)
+			return
+		}
+		io.WriteString(f, err.Error())
+	case 's':
+		io.WriteString(f, err.Error())
+	case 'q':
+		fmt.Fprintf(f, "%q", err.Error())
+	default:
+		fmt.Fprintf(f, "%%!%c(uerr.UError=%s)", verb, err.Error())
+	}
+}
+
+// render each link of the error chain on its own line, with where it was
+// created, if known, such as:
+//
+//	outer
+//	    at /src/prog/main.go:42
+//	caused by: EOF
+//
+// the errors of an Errors are listed with the chain of each.
+func FormatChain(err error) string {
+	if errs, ok := err.(*Errors); ok {
+		return errs.list(writeChain)
+	}
+	var b strings.Builder
+	writeChain(&b, err)
+	return b.String()
+}
+
+// write out each link of the chain on its own line, with location
+func writeChain(w io.Writer, err error) {
+	for i := 0; nil != err; i++ {
+		if 0 != i {
+			io.WriteString(w, "\ncaused by: ")
+		}
+		aware, ok := err.(uerrorAware_)
+		if !ok { // not ours - its message includes the rest of the chain
+			io.WriteString(w, err.Error())
+			return
+		}
+		u := aware.uerror()
+		if nil == u.Cause {
+			io.WriteString(w, err.Error())
+		} else if 0 != len(u.note) {
+			io.WriteString(w, u.note)
+			if 0 != len(u.fields) {
+				io.WriteString(w, " "+FormatFields(u.fields...))
+			}
+		} else if 0 != len(u.fields) {
+			io.WriteString(w, FormatFields(u.fields...))
+		} else {
+			io.WriteString(w, "(no message)")
+		}
+		if loc := u.Location(); 0 != len(loc) {
+			io.WriteString(w, "\n    at "+loc)
+		}
+		err = u.Cause
+	}
+}
+
+// render the stack captured closest to where the error originated, or ""
+// if no stack was captured, for logging
+func DeepestStack(err error) string {
+	var deepest *UError
+	for ; nil != err; err = errors.Unwrap(err) {
+		if aware, ok := err.(uerrorAware_); ok {
+			if u := aware.uerror(); 0 != len(u.stack) {
+				deepest = u
+			}
+		}
+	}
+	if nil == deepest {
+		return ""
+	}
+	var b strings.Builder
+	for _, frame := range deepest.Frames() {
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package uerr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClass(t *testing.T) {
+	const myCode = 12345
+	SetCodeClass(myCode, ClassConflict)
+
+	pathErr := &os.PathError{Op: "open", Path: "/nope", Err: syscall.ENOENT}
+	for i, tc := range []struct {
+		err    error
+		expect Class
+	}{
+		{nil, ClassUnknown},
+		{io.EOF, ClassUnknown},
+		{Chainf(io.EOF, "reading"), ClassUnknown},
+		{pathErr, ClassNotFound},
+		{Chainf(pathErr, "loading"), ClassNotFound},
+		{os.ErrPermission, ClassForbidden},
+		{fmt.Errorf("dialing: %w", syscall.ECONNREFUSED), ClassRetriable},
+		{&net.OpError{Op: "dial", Err: syscall.ETIMEDOUT}, ClassTimeout},
+		{&net.DNSError{IsNotFound: true}, ClassNotFound},
+		{Chainf(context.DeadlineExceeded, "waiting"), ClassTimeout},
+		{ChainfCode(nil, 404, "Invalid status"), ClassNotFound},
+		{ChainfCode(nil, 503, "Invalid status"), ClassRetriable},
+		{ChainfCode(io.EOF, myCode, "mine"), ClassConflict},
+		{Classify(syscall.ECONNREFUSED, ClassPermanent), ClassPermanent},
+		{fmt.Errorf("outer: %w",
+			Chainf(syscall.ECONNREFUSED, "x").Classify(ClassInvalid)), ClassInvalid},
+	} {
+		if class := ClassOf(tc.err); tc.expect != class {
+			t.Fatalf("%d: '%v' should be %s, got %s", i, tc.err, tc.expect, class)
+		}
+	}
+
+	errs := NewErrors(0)
+	errs.Add(io.EOF)
+	errs.Add(Chainf(syscall.ETIMEDOUT, "connecting"))
+	if !IsRetriable(errs) || !IsTimeout(errs) {
+		t.Fatalf("Should classify members of Errors")
+	}
+
+	if 404 != HTTPStatus(pathErr) || 500 != HTTPStatus(io.EOF) ||
+		200 != HTTPStatus(nil) {
+		t.Fatalf("HTTPStatus wrong")
+	} else if 66 != ExitCode(pathErr) || 1 != ExitCode(io.EOF) ||
+		0 != ExitCode(nil) || 75 != ExitCode(errs) {
+		t.Fatalf("ExitCode wrong")
+	}
+
+	AddClassifier(func(err error) Class {
+		if io.EOF == err {
+			return ClassRetriable
+		}
+		return ClassUnknown
+	})
+	if !IsRetriable(Chainf(io.EOF, "reading")) {
+		t.Fatalf("Classifier not used")
+	}
+}
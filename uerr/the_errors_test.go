@@ -0,0 +1,77 @@
+package uerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	type MyError struct {
+		UError
+	}
+
+	errs := NewErrors(3)
+	if nil != errs.Err() || errs.Add(nil) || 0 != errs.Len() {
+		t.Fatalf("Should be no errors")
+	}
+	errs.Add(io.EOF)
+	if io.EOF != errs.Err() {
+		t.Fatalf("Single error should be returned as is: %s", errs.Err())
+	}
+
+	//
+	// add concurrently
+	//
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if 0 == i {
+				errs.Add(Cast(&MyError{}, "mine"))
+			} else {
+				errs.Addf(os.ErrNotExist, "file %d", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if 11 != errs.Len() || 3 != len(errs.Errors()) {
+		t.Fatalf("Should have 11 errors, with 3 retained, got %d, %d",
+			errs.Len(), len(errs.Errors()))
+	}
+	err := errs.Err()
+	if err != error(errs) {
+		t.Fatalf("Should get Errors")
+	} else if !errors.Is(err, io.EOF) || !CausedBy(err, os.ErrNotExist) {
+		t.Fatalf("Should be EOF and not exist")
+	} else if !CauseMatchesString(err, "not exist") {
+		t.Fatalf("Should match string")
+	}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "11 errors:\n  1: EOF\n  2: ") ||
+		!strings.HasSuffix(msg, "\n  ... and 8 more") {
+		t.Fatalf("Error() wrong:\n%s", msg)
+	}
+
+	//
+	// As finds the member, and %+v shows member chains indented
+	//
+	errs = NewErrors(0)
+	errs.Add(Chainf(io.EOF, "reading"))
+	errs.Add(Cast(&MyError{}, "mine"))
+	var my *MyError
+	if !errors.As(errs, &my) || "mine" != my.Error() {
+		t.Fatalf("Should find MyError")
+	}
+	expect := "2 errors:\n  1: reading\n     caused by: EOF\n  2: mine"
+	if expect != fmt.Sprintf("%+v", errs) || expect != FormatChain(errs) {
+		t.Fatalf("%%+v wrong:\n%+v", errs)
+	}
+}
@@ -0,0 +1,59 @@
+package uerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	type MyError struct {
+		UError
+	}
+
+	if nil != With(nil, "a", 1) {
+		t.Fatalf("With nil should be nil")
+	} else if nil != Fields(io.EOF) {
+		t.Fatalf("Should be no fields for plain error")
+	}
+
+	err := With(io.EOF, "component", "reader", "file", "my file")
+	if `EOF [component=reader file="my file"]` != err.Error() {
+		t.Fatalf("Error() wrong: %s", err)
+	} else if !errors.Is(err, io.EOF) {
+		t.Fatalf("Should still be EOF")
+	}
+
+	err = Chainf(err, "reading").With("component", "outer", "n", 5)
+	err = fmt.Errorf("wrapped: %w", err)
+	err = Recast(&MyError{}, err, "mine")
+	err = With(err, "odd")
+
+	fields := Fields(err)
+	if 4 != len(fields) || "outer" != fields["component"] ||
+		5 != fields["n"] || "my file" != fields["file"] {
+		t.Fatalf("Fields wrong: %v", fields)
+	} else if v, found := fields["odd"]; !found || nil != v {
+		t.Fatalf("Missing value should be nil: %v", fields)
+	}
+
+	expect := `mine, caused by: wrapped: reading, caused by: ` +
+		`EOF [component=reader file="my file"] [component=outer n=5] [odd=<nil>]`
+	if expect != err.Error() {
+		t.Fatalf("Error() wrong:\n%s\n%s", err, expect)
+	}
+
+	var my *MyError
+	if !errors.As(err, &my) {
+		t.Fatalf("Should be MyError")
+	} else if nil != my.Fields() {
+		t.Fatalf("MyError should have no fields of its own: %v", my.Fields())
+	}
+
+	plain := Chainf(io.EOF, "reading").With("file", "f")
+	if "reading\ncaused by: EOF" == FormatChain(plain) ||
+		"reading [file=f]\ncaused by: EOF" != FormatChain(plain) {
+		t.Fatalf("chain wrong: %s", FormatChain(plain))
+	}
+}
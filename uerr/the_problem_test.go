@@ -0,0 +1,57 @@
+package uerr
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	if nil != ToProblem(nil) {
+		t.Fatalf("nil error should have no problem")
+	}
+
+	orig := With(Chainf(io.EOF, "reading %s", "f").Classify(ClassRetriable),
+		"file", "f")
+	orig = ChainfCode(orig, 7, "loading")
+
+	problem := ToProblem(orig)
+	if 503 != problem.Status || "retriable" != problem.Class ||
+		7 != problem.Code || "f" != problem.Fields["file"] ||
+		orig.Error() != problem.Detail || 4 != len(problem.Chain) {
+		t.Fatalf("Problem wrong: %#v", problem)
+	}
+
+	doc, err := problem.Marshal()
+	if err != nil {
+		t.Fatalf("Unable to marshal: %s", err)
+	}
+	problem, err = ParseProblem(doc)
+	if err != nil {
+		t.Fatalf("Unable to parse: %s", err)
+	}
+	rebuilt := problem.Err()
+	if orig.Error() != rebuilt.Error() {
+		t.Fatalf("Rebuilt error wrong:\n%s\n%s", rebuilt, orig)
+	} else if !IsRetriable(rebuilt) || "f" != Fields(rebuilt)["file"] {
+		t.Fatalf("Rebuilt error lost class or fields")
+	} else if code, _ := GetCode(rebuilt); 7 != code {
+		t.Fatalf("Rebuilt error lost code")
+	}
+
+	//
+	// a foreign problem
+	//
+	problem, err = ParseProblem([]byte(
+		`{"type":"about:blank","title":"Not Found","status":404}`))
+	if err != nil {
+		t.Fatalf("Unable to parse: %s", err)
+	} else if err = problem.Err(); "Not Found" != err.Error() ||
+		ClassNotFound != ClassOf(err) {
+		t.Fatalf("Foreign problem wrong: %s", err)
+	}
+	var u *UError
+	if !errors.As(err, &u) {
+		t.Fatalf("Should be a UError")
+	}
+}
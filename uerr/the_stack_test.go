@@ -0,0 +1,103 @@
+package uerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// mixes in UError, overriding Error()
+type customError_ struct {
+	UError
+}
+
+func (this *customError_) Error() string { return "custom: " + this.Message }
+
+func TestStack(t *testing.T) {
+	type MyError struct {
+		UError
+	}
+	here := func() string { // file:line of caller
+		_, file, line, _ := runtime.Caller(1)
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+
+	//
+	// by default, no stacks
+	//
+	err := Chainf(io.EOF, "outer")
+	if 0 != len(err.Frames()) || 0 != len(DeepestStack(err)) {
+		t.Fatalf("should not capture stack by default")
+	} else if "outer, caused by: EOF" != fmt.Sprintf("%v", err) {
+		t.Fatalf("%%v wrong: %v", err)
+	} else if "outer\ncaused by: EOF" != FormatChain(err) {
+		t.Fatalf("chain wrong: %s", FormatChain(err))
+	} else if "outer\ncaused by: EOF" != fmt.Sprintf("%+v", err) {
+		t.Fatalf("%%+v wrong: %+v", err)
+	} else if `"outer, caused by: EOF"` != fmt.Sprintf("%q", err) {
+		t.Fatalf("%%q wrong: %q", err)
+	}
+
+	//
+	// per call
+	//
+	err, at := Chainf(io.EOF, "per call").WithStack(), here()
+	if at != err.Location() {
+		t.Fatalf("wrong location: %s", err.Location())
+	}
+
+	//
+	// globally
+	//
+	CaptureStack.Store(true)
+	defer CaptureStack.Store(false)
+
+	var innerAt string
+	inner := func() (err error) {
+		err, innerAt = Cast(&MyError{}, "inner %d", 1), here()
+		return
+	}
+	err, middleAt := Chainf(inner(), "middle"), here()
+	outer, outerAt := Chain(err, "outer"), here()
+
+	full := FormatChain(outer)
+	lines := strings.Split(full, "\n")
+	if 6 != len(lines) ||
+		"outer" != lines[0] ||
+		"    at "+outerAt != lines[1] ||
+		"caused by: middle" != lines[2] ||
+		"    at "+middleAt != lines[3] ||
+		"caused by: inner 1" != lines[4] ||
+		"    at "+innerAt != lines[5] {
+		t.Fatalf("chain wrong:\n%s", full)
+	}
+	if full != fmt.Sprintf("%+v", outer) {
+		t.Fatalf("%%+v wrong:\n%+v", outer)
+	}
+
+	var myErr *MyError
+	if !errors.As(outer, &myErr) {
+		t.Fatalf("errors.As should still work")
+	} else if myErr.Message != fmt.Sprint(myErr) {
+		t.Fatalf("%%v of mixin wrong: %v", myErr)
+	}
+
+	//
+	// types overriding Error() are formatted with their Error()
+	//
+	custom := Cast(&customError_{}, "x")
+	if "custom: x" != fmt.Sprintf("%v", custom) ||
+		"custom: x" != fmt.Sprintf("%s", custom) ||
+		!strings.HasPrefix(fmt.Sprintf("%+v", custom), "custom: x\n    at ") {
+		t.Fatalf("%%v of override wrong: %v", custom)
+	}
+
+	deepest := DeepestStack(outer)
+	if !strings.HasPrefix(deepest, "github.com/tredeske/u/uerr.TestStack.func") ||
+		!strings.Contains(deepest, "\t"+innerAt+"\n") {
+		t.Fatalf("deepest stack wrong:\n%s", deepest)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
+
+	"github.com/tredeske/u/uerr"
 )
 
 // Time to wait for stuff to die, if anything registered
@@ -49,6 +52,9 @@ var (
 	exitHandlerC_ chan *exitHandler_ = make(chan *exitHandler_, 8)
 	exitDoneC_    chan bool          = make(chan bool, 32)
 	waitC_        chan bool          = start()
+
+	flushLock_ sync.Mutex
+	flushes_   []func() // see AtFlush
 )
 
 func start() (rv chan bool) {
@@ -71,6 +77,7 @@ func manageExit(waitC chan bool) {
 		if r != nil {
 			log.Printf("ERROR: Problem in exit handling: %s", r)
 			log.Printf("\n\nExitting with status %d\n\n", exitStatus)
+			runFlushes()
 			os.Exit(exitStatus)
 		}
 	}()
@@ -133,6 +140,7 @@ func manageExit(waitC chan bool) {
 	//
 	waitC <- true
 	log.Printf("\n\nExitting with status %d\n\n", exitStatus)
+	runFlushes()
 	os.Exit(exitStatus)
 }
 
@@ -160,6 +168,26 @@ func AtExitF(onExit func(exitCode int)) {
 	}()
 }
 
+// register a func to be run just before the process exits, after the AtExit
+// handlers, such as to flush buffered log output.  flush must not block for
+// long.
+func AtFlush(flush func()) {
+	flushLock_.Lock()
+	defer flushLock_.Unlock()
+	flushes_ = append(flushes_, flush)
+}
+
+// run the AtFlush funcs, once
+func runFlushes() {
+	flushLock_.Lock()
+	flushes := flushes_
+	flushes_ = nil
+	flushLock_.Unlock()
+	for _, flush := range flushes {
+		flush()
+	}
+}
+
 // register signals that should cause process exit
 func ExitOnSignals(sigs ...os.Signal) {
 	signal.Notify(sigC_, sigs...)
@@ -181,6 +209,7 @@ func ExitWait(code int, wait time.Duration) {
 	exitC_ <- code
 	time.Sleep(wait)
 	log.Printf("Exit handler failed to exit on time - exiting now")
+	runFlushes()
 	os.Exit(code)
 }
 
@@ -188,3 +217,9 @@ func ExitWait(code int, wait time.Duration) {
 func Exit(code int) {
 	ExitWait(code, WaitTime)
 }
+
+// cause the process to exit within WaitTime seconds, with the exit code
+// for err (see uerr.ExitCode)
+func ExitErr(err error) {
+	ExitWait(uerr.ExitCode(err), WaitTime)
+}
@@ -17,14 +17,14 @@ func InitDebug(config *uconfig.Section) (err error) {
 	}
 
 	if ustrings.Contains(enable, "all") {
-		ulog.DebugEnabled = true
+		ulog.SetDebugEnabled(true)
 	} else {
 		for _, item := range enable {
 			ulog.SetDebugEnabledFor(item)
 		}
 	}
 	if ustrings.Contains(disable, "all") {
-		ulog.DebugEnabled = false
+		ulog.SetDebugEnabled(false)
 	} else {
 		for _, item := range disable {
 			ulog.SetDebugDisabledFor(item)
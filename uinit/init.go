@@ -5,6 +5,9 @@ import (
 	"github.com/tredeske/u/ulog"
 )
 
+// if set, then snapshots of accepted config are kept here.  see ConfigAccepted.
+var ConfigHistory *uconfig.History
+
 //
 // Used upon process initialization to load initial config.
 //
@@ -21,6 +24,45 @@ func InitConfig(configF string) (config *uconfig.Section, err error) {
 		return
 	}
 	ulog.Printf("Loaded config: '%s'", configF)
+	err = initSections(config)
+	return
+}
+
+// Used to return to a config snapshot (see ConfigHistory), taking the value
+// of each redacted property from live, such as the config in use.
+func InitSnapshot(
+	snapF string,
+	live *uconfig.Section,
+) (
+	config *uconfig.Section,
+	err error,
+) {
+	err = uconfig.InitEnv()
+	if err != nil {
+		return
+	}
+
+	config, err = uconfig.LoadSnapshot(snapF, live)
+	if nil != err {
+		return
+	}
+	ulog.Printf("Loaded config snapshot: '%s'", snapF)
+	err = initSections(config)
+	return
+}
+
+// set up logging and debug from config
+func initSections(config *uconfig.Section) (err error) {
+	var logging *uconfig.Section
+	err = config.GetSectionIf("logging", &logging)
+	if err != nil {
+		return
+	} else if nil != logging {
+		err = InitLogging(logging)
+		if err != nil {
+			return
+		}
+	}
 
 	var dbg *uconfig.Section
 	err = config.GetSectionIf("debug", &dbg)
@@ -31,3 +73,19 @@ func InitConfig(configF string) (config *uconfig.Section, err error) {
 	}
 	return
 }
+
+// Record that config loaded by InitConfig was accepted (such as by golum),
+// saving a snapshot of it to ConfigHistory, if set.
+//
+// Problems saving the snapshot are logged, but do not prevent use of the config.
+func ConfigAccepted(config *uconfig.Section) {
+	if nil == ConfigHistory {
+		return
+	}
+	snap, err := ConfigHistory.Save(config)
+	if err != nil {
+		ulog.Warnf("Unable to save config snapshot: %s", err)
+	} else {
+		ulog.Printf("Config snapshot: %s", snap)
+	}
+}
@@ -0,0 +1,184 @@
+package uinit
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tredeske/u/uconfig"
+	"github.com/tredeske/u/ulog"
+)
+
+// paths the ulog ring is served on, as these cannot be removed
+var ringPaths_ = map[string]bool{}
+
+// configure logging from the 'logging' section of the config
+//
+//	logging:
+//	    format: json      # log (default), text, or json
+//	    output: journald  # stdout, syslog, journald, or a log file
+//	    limit:            # limit repeated messages (see ulog.Limit)
+//	        window: 10s
+//	        burst:  5
+//	        key:    format  # format (default), site, or message
+//	    limits:           # limit repeated messages for components
+//	      - component: compA
+//	        window:    1m
+//	        key:       message
+//	    async:            # write asynchronously (see ulog.SetAsync)
+//	        queue:    4Mi         # size of queue, 0 for synchronous
+//	        overflow: drop-debug  # block (default), drop-debug, or drop
+//	    sinks:            # destinations for routes (see ulog.Sink)
+//	      - name:     proto
+//	        output:   proto.log   # stdout, syslog, journald, or a log file
+//	        size:     100Mi       # rotate at this size
+//	        keep:     4           # rotated files to keep
+//	        daily:    true        # also rotate at midnight
+//	        every:    6h          # also rotate at this interval
+//	        compress: true        # compress rotated files
+//	        maxDays:  7           # remove rotated files older than this
+//	        maxTotal: 1Gi         # remove oldest rotated files beyond this
+//	    routes:           # first match goes to sink instead of main log
+//	      - components: ["proto*"]  # globs (default: any)
+//	        levels:     [debug]     # debug, info, todo, warn, error, fatal
+//	        sink:       proto       # a sink, or main
+//	        tee:        false       # also output to main log
+//
+//	    ring:             # keep recent records in memory (see ulog.SetRing)
+//	        records: 10000        # most records to keep, 0 for none
+//	        bytes:   8Mi          # most memory to use
+//	        path:    /debug/log   # serve records on http.DefaultServeMux
+//
+// if set, output overrides what was set by the command line (-log)
+func InitLogging(config *uconfig.Section) (err error) {
+	format := ulog.GetFormat()
+	output := ulog.GetOutput()
+	var limit ulog.Limit
+	limits := map[string]ulog.Limit{}
+	var sinks []ulog.Sink
+	var routes []ulog.Route
+	ringRecords, ringBytes := 0, 0
+	ringPath := ""
+	asyncQueue := 0
+	asyncOverflow := ulog.OverflowBlock
+	err = config.Chain().
+		GetEnum("format", &format, ulog.FormatLog, ulog.FormatText, ulog.FormatJson).
+		GetString("output", &output).
+		If("limit", func(c *uconfig.Chain) error {
+			return getLimit(c, &limit)
+		}).
+		If("ring", func(c *uconfig.Chain) error {
+			return c.
+				GetInt("records", &ringRecords, uconfig.IntNonNeg()).
+				GetByteSize("bytes", &ringBytes, uconfig.IntNonNeg()).
+				GetString("path", &ringPath).
+				Error
+		}).
+		If("async", func(c *uconfig.Chain) error {
+			return c.
+				GetByteSize("queue", &asyncQueue, uconfig.IntNonNeg()).
+				GetEnum("overflow", &asyncOverflow, ulog.OverflowBlock,
+					ulog.OverflowDropDebug, ulog.OverflowDrop).
+				Error
+		}).
+		EachIf("sinks", func(c *uconfig.Chain) (err error) {
+			var sink ulog.Sink
+			maxDays := 0
+			err = c.
+				GetString("name", &sink.Name, uconfig.StringNotBlank()).
+				GetString("output", &sink.Output, uconfig.StringNotBlank()).
+				GetByteSize("size", &sink.Size, uconfig.IntNonNeg()).
+				GetInt("keep", &sink.Keep, uconfig.IntNonNeg()).
+				GetBool("daily", &sink.Policy.Daily).
+				GetDuration("every", &sink.Policy.Every).
+				GetBool("compress", &sink.Policy.Compress).
+				GetInt("maxDays", &maxDays, uconfig.IntNonNeg()).
+				GetByteSize("maxTotal", &sink.Policy.MaxTotal, uconfig.IntNonNeg()).
+				Error
+			if nil == err {
+				sink.Policy.MaxAge = time.Duration(maxDays) * 24 * time.Hour
+				sinks = append(sinks, sink)
+			}
+			return
+		}).
+		EachIf("routes", func(c *uconfig.Chain) (err error) {
+			var route ulog.Route
+			var levels []string
+			err = c.
+				GetStrings("components", &route.Components).
+				GetStrings("levels", &levels).
+				GetString("sink", &route.Sink, uconfig.StringNotBlank()).
+				GetBool("tee", &route.Tee).
+				Error
+			if err != nil {
+				return
+			}
+			for _, name := range levels {
+				var level slog.Level
+				level, err = ulog.ParseLevel(name)
+				if err != nil {
+					return
+				}
+				route.Levels = append(route.Levels, level)
+			}
+			routes = append(routes, route)
+			return
+		}).
+		EachIf("limits", func(c *uconfig.Chain) (err error) {
+			var component string
+			var l ulog.Limit
+			err = getLimit(
+				c.GetString("component", &component, uconfig.StringNotBlank()),
+				&l)
+			if nil == err {
+				limits[component] = l
+			}
+			return
+		}).
+		Error
+	if err != nil {
+		return
+	}
+	ulog.ClearLimits()
+	err = ulog.SetLimit("", limit)
+	if err != nil {
+		return
+	}
+	for component, l := range limits {
+		err = ulog.SetLimit(component, l)
+		if err != nil {
+			return
+		}
+	}
+	err = ulog.SetAsync(asyncQueue, asyncOverflow)
+	if err != nil {
+		return
+	}
+	if output != ulog.GetOutput() {
+		err = ulog.Init(output, 0, 0)
+		if err != nil {
+			return
+		}
+	}
+	err = ulog.SetRoutes(sinks, routes)
+	if err != nil {
+		return
+	}
+	ulog.SetRing(ringRecords, ringBytes)
+	if 0 != len(ringPath) && !ringPaths_[ringPath] {
+		ringPaths_[ringPath] = true
+		http.Handle(ringPath, ulog.RingHandler())
+	}
+	return ulog.SetFormat(format)
+}
+
+// get the limit settings in the config
+func getLimit(c *uconfig.Chain, limit *ulog.Limit) error {
+	limit.Key = ulog.LimitByFormat
+	return c.
+		GetDuration("window", &limit.Window).
+		GetInt("burst", &limit.Burst, uconfig.IntNonNeg()).
+		GetEnum("key", &limit.Key,
+			ulog.LimitByFormat, ulog.LimitBySite, ulog.LimitByMessage).
+		Error
+}
@@ -21,7 +21,7 @@ func TestInitConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if ulog.DebugEnabled {
+	if ulog.IsDebugEnabled() {
 		t.Fatalf("debug should not be enabled for all")
 	} else if !ulog.IsDebugEnabledFor("foo") {
 		t.Fatalf("debug *should* be enabled for foo")
@@ -0,0 +1,247 @@
+package ulog
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tredeske/u/uexit"
+)
+
+// what an AsyncWriter does with a line when its queue is full
+const (
+	OverflowBlock     = "block"      // wait for space (default)
+	OverflowDropDebug = "drop-debug" // drop debug lines, wait for space for others
+	OverflowDrop      = "drop"       // drop the line
+)
+
+// AsyncWriter queues lines in memory, and writes them in batches from its own
+// goroutine, so that a slow disk does not stall each goroutine that logs.
+//
+// The queue is bounded to a number of bytes.  When the queue is full, lines
+// are handled according to the overflow policy (see OverflowBlock).  The
+// number of dropped lines is logged once there is space.
+//
+// Close (or process exit through uexit) flushes the queue.
+type AsyncWriter struct {
+	lock         sync.Mutex
+	cond         *sync.Cond // signaled when queue or state changes
+	w            io.Writer
+	max          int
+	overflow     string
+	queue        []byte
+	writing      bool // a batch is being written
+	closed       bool
+	dropped      int // lines dropped since last reported
+	droppedDebug int // debug lines dropped since last reported
+	done         chan struct{}
+}
+
+// create an AsyncWriter writing to w, with a queue of up to max bytes
+func NewAsyncWriter(w io.Writer, max int, overflow string) (rv *AsyncWriter, err error) {
+	overflow, err = checkAsync(max, overflow)
+	if err != nil {
+		return
+	}
+	rv = &AsyncWriter{
+		w:        w,
+		max:      max,
+		overflow: overflow,
+		queue:    make([]byte, 0, min(max, 64*1024)),
+		done:     make(chan struct{}),
+	}
+	rv.cond = sync.NewCond(&rv.lock)
+	go rv.run()
+	return
+}
+
+// validate the settings, returning the overflow policy to use
+func checkAsync(max int, overflow string) (string, error) {
+	switch overflow {
+	case "":
+		overflow = OverflowBlock
+	case OverflowBlock, OverflowDropDebug, OverflowDrop:
+	default:
+		return "", fmt.Errorf("unknown overflow '%s' - must be one of %s, %s, %s",
+			overflow, OverflowBlock, OverflowDropDebug, OverflowDrop)
+	}
+	if 0 >= max {
+		return "", fmt.Errorf("async log queue size must be positive, not %d", max)
+	}
+	return overflow, nil
+}
+
+// implement io.Writer, queueing b.  once closed, b is written directly.
+//
+// lines written this way are never dropped as debug lines.
+func (this *AsyncWriter) Write(b []byte) (n int, err error) {
+	return this.write(LevelInfo, b)
+}
+
+// queue b, which is a line at level
+func (this *AsyncWriter) write(level slog.Level, b []byte) (n int, err error) {
+	this.lock.Lock()
+	for !this.closed {
+		if this.max >= len(this.queue)+len(b) || 0 == len(this.queue) {
+			this.queue = append(this.queue, b...)
+			this.cond.Broadcast()
+			this.lock.Unlock()
+			return len(b), nil
+		} else if OverflowDrop == this.overflow {
+			this.dropped++
+			this.lock.Unlock()
+			return len(b), nil
+		} else if OverflowDropDebug == this.overflow && LevelInfo > level {
+			this.droppedDebug++
+			this.lock.Unlock()
+			return len(b), nil
+		}
+		this.cond.Wait()
+	}
+	this.lock.Unlock()
+	<-this.done
+	return this.w.Write(b)
+}
+
+// wait for lines queued so far to be written
+func (this *AsyncWriter) Flush() {
+	this.lock.Lock()
+	for !this.closed && (0 != len(this.queue) || this.writing) {
+		this.cond.Wait()
+	}
+	closed := this.closed
+	this.lock.Unlock()
+	if closed {
+		<-this.done
+	}
+}
+
+// flush the queue and stop the writer goroutine.  later writes are written
+// directly.  the underlying writer is not closed.
+func (this *AsyncWriter) Close() error {
+	this.lock.Lock()
+	wasClosed := this.closed
+	this.closed = true
+	this.cond.Broadcast()
+	this.lock.Unlock()
+	<-this.done
+	if !wasClosed {
+		this.reportDropped()
+	}
+	return nil
+}
+
+// the number of lines dropped so far
+func (this *AsyncWriter) Dropped() (lines, debugLines int) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.dropped, this.droppedDebug
+}
+
+func (this *AsyncWriter) run() {
+	defer close(this.done)
+	var batch []byte
+	this.lock.Lock()
+	for {
+		for 0 == len(this.queue) && !this.closed {
+			this.cond.Wait()
+		}
+		if 0 == len(this.queue) { // closed and drained
+			break
+		}
+		batch, this.queue = this.queue, batch[:0]
+		report := !this.closed && 0 != this.dropped+this.droppedDebug
+		this.writing = true
+		this.cond.Broadcast() // there is space
+		this.lock.Unlock()
+
+		this.w.Write(batch)
+		if report { // there was space, so logging will not drop it
+			go this.reportDropped()
+		}
+
+		this.lock.Lock()
+		this.writing = false
+		this.cond.Broadcast()
+	}
+	this.lock.Unlock()
+}
+
+// log the number of lines dropped since last reported, if any
+func (this *AsyncWriter) reportDropped() {
+	this.lock.Lock()
+	dropped, droppedDebug := this.dropped, this.droppedDebug
+	this.dropped, this.droppedDebug = 0, 0
+	this.lock.Unlock()
+	if 0 != dropped+droppedDebug {
+		Warnf("log queue full: dropped %d lines (%d debug)",
+			dropped+droppedDebug, droppedDebug)
+	}
+}
+
+var (
+	asyncOnce_     sync.Once
+	async_         atomic.Pointer[AsyncWriter] // set up by SetAsync, if any
+	outputLock_    sync.Mutex                  // guards logW_ and below
+	asyncSize_     int                         // see SetAsync
+	asyncOverflow_ string
+)
+
+// Make output of ulog (and golang standard log) asynchronous, through an
+// AsyncWriter with a queue of up to queueSize bytes and the overflow policy,
+// for stdout and file output (see Init).  A queueSize of 0 makes output
+// synchronous again.
+//
+// The queue is flushed before process exit through uexit (see uexit.AtFlush).
+func SetAsync(queueSize int, overflow string) (err error) {
+	if 0 != queueSize {
+		_, err = checkAsync(queueSize, overflow)
+		if err != nil {
+			return
+		}
+	}
+	outputLock_.Lock()
+	defer outputLock_.Unlock()
+	if queueSize == asyncSize_ && overflow == asyncOverflow_ {
+		return
+	}
+	asyncSize_, asyncOverflow_ = queueSize, overflow
+	setOutput(logW_)
+	return
+}
+
+// get the AsyncWriter set up by SetAsync, or nil if output is synchronous
+func GetAsync() *AsyncWriter {
+	return async_.Load()
+}
+
+// send ulog (and golang standard log) output to w, through an AsyncWriter
+// if SetAsync so indicates.  any prior AsyncWriter is flushed.
+//
+// outputLock_ must be held.
+func setOutput(w io.Writer) {
+	logW_ = w
+	if prior := async_.Swap(nil); nil != prior {
+		prior.Close()
+	}
+	var aw *AsyncWriter
+	if 0 != asyncSize_ && nil == getSink() {
+		aw, _ = NewAsyncWriter(w, asyncSize_, asyncOverflow_)
+		async_.Store(aw)
+		asyncOnce_.Do(func() {
+			uexit.AtFlush(func() {
+				if aw := async_.Load(); nil != aw {
+					aw.Close()
+				}
+			})
+		})
+	}
+	if nil != aw {
+		log.SetOutput(aw)
+	} else {
+		log.SetOutput(w)
+	}
+}
@@ -4,19 +4,38 @@ import (
 	"encoding/hex"
 	"log"
 	"sync"
+	"sync/atomic"
 )
 
 var ( // see uinit/debug.go
-	DebugEnabled      = false      // turn on all debug
+	// turn on all debug.  set this before logging starts, such as from flags.
+	// after that, use SetDebugEnabled and IsDebugEnabled.
+	DebugEnabled      = false
 	debugEnabledFor_  = sync.Map{} // turn on selective debug
 	debugDisabledFor_ = sync.Map{} // turn off selective debug
+
+	debugAll_          atomic.Int32  // set by SetDebugEnabled (debugAllOn_, ...)
+	debugEnabledCount_ atomic.Int32  // number of components with debug enabled
+	debugGen_          atomic.Uint64 // changed whenever debug state changes
+)
+
+// all debug state, overriding DebugEnabled once set
+const (
+	debugAllInit_ = int32(iota) // as DebugEnabled
+	debugAllOn_
+	debugAllOff_
 )
 
-// manage debug state for component
+// manage debug state for component.
+//
+// Enabled is the state when constructed.  Use On to get the current state,
+// which follows changes made at runtime (see EnableDebug).  Copies of a
+// constructed Debug follow changes as well.
 type Debug struct {
 	Enabled   bool
 	component string
 	Prefix    string
+	state     *atomic.Uint64 // debugGen_ << 1 | on, shared by copies
 }
 
 func NewDebug(component string) *Debug {
@@ -25,20 +44,52 @@ func NewDebug(component string) *Debug {
 
 // construct in place
 func (this *Debug) Construct(component string) *Debug {
-	this.Enabled = IsDebugEnabledFor(component)
 	this.component = component
 	this.Prefix = "DEBUG: " + component + ": "
+	this.state = &atomic.Uint64{}
+	this.Enabled = this.refresh(debugGen_.Load())
 	return this
 }
 
+// is debug enabled for the component?
+//
+// this is cheap unless debug state has changed since the last call.  if not
+// constructed, this is Enabled.
+func (this Debug) On() bool {
+	if nil == this.state {
+		return this.Enabled
+	}
+	gen := debugGen_.Load()
+	if state := this.state.Load(); gen == state>>1 {
+		return 1 == state&1
+	}
+	return this.refresh(gen)
+}
+
+// compute the state, caching it as of gen
+func (this Debug) refresh(gen uint64) (on bool) {
+	on = IsDebugEnabledFor(this.component)
+	state := gen << 1
+	if on {
+		state |= 1
+	}
+	this.state.Store(state)
+	return
+}
+
 // output a debug message for the component if it is enabled for debug
 func (this Debug) F(format string, args ...interface{}) {
-	if this.Enabled {
-		if 0 == len(args) {
-			log.Printf(this.Prefix + format)
-		} else {
-			log.Printf(this.Prefix+format, args...)
-		}
+	if !this.On() {
+		return
+	} else if nil != structured_.Load() || nil != getSink() ||
+		nil != routes_.Load() || nil != ring_.Load() {
+		emitf(LevelDebug, this.component, format, args)
+	} else if !allow(LevelDebug, this.component, format, args) {
+		return
+	} else if 0 == len(args) {
+		log.Print(this.Prefix + format)
+	} else {
+		log.Printf(this.Prefix+format, args...)
 	}
 }
 
@@ -48,7 +99,7 @@ func (this Debug) F(format string, args ...interface{}) {
 //	var b []byte
 //	d.F("hex dump:\n%s", d.Dump(b))
 func (this Debug) Dump(b []byte) string {
-	if this.Enabled {
+	if this.On() {
 		return hex.Dump(b)
 	}
 	return ""
@@ -61,65 +112,66 @@ func (this Debug) Dump(b []byte) string {
 //	var b []byte
 //	d.F("hex dump:\n%s", d.DumpIf(enableDump, b))
 func (this Debug) DumpIf(on bool, b []byte) string {
-	if on && this.Enabled {
+	if on && this.On() {
 		return hex.Dump(b)
 	}
 	return "[dump disabled]"
 }
 
 //
-// these are meant to be set upon program initialization, and then read-only
-// from that point on
+// these are meant to be set upon program initialization (see uinit/debug.go).
+// to change debug state at runtime, see EnableDebug and DisableDebug.
 //
 
 func SetDebugEnabledFor(component string) {
-	log.Println("ENABLING DEBUG FOR " + component)
-	debugEnabledFor_.Store(component, true)
-	//debugEnabledFor_[component] = true
+	Println("ENABLING DEBUG FOR " + component)
+	if _, loaded := debugEnabledFor_.LoadOrStore(component, true); !loaded {
+		debugEnabledCount_.Add(1)
+	}
+	debugGen_.Add(1)
 }
 
 func SetDebugDisabledFor(component string) {
-	log.Println("DISABLING DEBUG FOR " + component)
+	Println("DISABLING DEBUG FOR " + component)
 	debugDisabledFor_.Store(component, true)
-	//debugDisabledFor_[component] = true
+	debugGen_.Add(1)
 }
 
-// output a debug message if DebugEnabled
+// turn on or off all debug, letting constructed Debug values know
+func SetDebugEnabled(on bool) {
+	if on {
+		debugAll_.Store(debugAllOn_)
+	} else {
+		debugAll_.Store(debugAllOff_)
+	}
+	debugGen_.Add(1)
+}
+
+// output a debug message if IsDebugEnabled()
 func Debugf(format string, args ...interface{}) {
-	if DebugEnabled {
-		if 0 == len(args) {
-			log.Printf("DEBUG: " + format)
-		} else {
-			log.Printf("DEBUG: "+format, args...)
-		}
+	if IsDebugEnabled() {
+		emitf(LevelDebug, "", format, args)
 	}
 }
 
-// output a debug message if DebugEnabled
+// output a debug message if IsDebugEnabled()
 func Debug1(s string) {
-	if DebugEnabled {
-		log.Printf("DEBUG: " + s)
+	if IsDebugEnabled() {
+		emit(LevelDebug, "", s, nil)
 	}
 }
 
-// output a debug message if DebugEnabled
+// output a debug message if IsDebugEnabled()
 func Debugln(args ...interface{}) {
-	if DebugEnabled {
-		arr := [8]interface{}{}
-		slice := append(arr[:0], "DEBUG:")
-		slice = append(slice, args...)
-		log.Println(slice...)
+	if IsDebugEnabled() {
+		emit(LevelDebug, "", sprintln(args), nil)
 	}
 }
 
 // output a debug message if IsDebugEnabledFor("party")
 func DebugfFor(component string, format string, args ...interface{}) {
 	if IsDebugEnabledFor(component) {
-		if 0 == len(args) {
-			log.Printf("DEBUG: %s: %s", component, format)
-		} else {
-			log.Printf("DEBUG: "+component+": "+format, args...)
-		}
+		emitf(LevelDebug, component, format, args)
 	}
 }
 
@@ -127,23 +179,25 @@ func DebugfFor(component string, format string, args ...interface{}) {
 //
 // hint: set dbg to IsDebugEnabledFor() to compute that once, then reuse
 func DebugfIf(dbg bool, format string, args ...interface{}) {
-	if dbg || DebugEnabled {
-		if 0 == len(args) {
-			log.Printf("DEBUG: %s", format)
-		} else {
-			log.Printf("DEBUG: "+format, args...)
-		}
+	if dbg || IsDebugEnabled() {
+		emitf(LevelDebug, "", format, args)
 	}
 }
 
 // is debug enabled globally?
 func IsDebugEnabled() bool {
+	switch debugAll_.Load() {
+	case debugAllOn_:
+		return true
+	case debugAllOff_:
+		return false
+	}
 	return DebugEnabled
 }
 
 // is debug enabled for component?
 func IsDebugEnabledFor(component string) bool {
-	if DebugEnabled {
+	if IsDebugEnabled() {
 		_, ok := debugDisabledFor_.Load(component)
 		return !ok
 	} else {
@@ -0,0 +1,149 @@
+package ulog
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the component that stands for all debug in EnableDebug and DisableDebug
+const DebugAll = "all"
+
+var (
+	revertLock_ sync.Mutex
+	reverts_    = map[string]*revert_{} // pending reverts by component
+)
+
+// a pending return to a prior debug state
+type revert_ struct {
+	timer   *time.Timer
+	restore func()
+}
+
+// Turn on debug for component (or DebugAll) at runtime.  Constructed Debug
+// values follow the change.
+//
+// If revert is positive, then the prior state is restored after that long,
+// so that debug may be turned on in production for a short time:
+//
+//	ulog.EnableDebug("party", 5*time.Minute)
+//
+// A later change to the same component before the revert replaces the
+// pending revert, which still restores the state prior to the first change.
+func EnableDebug(component string, revert time.Duration) {
+	changeDebug(component, true, revert)
+}
+
+// Turn off debug for component (or DebugAll) at runtime.  See EnableDebug.
+func DisableDebug(component string, revert time.Duration) {
+	changeDebug(component, false, revert)
+}
+
+func changeDebug(component string, on bool, revert time.Duration) {
+	revertLock_.Lock()
+	defer revertLock_.Unlock()
+
+	pending := reverts_[component]
+	if nil != pending {
+		pending.timer.Stop()
+		delete(reverts_, component)
+	}
+	restore := setDebug(component, on)
+	if nil != pending {
+		restore = pending.restore
+	}
+	if 0 < revert {
+		pending = &revert_{restore: restore}
+		pending.timer = time.AfterFunc(revert, func() {
+			revertLock_.Lock()
+			defer revertLock_.Unlock()
+			if pending == reverts_[component] {
+				delete(reverts_, component)
+				pending.restore()
+			}
+		})
+		reverts_[component] = pending
+	}
+
+	state := "OFF"
+	if on {
+		state = "ON"
+	}
+	if 0 < revert {
+		Printf("DEBUG %s FOR %s (reverting in %s)", state, component, revert)
+	} else {
+		Printf("DEBUG %s FOR %s", state, component)
+	}
+}
+
+// set the debug state of component, returning a func to restore prior state
+func setDebug(component string, on bool) (restore func()) {
+	if DebugAll == component {
+		prior := IsDebugEnabled()
+		SetDebugEnabled(on)
+		return func() {
+			Printf("DEBUG REVERTED FOR %s", component)
+			SetDebugEnabled(prior)
+		}
+	}
+	_, wasEnabled := debugEnabledFor_.Load(component)
+	_, wasDisabled := debugDisabledFor_.Load(component)
+	setDebugFor(component, on, !on)
+	return func() {
+		Printf("DEBUG REVERTED FOR %s", component)
+		setDebugFor(component, wasEnabled, wasDisabled)
+	}
+}
+
+func setDebugFor(component string, enabled, disabled bool) {
+	if enabled {
+		if _, loaded := debugEnabledFor_.LoadOrStore(component, true); !loaded {
+			debugEnabledCount_.Add(1)
+		}
+	} else if _, loaded := debugEnabledFor_.LoadAndDelete(component); loaded {
+		debugEnabledCount_.Add(-1)
+	}
+	if disabled {
+		debugDisabledFor_.Store(component, true)
+	} else {
+		debugDisabledFor_.Delete(component)
+	}
+	debugGen_.Add(1)
+}
+
+// get the current debug state: whether all debug is on, and the components
+// with debug selectively enabled and disabled
+func DebugState() (all bool, enabled, disabled []string) {
+	all = IsDebugEnabled()
+	debugEnabledFor_.Range(func(k, _ any) bool {
+		enabled = append(enabled, k.(string))
+		return true
+	})
+	debugDisabledFor_.Range(func(k, _ any) bool {
+		disabled = append(disabled, k.(string))
+		return true
+	})
+	sort.Strings(enabled)
+	sort.Strings(disabled)
+	return
+}
+
+// Toggle all debug each time one of sigs is received.  When turned on, debug
+// is turned back off after revert, if positive.  uboot uses SIGUSR1:
+//
+//	kill -USR1 $pid
+func ToggleDebugOnSignal(revert time.Duration, sigs ...os.Signal) {
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, sigs...)
+	go func() {
+		for range sigC {
+			if IsDebugEnabled() {
+				DisableDebug(DebugAll, 0)
+			} else {
+				EnableDebug(DebugAll, revert)
+			}
+		}
+	}()
+}
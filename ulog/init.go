@@ -19,8 +19,29 @@ var (
 	stdout_  = true
 
 	maxSz_ = int64(40 * 1024 * 1024)
+
+	policy_ RotatePolicy  // see SetRotatePolicy
+	logWm_  *WriteManager // manages the log set up by Init, if any
+	output_ = "stdout"    // the output set up by Init
+
+	logW_ = io.Writer(os.Stdout) // set up by Init, before any async (see outputLock_)
+
+	sinkTimeFlags_ int // log time flags in effect before output went to a sink
 )
 
+// the log flags for time, which the syslog and journald daemons add
+const timeFlags_ = log.Ldate | log.Ltime | log.Lmicroseconds
+
+// Set the rotation and retention policy for log files set up by Init and
+// NewLogger, in addition to the size and keep settings.  Any log file already
+// set up by Init is updated.
+func SetRotatePolicy(policy RotatePolicy) {
+	policy_ = policy
+	if nil != logWm_ {
+		logWm_.SetPolicy(policy)
+	}
+}
+
 // Get name of log file to use, or 'stdout'
 //
 // This name is based on what was set in Init, so if stdout was configured
@@ -47,13 +68,21 @@ func NewLogger(base string, maxSz int64, keep int) (rv *log.Logger, err error) {
 
 	var w io.Writer
 	output := getLogName(base)
-	if "stdout" == output {
+	if nil != getSink() && !strings.ContainsRune(base, '/') {
+		rv = log.New(sinkWriter_{}, "", 0)
+		return
+	} else if "stdout" == output {
 		w = os.Stdout
 	} else {
 		if 0 >= maxSz {
 			maxSz = maxSz_
 		}
-		w, err = NewWriteManager(output, maxSz, keep)
+		var wMgr *WriteManager
+		wMgr, err = NewWriteManager(output, maxSz, keep)
+		if nil == err {
+			wMgr.SetPolicy(policy_)
+			w = wMgr
+		}
 	}
 	if nil == err {
 		rv = log.New(w, "", log.LstdFlags)
@@ -65,19 +94,40 @@ func NewLogger(base string, maxSz int64, keep int) (rv *log.Logger, err error) {
 //
 // If logF is empty or set to 'stdout', then use stdout
 //
+// If logF is 'syslog' or 'journald', then records go to the daemon, with
+// the severity, component and any key/value attributes of each.
+//
 // Otherwise, the log file will be written to and managed (rotated when
 // maxSz reached).
 //
+// Output to stdout or a file may be made asynchronous (see SetAsync).
+//
 // maxSz is the maximum output file size.  if unset, we use default of 40M.
 //
 // used by uboot
 func Init(logF string, maxSz int64, keep int) (err error) {
 
 	var w io.Writer
+	var sink recordSink_
+	logWm_ = nil
 
 	if 0 == len(logF) || "stdout" == logF {
 		stdout_ = true
 		w = os.Stdout
+		logF = "stdout"
+
+	} else if OutputSyslog == logF || OutputJournald == logF {
+		stdout_ = false
+		sink, err = newSink(logF)
+		if err != nil {
+			return
+		}
+		w = sinkWriter_{}
+		// the daemon adds the time
+		if nil == getSink() {
+			sinkTimeFlags_ = log.Flags() & timeFlags_
+		}
+		log.SetFlags(log.Flags() &^ timeFlags_)
 
 	} else { // file
 		stdout_ = false
@@ -90,12 +140,26 @@ func Init(logF string, maxSz int64, keep int) (err error) {
 		var wMgr *WriteManager
 		wMgr, err = NewWriteManager(logF, maxSz_, keep)
 		if nil != wMgr {
+			wMgr.SetPolicy(policy_)
 			dir_ = wMgr.Dir()
 			w = wMgr
+			logWm_ = wMgr
 		}
 	}
 	if nil == err {
-		log.SetOutput(w)
+		if nil == sink && nil != getSink() { // leaving the daemon
+			log.SetFlags(log.Flags() | sinkTimeFlags_)
+		}
+		setSink(sink)
+		outputLock_.Lock()
+		setOutput(w)
+		outputLock_.Unlock()
+		output_ = logF
 	}
 	return
 }
+
+// get the output set up by Init: 'stdout', 'syslog', 'journald', or the file
+func GetOutput() string {
+	return output_
+}
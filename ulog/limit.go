@@ -0,0 +1,243 @@
+package ulog
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// how messages are grouped by a Limit
+const (
+	LimitByFormat  = "format"  // same format string or message (default)
+	LimitBySite    = "site"    // same call site (file:line)
+	LimitByMessage = "message" // same message text, after formatting
+)
+
+// most distinct keys tracked by a limit per window - beyond that, messages
+// are not limited
+const maxLimitKeys = 10_000
+
+// Limit repeated log messages.  Once Burst messages with the same key (see
+// LimitByFormat) have been logged within Window, the rest in the Window are
+// suppressed, and a summary is logged at the end of the Window:
+//
+//	WARN: last message repeated 4123 times in 10s: connect failed: ...
+//
+// Suppressed messages are still counted (Warns, Errors, Todos).  Fatal
+// messages are never suppressed.
+type Limit struct {
+	Window time.Duration // period messages are limited over (0: no limit)
+	Burst  int           // messages allowed per key per Window (default: 1)
+	Key    string        // LimitByFormat (default), LimitBySite, or LimitByMessage
+}
+
+type limitKey_ struct {
+	level     slog.Level
+	component string
+	key       string
+}
+
+type limitEntry_ struct {
+	start      time.Time
+	count      int
+	suppressed int
+	msg        string // first suppressed message
+}
+
+type limiter_ struct {
+	Limit
+	lock     sync.Mutex
+	entries  map[limitKey_]*limitEntry_
+	sweeping bool
+}
+
+var (
+	limit_         atomic.Pointer[limiter_] // for all messages
+	limitFor_      sync.Map                 // component -> *limiter_
+	limitForCount_ atomic.Int32             // number of components limited
+)
+
+// Limit all messages, or only messages for component, if not empty.
+// A component limit applies instead of the limit for all messages.
+//
+// A zero Window removes the limit.  Any messages suppressed by a prior limit
+// are summarized.
+func SetLimit(component string, limit Limit) (err error) {
+	switch limit.Key {
+	case "":
+		limit.Key = LimitByFormat
+	case LimitByFormat, LimitBySite, LimitByMessage:
+	default:
+		return fmt.Errorf("unknown limit key '%s' - must be one of %s, %s, %s",
+			limit.Key, LimitByFormat, LimitBySite, LimitByMessage)
+	}
+	if 0 > limit.Window || 0 > limit.Burst {
+		return fmt.Errorf("limit window and burst must not be negative")
+	} else if 0 == limit.Burst {
+		limit.Burst = 1
+	}
+	var l *limiter_
+	if 0 != limit.Window {
+		l = &limiter_{Limit: limit, entries: map[limitKey_]*limitEntry_{}}
+	}
+	var prior *limiter_
+	if 0 == len(component) {
+		prior = limit_.Swap(l)
+	} else if nil == l {
+		if p, loaded := limitFor_.LoadAndDelete(component); loaded {
+			prior = p.(*limiter_)
+			limitForCount_.Add(-1)
+		}
+	} else if p, loaded := limitFor_.Swap(component, l); loaded {
+		prior = p.(*limiter_)
+	} else {
+		limitForCount_.Add(1)
+	}
+	if nil != prior {
+		prior.flush(true)
+	}
+	return
+}
+
+// remove all limits.  see SetLimit.
+func ClearLimits() {
+	SetLimit("", Limit{})
+	limitFor_.Range(func(k, _ any) bool {
+		SetLimit(k.(string), Limit{})
+		return true
+	})
+}
+
+// should the message be output, or is it suppressed by a limit?
+func allow(level slog.Level, component, format string, args []any) bool {
+	if LevelFatal <= level ||
+		(nil == limit_.Load() && 0 == limitForCount_.Load()) {
+		return true
+	}
+	l := limit_.Load()
+	if 0 != len(component) && 0 != limitForCount_.Load() {
+		if p, ok := limitFor_.Load(component); ok {
+			l = p.(*limiter_)
+		}
+	}
+	if nil == l {
+		return true
+	}
+	return l.allow(level, component, format, args)
+}
+
+func (this *limiter_) allow(
+	level slog.Level,
+	component, format string,
+	args []any,
+) bool {
+	key := limitKey_{level: level, component: component, key: format}
+	switch this.Key {
+	case LimitBySite:
+		key.key = callSite()
+	case LimitByMessage:
+		if 0 != len(args) {
+			key.key = fmt.Sprintf(format, args...)
+		}
+	}
+	now := time.Now()
+
+	this.lock.Lock()
+	e := this.entries[key]
+	var summary *limitEntry_
+	if nil != e && this.Window <= now.Sub(e.start) {
+		if 0 != e.suppressed {
+			summary = e
+		}
+		delete(this.entries, key)
+		e = nil
+	}
+	if nil == e {
+		if maxLimitKeys <= len(this.entries) {
+			this.lock.Unlock()
+			this.summarize(key, summary)
+			return true
+		}
+		e = &limitEntry_{start: now}
+		this.entries[key] = e
+		if !this.sweeping {
+			this.sweeping = true
+			time.AfterFunc(this.Window, func() { this.flush(false) })
+		}
+	}
+	e.count++
+	allowed := e.count <= this.Burst
+	if !allowed {
+		e.suppressed++
+		if 1 == e.suppressed {
+			e.msg = format
+			if 0 != len(args) {
+				e.msg = fmt.Sprintf(format, args...)
+			}
+		}
+	}
+	this.lock.Unlock()
+
+	this.summarize(key, summary)
+	return allowed
+}
+
+// remove expired entries (or all, if all), summarizing suppressed messages
+func (this *limiter_) flush(all bool) {
+	type summary_ struct {
+		key limitKey_
+		e   *limitEntry_
+	}
+	var summaries []summary_
+	now := time.Now()
+
+	this.lock.Lock()
+	for key, e := range this.entries {
+		if all || this.Window <= now.Sub(e.start) {
+			if 0 != e.suppressed {
+				summaries = append(summaries, summary_{key, e})
+			}
+			delete(this.entries, key)
+		}
+	}
+	if 0 != len(this.entries) && !all {
+		time.AfterFunc(this.Window, func() { this.flush(false) })
+	} else {
+		this.sweeping = false
+	}
+	this.lock.Unlock()
+
+	for _, s := range summaries {
+		this.summarize(s.key, s.e)
+	}
+}
+
+// output the summary of suppressed messages, if any
+func (this *limiter_) summarize(key limitKey_, e *limitEntry_) {
+	if nil != e {
+		output(key.level, key.component,
+			fmt.Sprintf("last message repeated %d times in %s: %s",
+				e.suppressed, this.Window, e.msg), nil)
+	}
+}
+
+// the file:line of the first caller outside of ulog (and slog)
+func callSite() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !(strings.HasPrefix(frame.Function, "log/slog.") ||
+			(strings.HasPrefix(frame.Function, "github.com/tredeske/u/ulog.") &&
+				!strings.HasSuffix(frame.File, "_test.go"))) ||
+			!more {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+	}
+}
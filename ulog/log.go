@@ -31,7 +31,7 @@
 // Guard methods help detect when debug is disabled so that expensive operations
 // are not performed.
 //
-//	if ulog.DebugEnabled {
+//	if ulog.IsDebugEnabled() {
 //	    ulog.Debugf("some debug message: %s", expensive())
 //	}
 //	if ulog.IsDebugEnabledFor("party") {
@@ -47,6 +47,64 @@
 // The 'For' methods indicate you are emitting a debug message 'for' a
 // party / component.  These can be selectively enabled / disabled.
 //
+// Debug may be changed at runtime, optionally reverting after a time, and
+// constructed Debug values follow the change (see Debug.On).
+//
+//	ulog.EnableDebug("party", 5*time.Minute)
+//	ulog.EnableDebug(ulog.DebugAll, 5*time.Minute)
+//	ulog.DisableDebug("party", 0)
+//	ulog.ToggleDebugOnSignal(5*time.Minute, syscall.SIGUSR1)
+//
+// Structured:
+//
+// Info, Warn and Error log key/value attributes after the message, in the
+// same form used by errors with fields attached by uerr.With.
+//
+//	ulog.Warn("slow response", "host", host, "elapsed", elapsed)
+//
+// InfoContext, WarnContext and ErrorContext also add attributes from the
+// context, such as trace and span IDs (see AddContextFields and utrace).
+//
+// SetFormat (or 'format' in the 'logging' config section) switches output to
+// slog text or JSON lines, where every message is a record with level,
+// component (for debug) and attributes.  Handler provides a slog.Handler, so
+// code using slog can log through ulog.
+//
+//	logging:
+//	    format: json
+//
+// Routing:
+//
+// SetRoutes (or 'sinks' and 'routes' in the 'logging' config section) sends
+// records for some components or levels to other log files or outputs, each
+// rotated on its own, so that high volume debug does not drown the main log.
+//
+//	logging:
+//	    sinks:
+//	      - name:   proto
+//	        output: proto.log
+//	    routes:
+//	      - components: ["proto*"]
+//	        sink:       proto
+//
+// Recent:
+//
+// SetRing (or 'ring' in the 'logging' config section) keeps the most recent
+// records in memory, to be queried (Ring.Query) or fetched over HTTP, even
+// without shell access to the host.
+//
+//	http.Handle("/debug/log", ulog.RingHandler())
+//	curl 'http://host:port/debug/log?level=error&since=1h'
+//
+// Limits:
+//
+// SetLimit (or 'limit' and 'limits' in the 'logging' config section) limits
+// repeated messages, so that a flood of the same warning does not rotate away
+// useful history.  Suppressed messages are summarized at the end of each
+// window, and are still counted.
+//
+//	ulog.SetLimit("", ulog.Limit{Window: 10 * time.Second, Burst: 5})
+//
 // Config / Setup:
 //
 // The log will be set up according to the command line flags
@@ -91,7 +149,6 @@ package ulog
 
 import (
 	"fmt"
-	"log"
 	"sync/atomic"
 
 	"github.com/tredeske/u/uexit"
@@ -110,46 +167,30 @@ var (
 
 func TODO(format string, args ...any) {
 	atomic.AddInt64(&Todos, 1)
-	if 0 == len(args) {
-		log.Printf("TODO: " + format)
-	} else {
-		log.Printf("TODO: "+format, args...)
-	}
+	emitf(LevelTodo, "", format, args)
 }
 
 func Println(args ...any) {
-	log.Println(args...)
+	emit(LevelInfo, "", sprintln(args), nil)
 }
 
 func Printf(format string, args ...any) {
-	log.Printf(format, args...)
+	emitf(LevelInfo, "", format, args)
 }
 
 func Warnf(format string, args ...any) {
 	atomic.AddInt64(&Warns, 1)
-	if 0 == len(args) {
-		log.Printf("WARN: " + format)
-	} else {
-		log.Printf("WARN: "+format, args...)
-	}
+	emitf(LevelWarn, "", format, args)
 }
 
 func Errorf(format string, args ...any) {
 	atomic.AddInt64(&Errors, 1)
-	if 0 == len(args) {
-		log.Printf("ERROR: " + format)
-	} else {
-		log.Printf("ERROR: "+format, args...)
-	}
+	emitf(LevelError, "", format, args)
 }
 
 // log message and exit program with status 1
 func Fatalf(format string, args ...any) {
-	if 0 == len(args) {
-		log.Printf("FATAL: " + format)
-	} else {
-		log.Printf("FATAL: "+format, args...)
-	}
+	emitf(LevelFatal, "", format, args)
 	uexit.Exit(1)
 }
 
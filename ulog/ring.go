@@ -0,0 +1,331 @@
+package ulog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// a log record kept by a Ring
+type Record struct {
+	Time      time.Time  `json:"time"`
+	Level     slog.Level `json:"level"`
+	Component string     `json:"component,omitempty"`
+	Message   string     `json:"msg"`
+	Fields    []string   `json:"fields,omitempty"` // key, value, ...
+}
+
+// the size of the record for Ring byte accounting
+func (this *Record) size() (rv int) {
+	rv = 64 + len(this.Component) + len(this.Message)
+	for _, f := range this.Fields {
+		rv += 16 + len(f)
+	}
+	return
+}
+
+// the record as a classic log line
+func (this *Record) String() string {
+	kv := make([]any, len(this.Fields))
+	for i, f := range this.Fields {
+		kv[i] = f
+	}
+	return this.Time.Format("2006/01/02 15:04:05.000000 ") +
+		classicLine(this.Level, this.Component, this.Message, kv)
+}
+
+// select records from a Ring.  the zero Query selects all records at
+// LevelInfo and above.
+type Query struct {
+	MinLevel  slog.Level // records at or above this level
+	Component string     // glob of component (empty: any)
+	Contains  string     // substring of message (empty: any)
+	Since     time.Time  // records at or after (zero: any)
+	Until     time.Time  // records before (zero: any)
+	Limit     int        // most recent records (0: all)
+}
+
+// does the record match?
+func (this *Query) matches(r *Record) bool {
+	if this.MinLevel > r.Level ||
+		(!this.Since.IsZero() && r.Time.Before(this.Since)) ||
+		(!this.Until.IsZero() && !r.Time.Before(this.Until)) ||
+		(0 != len(this.Contains) && !strings.Contains(r.Message, this.Contains)) {
+		return false
+	} else if 0 != len(this.Component) {
+		matched, _ := path.Match(this.Component, r.Component)
+		return matched
+	}
+	return true
+}
+
+// Ring keeps the most recent log records in memory, bounded by a count and
+// by an approximate number of bytes, so that recent logs can be fetched from
+// a running process (see ServeHTTP).
+type Ring struct {
+	lock     sync.Mutex
+	records  []Record // circular
+	head     int      // oldest
+	count    int
+	bytes    int
+	maxBytes int
+	watchers map[*watcher_]struct{}
+}
+
+type watcher_ struct {
+	q Query
+	c chan Record
+}
+
+// create a Ring with up to max records taking up to maxBytes (0: no limit)
+func NewRing(max, maxBytes int) *Ring {
+	if 0 >= max {
+		max = 1
+	}
+	return &Ring{
+		records:  make([]Record, max),
+		maxBytes: maxBytes,
+		watchers: map[*watcher_]struct{}{},
+	}
+}
+
+// add the record, dropping the oldest records to make room
+func (this *Ring) Add(r Record) {
+	size := r.size()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for 0 != this.count && (len(this.records) == this.count ||
+		(0 != this.maxBytes && this.maxBytes < this.bytes+size)) {
+		this.bytes -= this.records[this.head].size()
+		this.records[this.head] = Record{}
+		this.head = (this.head + 1) % len(this.records)
+		this.count--
+	}
+	this.records[(this.head+this.count)%len(this.records)] = r
+	this.count++
+	this.bytes += size
+	for w := range this.watchers {
+		if w.q.matches(&r) {
+			select { // a slow watcher misses records rather than slowing logging
+			case w.c <- r:
+			default:
+			}
+		}
+	}
+}
+
+// get the records matching q, oldest first
+func (this *Ring) Query(q Query) (rv []Record) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for i := this.count - 1; 0 <= i; i-- { // newest first, for Limit
+		r := &this.records[(this.head+i)%len(this.records)]
+		if q.matches(r) {
+			rv = append(rv, *r)
+			if len(rv) == q.Limit {
+				break
+			}
+		}
+	}
+	for i, j := 0, len(rv)-1; i < j; i, j = i+1, j-1 {
+		rv[i], rv[j] = rv[j], rv[i]
+	}
+	return
+}
+
+// get new records matching q until cancel is called.  if the receiver falls
+// behind, records are skipped.
+func (this *Ring) Watch(q Query) (records <-chan Record, cancel func()) {
+	w := &watcher_{q: q, c: make(chan Record, 256)}
+	this.lock.Lock()
+	this.watchers[w] = struct{}{}
+	this.lock.Unlock()
+	return w.c, func() {
+		this.lock.Lock()
+		delete(this.watchers, w)
+		this.lock.Unlock()
+	}
+}
+
+// Serve records as text lines (or JSON, with format=json), selected by the
+// query parameters:
+//
+//	level=warn               - at or above level (default: info)
+//	component=proto*         - component matching glob
+//	contains=timeout         - message containing substring
+//	since=10m                - in last duration, or since RFC 3339 time
+//	until=2024-01-02T15:04:05Z
+//	limit=100                - most recent records
+//	follow=true              - stream new records until disconnect
+//
+// For example, to see recent errors:
+//
+//	curl 'http://host:port/debug/log?level=error&since=1h'
+func (this *Ring) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	q, err := parseQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := req.URL.Query()
+	asJson := "json" == params.Get("format")
+	follow, _ := strconv.ParseBool(params.Get("follow"))
+
+	var records <-chan Record
+	if follow { // before query, to not miss any
+		var cancel func()
+		records, cancel = this.Watch(q)
+		defer cancel()
+	}
+	if asJson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	enc := json.NewEncoder(w)
+	write := func(r *Record) error {
+		if asJson {
+			return enc.Encode(r)
+		}
+		_, err := fmt.Fprintln(w, r.String())
+		return err
+	}
+	var last time.Time
+	for _, r := range this.Query(q) {
+		if err = write(&r); err != nil {
+			return
+		}
+		last = r.Time
+	}
+	if !follow {
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	for {
+		if nil != flusher {
+			flusher.Flush()
+		}
+		select {
+		case <-req.Context().Done():
+			return
+		case r := <-records:
+			if !r.Time.After(last) { // already sent by query
+				continue
+			} else if err = write(&r); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// get the Query from the request parameters (see ServeHTTP)
+func parseQuery(req *http.Request) (q Query, err error) {
+	params := req.URL.Query()
+	if level := params.Get("level"); 0 != len(level) {
+		q.MinLevel, err = ParseLevel(level)
+		if err != nil {
+			return
+		}
+	}
+	q.Component = params.Get("component")
+	q.Contains = params.Get("contains")
+	q.Since, err = parseWhen(params.Get("since"))
+	if err != nil {
+		return
+	}
+	q.Until, err = parseWhen(params.Get("until"))
+	if err != nil {
+		return
+	}
+	if limit := params.Get("limit"); 0 != len(limit) {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			err = fmt.Errorf("invalid limit '%s'", limit)
+		}
+	}
+	return
+}
+
+// parse a duration ago, or an RFC 3339 time
+func parseWhen(s string) (rv time.Time, err error) {
+	if 0 == len(s) {
+		return
+	} else if d, perr := time.ParseDuration(s); nil == perr {
+		return time.Now().Add(-d), nil
+	}
+	rv, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		err = fmt.Errorf("invalid time '%s' - must be duration or RFC 3339", s)
+	}
+	return
+}
+
+// the Ring set up by SetRing, if any
+var ring_ atomic.Pointer[Ring]
+
+// Keep the most recent log records in memory, up to max records taking up to
+// maxBytes.  A max of 0 stops keeping records.  Any records already kept are
+// carried over, as space allows.
+func SetRing(max, maxBytes int) {
+	prior := ring_.Load()
+	if nil != prior && max == len(prior.records) && maxBytes == prior.maxBytes {
+		return
+	}
+	var r *Ring
+	if 0 < max {
+		r = NewRing(max, maxBytes)
+		if nil != prior {
+			for _, rec := range prior.Query(Query{MinLevel: LevelDebug}) {
+				r.Add(rec)
+			}
+		}
+	}
+	ring_.Store(r)
+}
+
+// get the Ring set up by SetRing, or nil if records are not kept
+func GetRing() *Ring {
+	return ring_.Load()
+}
+
+// Get an http.Handler for the Ring set up by SetRing (see Ring.ServeHTTP).
+// This follows changes to the Ring, responding with 404 if there is none.
+//
+//	http.Handle("/debug/log", ulog.RingHandler())
+func RingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r := ring_.Load(); nil != r {
+			r.ServeHTTP(w, req)
+		} else {
+			http.Error(w, "log ring not enabled", http.StatusNotFound)
+		}
+	})
+}
+
+// keep the record in the Ring, if any
+func keep(level slog.Level, component, msg string, kv []any) {
+	r := ring_.Load()
+	if nil == r {
+		return
+	}
+	rec := Record{
+		Time:      time.Now(),
+		Level:     level,
+		Component: component,
+		Message:   msg,
+	}
+	if 0 != len(kv) {
+		rec.Fields = make([]string, len(kv))
+		for i, f := range kv {
+			rec.Fields[i] = fieldString(f)
+		}
+	}
+	r.Add(rec)
+}
@@ -0,0 +1,290 @@
+package ulog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tredeske/u/uerr"
+)
+
+// the name of the main log (see Init) as a Route destination
+const SinkMain = "main"
+
+// Sink is a named destination for log records routed by a Route
+type Sink struct {
+	Name   string       // name used by routes
+	Output string       // stdout, syslog, journald, or a log file
+	Size   int64        // size of log file before rotation (default: as Init)
+	Keep   int          // log files to keep around (default: 4)
+	Policy RotatePolicy // additional rotation and retention for a log file
+}
+
+// Route log records for matching components and levels to a Sink, instead of
+// to the main log.  The first matching route is used.
+//
+// Routes apply to all ulog output, including debug (DebugfFor, Debug.F) and
+// slog records through Handler.
+type Route struct {
+	Components []string     // components matching these globs (empty: any)
+	Levels     []slog.Level // records at these levels (empty: any)
+	Sink       string       // the name of the Sink, or SinkMain
+	Tee        bool         // also output to the main log
+}
+
+// does the route match the record?
+func (this *Route) matches(level slog.Level, component string) bool {
+	if 0 != len(this.Levels) {
+		found := false
+		for _, l := range this.Levels {
+			if l == level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if 0 == len(this.Components) {
+		return true
+	}
+	for _, glob := range this.Components {
+		if matched, _ := path.Match(glob, component); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// get the level named by name (debug, info, todo, warn, error, fatal)
+func ParseLevel(name string) (level slog.Level, err error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		level = LevelDebug
+	case "info":
+		level = LevelInfo
+	case "todo":
+		level = LevelTodo
+	case "warn", "warning":
+		level = LevelWarn
+	case "error":
+		level = LevelError
+	case "fatal":
+		level = LevelFatal
+	default:
+		err = fmt.Errorf("unknown log level '%s'", name)
+	}
+	return
+}
+
+// where a Sink writes records
+type target_ struct {
+	sink   recordSink_  // if syslog or journald
+	w      io.Writer    // otherwise
+	logger *log.Logger  // for FormatLog
+	text   slog.Handler // for FormatText
+	json   slog.Handler // for FormatJson
+}
+
+func (this *target_) output(level slog.Level, component, msg string, kv []any) {
+	if nil != this.sink {
+		this.sink.emit(level, component, msg, kv)
+		return
+	}
+	switch GetFormat() {
+	case FormatJson:
+		this.json.Handle(context.Background(), newRecord(level, component, msg, kv))
+	case FormatText:
+		this.text.Handle(context.Background(), newRecord(level, component, msg, kv))
+	default:
+		this.logger.Print(classicLine(level, component, msg, kv))
+	}
+}
+
+func (this *target_) close() {
+	if nil != this.sink {
+		this.sink.close()
+	} else if wm, ok := this.w.(*WriteManager); ok {
+		wm.Close()
+	}
+}
+
+// open the target for the sink.  a log file with no path goes in the same
+// directory as the main log.  if the main log is stdout, so is a log file.
+func openTarget(sink Sink) (rv *target_, err error) {
+	rv = &target_{}
+	switch sink.Output {
+	case OutputSyslog, OutputJournald:
+		rv.sink, err = newSink(sink.Output)
+		if err != nil {
+			rv = nil
+		}
+		return
+	}
+	if output := getLogName(sink.Output); "stdout" == output {
+		rv.w = os.Stdout
+	} else {
+		size, keep := sink.Size, sink.Keep
+		if 0 >= size {
+			size = maxSz_
+		}
+		if 0 == keep {
+			keep = 4
+		} else if 2 > keep {
+			keep = 2
+		}
+		var wm *WriteManager
+		wm, err = NewWriteManager(output, size, keep)
+		if err != nil {
+			rv = nil
+			return
+		}
+		wm.SetPolicy(sink.Policy)
+		rv.w = wm
+	}
+	rv.logger = log.New(rv.w, "", log.LstdFlags)
+	rv.text = newHandler(FormatText, rv.w)
+	rv.json = newHandler(FormatJson, rv.w)
+	return
+}
+
+type routeKey_ struct {
+	level     slog.Level
+	component string
+}
+
+// the routes and the targets of their sinks
+type router_ struct {
+	routes  []Route
+	targets []*target_   // by route, nil for SinkMain
+	cache   sync.Map     // routeKey_ -> index of route, or -1 for none
+	lock    sync.RWMutex // read locked while using targets, so close can drain
+	closed  bool
+}
+
+var routes_ atomic.Pointer[router_] // see SetRoutes
+
+// output the record to the target of the first matching route, returning
+// true if done (not to also go to the main log).  once closed (replaced by
+// SetRoutes), records go to the main log.
+func (this *router_) output(level slog.Level, component, msg string, kv []any) bool {
+	key := routeKey_{level: level, component: component}
+	i := -1
+	if cached, ok := this.cache.Load(key); ok {
+		i = cached.(int)
+	} else {
+		for j := range this.routes {
+			if this.routes[j].matches(level, component) {
+				i = j
+				break
+			}
+		}
+		this.cache.Store(key, i)
+	}
+	if 0 > i || nil == this.targets[i] {
+		return false
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if this.closed {
+		return false
+	}
+	this.targets[i].output(level, component, msg, kv)
+	return !this.routes[i].Tee
+}
+
+// close the targets once any outputs in progress are done
+func (this *router_) close() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.closed = true
+	closed := map[*target_]bool{}
+	for _, t := range this.targets {
+		if nil != t && !closed[t] {
+			closed[t] = true
+			t.close()
+		}
+	}
+}
+
+// Route log records to sinks, replacing any prior routes and closing prior
+// sinks.  With no routes, all records go to the main log.
+//
+//	err := ulog.SetRoutes(
+//	    []ulog.Sink{{Name: "proto", Output: "proto.log", Size: 100_000_000}},
+//	    []ulog.Route{{
+//	        Components: []string{"proto*"},
+//	        Levels:     []slog.Level{ulog.LevelDebug},
+//	        Sink:       "proto",
+//	    }})
+func SetRoutes(sinks []Sink, routes []Route) (err error) {
+	var r *router_
+	if 0 != len(routes) {
+		r, err = newRouter(sinks, routes)
+		if err != nil {
+			return
+		}
+	}
+	if prior := routes_.Swap(r); nil != prior {
+		prior.close()
+	}
+	return
+}
+
+func newRouter(sinks []Sink, routes []Route) (rv *router_, err error) {
+	byName := make(map[string]Sink, len(sinks))
+	for _, sink := range sinks {
+		if 0 == len(sink.Name) || SinkMain == sink.Name {
+			return nil, fmt.Errorf("log sink name '%s' invalid", sink.Name)
+		} else if _, exists := byName[sink.Name]; exists {
+			return nil, fmt.Errorf("log sink '%s' defined more than once", sink.Name)
+		}
+		byName[sink.Name] = sink
+	}
+	rv = &router_{
+		routes:  routes,
+		targets: make([]*target_, len(routes)),
+	}
+	opened := map[string]*target_{}
+	for i, route := range routes {
+		for _, glob := range route.Components {
+			if _, err = path.Match(glob, ""); err != nil {
+				err = uerr.Chainf(err, "log route component '%s' invalid", glob)
+				break
+			}
+		}
+		if err != nil {
+			break
+		} else if SinkMain == route.Sink {
+			continue
+		}
+		sink, exists := byName[route.Sink]
+		if !exists {
+			err = fmt.Errorf("log route to unknown sink '%s'", route.Sink)
+			break
+		}
+		t := opened[sink.Name]
+		if nil == t {
+			t, err = openTarget(sink)
+			if err != nil {
+				err = uerr.Chainf(err, "opening log sink '%s'", sink.Name)
+				break
+			}
+			opened[sink.Name] = t
+		}
+		rv.targets[i] = t
+	}
+	if err != nil {
+		rv.close()
+		rv = nil
+	}
+	return
+}
@@ -0,0 +1,385 @@
+package ulog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tredeske/u/uerr"
+)
+
+// output formats.  see SetFormat.
+const (
+	FormatLog  = "log"  // classic log lines, such as "WARN: message" (default)
+	FormatText = "text" // slog text lines, with key=value attributes
+	FormatJson = "json" // slog JSON lines
+)
+
+// log levels, extending the slog levels with TODO and FATAL
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelTodo  = slog.Level(2)
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+	LevelFatal = slog.Level(12)
+)
+
+// the slog handler producing structured output, or nil if FormatLog
+var structured_ atomic.Pointer[slog.Handler]
+
+// Set the output format to FormatLog, FormatText, or FormatJson.
+//
+// With FormatText or FormatJson, each ulog function (and slog, if using
+// Handler) produces a record with the level, message, component (for debug),
+// and any attributes.
+//
+// Output goes to the same place as the golang standard log (see Init), so
+// WriteManager rotation keeps working.
+func SetFormat(format string) (err error) {
+	var h slog.Handler
+	switch format {
+	case "", FormatLog:
+	case FormatText, FormatJson:
+		h = newHandler(format, logWriter_{})
+	default:
+		return fmt.Errorf("unknown log format '%s' - must be one of %s, %s, %s",
+			format, FormatLog, FormatText, FormatJson)
+	}
+	if nil == h {
+		structured_.Store(nil)
+	} else {
+		structured_.Store(&h)
+	}
+	return
+}
+
+// create the slog handler for FormatText or FormatJson, writing to w
+func newHandler(format string, w io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       LevelDebug, // ulog decides what is enabled
+		ReplaceAttr: replaceLevel,
+	}
+	if FormatJson == format {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// get the output format
+func GetFormat() string {
+	if h := structured_.Load(); nil != h {
+		if _, isJson := (*h).(*slog.JSONHandler); isJson {
+			return FormatJson
+		}
+		return FormatText
+	}
+	return FormatLog
+}
+
+// name the levels slog does not know about
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if slog.LevelKey == a.Key && 0 == len(groups) {
+		switch a.Value.Any() {
+		case LevelTodo:
+			a.Value = slog.StringValue("TODO")
+		case LevelFatal:
+			a.Value = slog.StringValue("FATAL")
+		}
+	}
+	return a
+}
+
+// write to wherever the golang standard log is currently writing
+type logWriter_ struct{}
+
+func (logWriter_) Write(b []byte) (int, error) {
+	return log.Writer().Write(b)
+}
+
+// the prefix of classic log lines for the level
+func levelPrefix(level slog.Level) string {
+	switch {
+	case LevelFatal <= level:
+		return "FATAL: "
+	case LevelError <= level:
+		return "ERROR: "
+	case LevelWarn <= level:
+		return "WARN: "
+	case LevelTodo <= level:
+		return "TODO: "
+	case LevelInfo <= level:
+		return ""
+	}
+	return "DEBUG: "
+}
+
+// emit a printf style message at level, for component (if any), unless
+// suppressed by a limit (see SetLimit)
+func emitf(level slog.Level, component, format string, args []any) {
+	if !allow(level, component, format, args) {
+		return
+	} else if 0 != len(args) {
+		format = fmt.Sprintf(format, args...)
+	}
+	output(level, component, format, nil)
+}
+
+// emit the message at level, for component (if any), with key/value attrs,
+// unless suppressed by a limit (see SetLimit)
+func emit(level slog.Level, component, msg string, kv []any) {
+	if allow(level, component, msg, nil) {
+		output(level, component, msg, kv)
+	}
+}
+
+// output the message at level, for component (if any), with key/value attrs,
+// to where it is routed (see SetRoutes), keeping it in the Ring, if any
+func output(level slog.Level, component, msg string, kv []any) {
+	keep(level, component, msg, kv)
+	if r := routes_.Load(); nil != r && r.output(level, component, msg, kv) {
+		return
+	}
+	outputMain(level, component, msg, kv)
+}
+
+// output the message to the main log
+func outputMain(level slog.Level, component, msg string, kv []any) {
+	if sink := getSink(); nil != sink {
+		sink.emit(level, component, msg, kv)
+		return
+	} else if aw := async_.Load(); nil != aw && LevelInfo > level &&
+		io.Writer(aw) == log.Writer() {
+		outputAsyncDebug(aw, level, component, msg, kv)
+		return
+	} else if h := structured_.Load(); nil != h {
+		(*h).Handle(context.Background(), newRecord(level, component, msg, kv))
+		return
+	}
+	log.Print(classicLine(level, component, msg, kv))
+}
+
+// render the debug message as outputMain would, and queue it with its level,
+// so that it can be dropped if the queue is full (see OverflowDropDebug)
+func outputAsyncDebug(
+	aw *AsyncWriter,
+	level slog.Level,
+	component, msg string,
+	kv []any,
+) {
+	var b bytes.Buffer
+	if h := structured_.Load(); nil != h {
+		newHandler(GetFormat(), &b).Handle(context.Background(),
+			newRecord(level, component, msg, kv))
+	} else {
+		log.New(&b, log.Prefix(), log.Flags()).
+			Output(2, classicLine(level, component, msg, kv))
+	}
+	aw.write(level, b.Bytes())
+}
+
+// the classic log line for the message, without date and time
+func classicLine(level slog.Level, component, msg string, kv []any) string {
+	prefix := levelPrefix(level)
+	if 0 != len(component) {
+		prefix += component + ": "
+	}
+	if 0 != len(kv) {
+		msg += " " + uerr.FormatFields(kv...)
+	}
+	return prefix + msg
+}
+
+// a slog record for the message
+func newRecord(level slog.Level, component, msg string, kv []any) slog.Record {
+	r := slog.NewRecord(time.Now(), level, msg, 0)
+	if 0 != len(component) {
+		r.AddAttrs(slog.String("component", component))
+	}
+	r.Add(kv...)
+	return r
+}
+
+// count the message at level
+func count(level slog.Level) {
+	switch {
+	case LevelFatal <= level:
+	case LevelError <= level:
+		atomic.AddInt64(&Errors, 1)
+	case LevelWarn <= level:
+		atomic.AddInt64(&Warns, 1)
+	case LevelTodo <= level:
+		atomic.AddInt64(&Todos, 1)
+	}
+}
+
+// Get a slog.Handler that writes to ulog, so that code using slog has its
+// output counted (Warns, Errors) and written like the rest of ulog.
+//
+//	slog.SetDefault(slog.New(ulog.Handler()))
+//
+// Debug records are enabled if debug is enabled (see IsDebugEnabled), or if
+// enabled for the "component" attribute.
+func Handler() slog.Handler {
+	return &handler_{}
+}
+
+type handler_ struct {
+	component string
+	attrs     []any // key/value, with group prefix applied
+	group     string
+}
+
+// implement slog.Handler
+func (this *handler_) Enabled(_ context.Context, level slog.Level) bool {
+	if LevelInfo <= level || IsDebugEnabled() {
+		return true
+	} else if 0 != len(this.component) {
+		return IsDebugEnabledFor(this.component)
+	}
+	return 0 != debugEnabledCount_.Load()
+}
+
+// implement slog.Handler
+func (this *handler_) Handle(ctx context.Context, r slog.Record) error {
+	component := this.component
+	kv := contextFields(ctx, append([]any(nil), this.attrs...))
+	r.Attrs(func(a slog.Attr) bool {
+		if "component" == a.Key && 0 == len(this.group) {
+			component = a.Value.String()
+		} else {
+			kv = appendAttr(kv, this.group, a)
+		}
+		return true
+	})
+	if LevelInfo > r.Level && !IsDebugEnabledFor(component) {
+		return nil
+	}
+	count(r.Level)
+	emit(r.Level, component, r.Message, kv)
+	return nil
+}
+
+// implement slog.Handler
+func (this *handler_) WithAttrs(attrs []slog.Attr) slog.Handler {
+	rv := *this
+	rv.attrs = append([]any(nil), this.attrs...)
+	for _, a := range attrs {
+		if "component" == a.Key && 0 == len(this.group) {
+			rv.component = a.Value.String()
+		} else {
+			rv.attrs = appendAttr(rv.attrs, this.group, a)
+		}
+	}
+	return &rv
+}
+
+// implement slog.Handler
+func (this *handler_) WithGroup(name string) slog.Handler {
+	if 0 == len(name) {
+		return this
+	}
+	rv := *this
+	rv.group = this.group + name + "."
+	return &rv
+}
+
+// flatten the attr into key/values, with keys prefixed by group
+func appendAttr(kv []any, group string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if slog.KindGroup == a.Value.Kind() {
+		if 0 != len(a.Key) {
+			group += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kv = appendAttr(kv, group, ga)
+		}
+		return kv
+	} else if 0 == len(a.Key) {
+		return kv
+	}
+	return append(kv, group+a.Key, a.Value.Any())
+}
+
+// log the message with key/value attributes.  with FormatLog, attributes are
+// rendered the same way as fields attached to errors (see uerr.With):
+//
+//	ulog.Info("connected", "host", host, "port", port)
+//	-> connected [host=example.com port=80]
+func Info(msg string, kv ...any) {
+	emit(LevelInfo, "", msg, kv)
+}
+
+// log the warning with key/value attributes.  see Info.
+func Warn(msg string, kv ...any) {
+	atomic.AddInt64(&Warns, 1)
+	emit(LevelWarn, "", msg, kv)
+}
+
+// log the error with key/value attributes.  see Info.
+func Error(msg string, kv ...any) {
+	atomic.AddInt64(&Errors, 1)
+	emit(LevelError, "", msg, kv)
+}
+
+// funcs getting key/value attributes from a context (see AddContextFields)
+var contextFuncs_ atomic.Pointer[[]func(context.Context) []any]
+
+// Add fn to get key/value attributes from the context of messages logged with
+// a context (InfoContext, WarnContext, ErrorContext, and slog ...Context calls
+// through Handler).  This is how utrace adds trace and span IDs.
+func AddContextFields(fn func(ctx context.Context) (kv []any)) {
+	for {
+		prior := contextFuncs_.Load()
+		var fns []func(context.Context) []any
+		if nil != prior {
+			fns = append(fns, *prior...)
+		}
+		fns = append(fns, fn)
+		if contextFuncs_.CompareAndSwap(prior, &fns) {
+			return
+		}
+	}
+}
+
+// append the attributes from ctx (see AddContextFields) to kv
+func contextFields(ctx context.Context, kv []any) []any {
+	if fns := contextFuncs_.Load(); nil != fns && nil != ctx {
+		kv = kv[:len(kv):len(kv)] // do not append into the caller's slice
+		for _, fn := range *fns {
+			kv = append(kv, fn(ctx)...)
+		}
+	}
+	return kv
+}
+
+// log the message with key/value attributes, adding any attributes from ctx
+// (see AddContextFields)
+func InfoContext(ctx context.Context, msg string, kv ...any) {
+	emit(LevelInfo, "", msg, contextFields(ctx, kv))
+}
+
+// log the warning with key/value attributes, adding any attributes from ctx
+// (see AddContextFields)
+func WarnContext(ctx context.Context, msg string, kv ...any) {
+	atomic.AddInt64(&Warns, 1)
+	emit(LevelWarn, "", msg, contextFields(ctx, kv))
+}
+
+// log the error with key/value attributes, adding any attributes from ctx
+// (see AddContextFields)
+func ErrorContext(ctx context.Context, msg string, kv ...any) {
+	atomic.AddInt64(&Errors, 1)
+	emit(LevelError, "", msg, contextFields(ctx, kv))
+}
+
+// trim the newline from Sprintln output
+func sprintln(args []any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
@@ -0,0 +1,328 @@
+package ulog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/tredeske/u/uerr"
+)
+
+// log outputs, in addition to 'stdout' and a file.  see Init.
+const (
+	OutputSyslog   = "syslog"   // RFC 5424 records to SyslogSocket
+	OutputJournald = "journald" // native journald records to JournaldSocket
+)
+
+var (
+	// the socket of the syslog daemon
+	SyslogSocket = "/dev/log"
+
+	// the native socket of the journald daemon
+	JournaldSocket = "/run/systemd/journal/socket"
+
+	// the syslog facility of records (3 is daemon)
+	SyslogFacility = 3
+
+	// the program name given to syslog and journald
+	SyslogIdent = filepath.Base(os.Args[0])
+)
+
+// a destination that takes log records rather than lines
+type recordSink_ interface {
+	emit(level slog.Level, component, msg string, kv []any) error
+	close() error
+}
+
+// the sink records go to, if not the golang standard log
+var sink_ atomic.Pointer[recordSink_]
+
+func getSink() recordSink_ {
+	if p := sink_.Load(); nil != p {
+		return *p
+	}
+	return nil
+}
+
+// replace the sink, closing any prior one
+func setSink(sink recordSink_) {
+	var p *recordSink_
+	if nil != sink {
+		p = &sink
+	}
+	if prior := sink_.Swap(p); nil != prior {
+		(*prior).close()
+	}
+}
+
+// create a sink for the output, or nil if not a sink output
+func newSink(output string) (rv recordSink_, err error) {
+	var sink *dgramSink_
+	switch output {
+	case OutputSyslog:
+		sink, err = newDgramSink(SyslogSocket, formatSyslog)
+	case OutputJournald:
+		sink, err = newDgramSink(JournaldSocket, formatJournal)
+	default:
+		return
+	}
+	if err != nil {
+		err = uerr.Chainf(err, "connecting to %s", output)
+		return
+	}
+	return sink, nil
+}
+
+// the syslog severity of the level
+func severity(level slog.Level) int {
+	switch {
+	case LevelFatal <= level:
+		return 2 // crit
+	case LevelError <= level:
+		return 3 // err
+	case LevelWarn <= level:
+		return 4 // warning
+	case LevelTodo <= level:
+		return 5 // notice
+	case LevelInfo <= level:
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+// get the level and message of a line written by the golang standard log,
+// using the ulog prefixes (see levelPrefix)
+func levelOfLine(line string) (level slog.Level, msg string) {
+	line = strings.TrimRight(line, "\n")
+	for _, level = range []slog.Level{LevelFatal, LevelError, LevelWarn,
+		LevelTodo, LevelDebug} {
+		prefix := levelPrefix(level)
+		if rest, found := strings.CutPrefix(line, prefix); found {
+			return level, rest
+		}
+	}
+	return LevelInfo, line
+}
+
+// a writer for the golang standard log, sending each line to the sink
+type sinkWriter_ struct{}
+
+func (sinkWriter_) Write(b []byte) (n int, err error) {
+	sink := getSink()
+	if nil == sink {
+		return os.Stdout.Write(b)
+	}
+	level, msg := levelOfLine(string(b))
+	err = sink.emit(level, "", msg, nil)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// a datagram socket sink, reconnecting if the daemon restarts
+type dgramSink_ struct {
+	lock   sync.Mutex
+	socket string
+	conn   net.Conn
+	format func(b *bytes.Buffer, level slog.Level, component, msg string, kv []any)
+	buff   bytes.Buffer
+}
+
+func newDgramSink(
+	socket string,
+	format func(b *bytes.Buffer, level slog.Level, component, msg string, kv []any),
+) (rv *dgramSink_, err error) {
+	rv = &dgramSink_{socket: socket, format: format}
+	rv.conn, err = net.Dial("unixgram", socket)
+	if err != nil {
+		rv = nil
+	}
+	return
+}
+
+func (this *dgramSink_) emit(level slog.Level, component, msg string, kv []any) (err error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.buff.Reset()
+	this.format(&this.buff, level, component, msg, kv)
+	if nil != this.conn {
+		_, err = this.conn.Write(this.buff.Bytes())
+		if nil == err {
+			return
+		}
+		this.conn.Close()
+		this.conn = nil
+	}
+	this.conn, err = net.Dial("unixgram", this.socket)
+	if nil == err {
+		_, err = this.conn.Write(this.buff.Bytes())
+	}
+	return
+}
+
+func (this *dgramSink_) close() (err error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if nil != this.conn {
+		err = this.conn.Close()
+		this.conn = nil
+	}
+	return
+}
+
+// produce an RFC 5424 record, with the PID as PROCID, and the component and
+// key/values as structured data
+func formatSyslog(b *bytes.Buffer, level slog.Level, component, msg string, kv []any) {
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(SyslogFacility*8 + severity(level)))
+	b.WriteString(">1 ")
+	b.WriteString(time.Now().Format("2006-01-02T15:04:05.000000Z07:00"))
+	b.WriteByte(' ')
+	b.WriteString(syslogHeader(hostname_, 255))
+	b.WriteByte(' ')
+	b.WriteString(syslogHeader(SyslogIdent, 48))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(os.Getpid())) // PROCID
+	b.WriteString(" - ")                     // MSGID
+	if 0 == len(component) && 0 == len(kv) {
+		b.WriteByte('-')
+	} else {
+		b.WriteString("[ulog@32473")
+		if 0 != len(component) {
+			writeSdParam(b, "component", component)
+		}
+		for i := 0; i < len(kv); i += 2 {
+			var v any
+			if i+1 < len(kv) {
+				v = kv[i+1]
+			}
+			writeSdParam(b, sdName(kv[i]), fieldString(v))
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte(' ')
+	b.WriteString(msg)
+}
+
+var hostname_, _ = os.Hostname()
+
+// an RFC 5424 header field - printable ascii, or "-" if empty
+func syslogHeader(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if 33 > r || 126 < r {
+			return -1
+		}
+		return r
+	}, s)
+	if 0 == len(s) {
+		return "-"
+	} else if max < len(s) {
+		s = s[:max]
+	}
+	return s
+}
+
+// an RFC 5424 SD-NAME
+func sdName(key any) string {
+	name := strings.Map(func(r rune) rune {
+		if 33 > r || 126 < r || '=' == r || ']' == r || '"' == r || ' ' == r {
+			return '_'
+		}
+		return r
+	}, fieldString(key))
+	if 32 < len(name) {
+		name = name[:32]
+	}
+	return name
+}
+
+func writeSdParam(b *bytes.Buffer, name, value string) {
+	b.WriteByte(' ')
+	b.WriteString(name)
+	b.WriteString(`="`)
+	for _, r := range value {
+		if '"' == r || '\\' == r || ']' == r {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
+
+// produce a journald native protocol record
+func formatJournal(b *bytes.Buffer, level slog.Level, component, msg string, kv []any) {
+	writeJournalField(b, "MESSAGE", msg)
+	writeJournalField(b, "PRIORITY", strconv.Itoa(severity(level)))
+	writeJournalField(b, "SYSLOG_FACILITY", strconv.Itoa(SyslogFacility))
+	writeJournalField(b, "SYSLOG_IDENTIFIER", SyslogIdent)
+	writeJournalField(b, "SYSLOG_PID", strconv.Itoa(os.Getpid()))
+	if 0 != len(component) {
+		writeJournalField(b, "COMPONENT", component)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		writeJournalField(b, journalName(kv[i]), fieldString(v))
+	}
+}
+
+// a journald field name: upper case letters, digits and underscore, not
+// starting with underscore (reserved for trusted fields)
+func journalName(key any) string {
+	name := strings.Map(func(r rune) rune {
+		r = unicode.ToUpper(r)
+		if ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, fieldString(key))
+	name = strings.TrimLeft(name, "_")
+	if 0 == len(name) || ('0' <= name[0] && name[0] <= '9') {
+		name = "F_" + name
+	}
+	if 64 < len(name) {
+		name = name[:64]
+	}
+	return name
+}
+
+// write the journald field, using the binary form if value is multi-line
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		b.WriteByte('\n')
+		var size [8]byte
+		binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+		b.Write(size[:])
+	} else {
+		b.WriteByte('=')
+	}
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// render a field key or value as a string
+func fieldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case nil:
+		return "<nil>"
+	}
+	return fmt.Sprint(v)
+}
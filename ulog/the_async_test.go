@@ -0,0 +1,135 @@
+package ulog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a writer that waits to be released before each write
+type gatedWriter_ struct {
+	lock sync.Mutex
+	gate chan bool
+	b    bytes.Buffer
+}
+
+func (this *gatedWriter_) Write(b []byte) (int, error) {
+	<-this.gate
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.b.Write(b)
+}
+
+func (this *gatedWriter_) String() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.b.String()
+}
+
+func TestAsync(t *testing.T) {
+	if _, err := NewAsyncWriter(nil, 10, "bogus"); nil == err {
+		t.Fatalf("Should not accept bogus overflow")
+	}
+
+	//
+	// drop debug lines when full, but not others
+	//
+	gw := &gatedWriter_{gate: make(chan bool)}
+	aw, err := NewAsyncWriter(gw, 20, OverflowDropDebug)
+	if err != nil {
+		t.Fatalf("Unable to create: %s", err)
+	}
+	aw.Write([]byte("line 1\n"))
+	for queued := 1; 0 != queued; { // wait for writer to take it
+		time.Sleep(time.Millisecond)
+		aw.lock.Lock()
+		queued = len(aw.queue)
+		aw.lock.Unlock()
+	}
+	aw.Write([]byte("line 2\n"))
+	aw.Write([]byte("line 3\n"))
+	aw.write(LevelDebug, []byte("line 4\n"))
+	if lines, debugLines := aw.Dropped(); 1 != lines+debugLines || 1 != debugLines {
+		t.Fatalf("Should have dropped 1 debug line, got %d, %d", lines, debugLines)
+	}
+	blocked := make(chan bool)
+	go func() {
+		aw.write(LevelWarn, []byte("DEBUG: line 5\n"))
+		close(blocked)
+	}()
+	close(gw.gate)
+	<-blocked
+	aw.Close()
+	if "line 1\nline 2\nline 3\nDEBUG: line 5\n" != gw.String() {
+		t.Fatalf("Wrong output:\n%s", gw.String())
+	}
+	aw.Write([]byte("line 6\n"))
+	if !strings.HasSuffix(gw.String(), "line 6\n") {
+		t.Fatalf("Write after close should be direct:\n%s", gw.String())
+	}
+
+	//
+	// drop all when full
+	//
+	gw = &gatedWriter_{gate: make(chan bool)}
+	aw, _ = NewAsyncWriter(gw, 10, OverflowDrop)
+	for i := 0; i < 10; i++ {
+		aw.Write([]byte("line\n"))
+	}
+	if lines, _ := aw.Dropped(); 0 == lines {
+		t.Fatalf("Should have dropped lines")
+	}
+	close(gw.gate)
+	aw.Close()
+	if lines, _ := aw.Dropped(); 0 != lines {
+		t.Fatalf("Dropped lines should be reported on close")
+	}
+
+	//
+	// ulog passes the level along, so only debug lines are dropped
+	//
+	gw = &gatedWriter_{gate: make(chan bool)}
+	outputLock_.Lock()
+	savedW, savedSize, savedOverflow := logW_, asyncSize_, asyncOverflow_
+	asyncSize_, asyncOverflow_ = 20, OverflowDropDebug
+	setOutput(gw)
+	outputLock_.Unlock()
+	defer func() {
+		outputLock_.Lock()
+		asyncSize_, asyncOverflow_ = savedSize, savedOverflow
+		setOutput(savedW)
+		outputLock_.Unlock()
+	}()
+	aw = GetAsync()
+	Printf("line 1")
+	for queued := 1; 0 != queued; { // wait for writer to take it
+		time.Sleep(time.Millisecond)
+		aw.lock.Lock()
+		queued = len(aw.queue)
+		aw.lock.Unlock()
+	}
+	Printf("line 2")
+	debugged := make(chan bool)
+	go func() {
+		DebugfIf(true, "line 3")
+		close(debugged)
+	}()
+	select { // if not dropped, then blocked until gate opens
+	case <-debugged:
+	case <-time.After(time.Second):
+	}
+	blocked = make(chan bool)
+	go func() {
+		Warnf("DEBUG: line 4")
+		close(blocked)
+	}()
+	close(gw.gate)
+	<-blocked
+	aw.Close()
+	if out := gw.String(); strings.Contains(out, "line 3") ||
+		!strings.Contains(out, "WARN: DEBUG: line 4") {
+		t.Fatalf("Only debug line should be dropped:\n%s", out)
+	}
+}
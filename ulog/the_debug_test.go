@@ -0,0 +1,75 @@
+package ulog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebugToggle(t *testing.T) {
+	d := NewDebug("toggleTest")
+	other := NewDebug("toggleOther")
+	t.Cleanup(func() {
+		debugAll_.Store(debugAllInit_)
+		debugDisabledFor_.Delete("toggleOther")
+	})
+	if d.Enabled || d.On() {
+		t.Fatalf("Should not be enabled")
+	}
+
+	//
+	// per component, with revert
+	//
+	copied := *d
+	EnableDebug("toggleTest", 50*time.Millisecond)
+	if !d.On() || !copied.On() {
+		t.Fatalf("Should be enabled")
+	} else if other.On() {
+		t.Fatalf("Other should not be enabled")
+	} else if d.Enabled {
+		t.Fatalf("Enabled should be the state at construction")
+	}
+	_, enabled, _ := DebugState()
+	if 1 != len(enabled) || "toggleTest" != enabled[0] {
+		t.Fatalf("State should have toggleTest enabled, got %v", enabled)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if d.On() {
+		t.Fatalf("Should have reverted")
+	} else if 0 != debugEnabledCount_.Load() {
+		t.Fatalf("Enabled count should be 0, is %d", debugEnabledCount_.Load())
+	}
+
+	//
+	// a second change restores the state prior to the first
+	//
+	EnableDebug("toggleTest", time.Hour)
+	DisableDebug("toggleTest", 50*time.Millisecond)
+	if d.On() {
+		t.Fatalf("Should be disabled")
+	}
+	time.Sleep(200 * time.Millisecond)
+	_, enabled, disabled := DebugState()
+	if 0 != len(enabled) || 0 != len(disabled) {
+		t.Fatalf("Should revert to initial state, got %v, %v", enabled, disabled)
+	}
+
+	//
+	// all, with a component disabled
+	//
+	DisableDebug("toggleOther", 0)
+	EnableDebug(DebugAll, 50*time.Millisecond)
+	if !d.On() || !IsDebugEnabled() {
+		t.Fatalf("All should be enabled")
+	} else if other.On() {
+		t.Fatalf("Other should be disabled")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if d.On() || IsDebugEnabled() {
+		t.Fatalf("All should have reverted")
+	}
+	EnableDebug("toggleOther", 0)
+	DisableDebug("toggleOther", 0)
+	if other.On() {
+		t.Fatalf("Other should be disabled")
+	}
+}
@@ -0,0 +1,119 @@
+package ulog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a buffer that summaries may write to while the test reads it
+type lockedBuffer_ struct {
+	lock sync.Mutex
+	b    bytes.Buffer
+}
+
+func (this *lockedBuffer_) Write(p []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.b.Write(p)
+}
+
+func (this *lockedBuffer_) String() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.b.String()
+}
+
+func (this *lockedBuffer_) Reset() {
+	this.lock.Lock()
+	this.b.Reset()
+	this.lock.Unlock()
+}
+
+func TestLimit(t *testing.T) {
+	var b lockedBuffer_
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(&b)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+		ClearLimits()
+	}()
+
+	if nil == SetLimit("", Limit{Window: time.Second, Key: "bogus"}) {
+		t.Fatalf("Should not accept bogus key")
+	}
+	err := SetLimit("", Limit{Window: 100 * time.Millisecond, Burst: 2})
+	if err != nil {
+		t.Fatalf("Unable to set limit: %s", err)
+	}
+
+	//
+	// by format: only the first 2 are output, but all are counted
+	//
+	warns := atomic.LoadInt64(&Warns)
+	for i := 0; i < 10; i++ {
+		Warnf("connect failed: %d", i)
+	}
+	Printf("other")
+	if warns+10 != atomic.LoadInt64(&Warns) {
+		t.Fatalf("All warnings should be counted")
+	} else if "WARN: connect failed: 0\nWARN: connect failed: 1\nother\n" != b.String() {
+		t.Fatalf("Wrong output:\n%s", b.String())
+	}
+	time.Sleep(300 * time.Millisecond)
+	if !strings.HasSuffix(b.String(),
+		"WARN: last message repeated 8 times in 100ms: connect failed: 2\n") {
+		t.Fatalf("No summary:\n%s", b.String())
+	}
+
+	//
+	// per component, by message
+	//
+	SetDebugEnabledFor("limitTest")
+	defer func() {
+		debugEnabledFor_.Delete("limitTest")
+		debugEnabledCount_.Add(-1)
+	}()
+	b.Reset()
+	err = SetLimit("limitTest",
+		Limit{Window: 100 * time.Millisecond, Key: LimitByMessage})
+	if err != nil {
+		t.Fatalf("Unable to set limit: %s", err)
+	}
+	d := NewDebug("limitTest")
+	for i := 0; i < 4; i++ {
+		d.F("value %d", i%2)
+	}
+	if "DEBUG: limitTest: value 0\nDEBUG: limitTest: value 1\n" != b.String() {
+		t.Fatalf("Wrong output:\n%s", b.String())
+	}
+
+	//
+	// removing the limit summarizes what was suppressed
+	//
+	SetLimit("limitTest", Limit{})
+	if 4 != strings.Count(b.String(), "\n") ||
+		!strings.Contains(b.String(), "last message repeated 1 times") {
+		t.Fatalf("Wrong output:\n%s", b.String())
+	}
+
+	//
+	// by site
+	//
+	b.Reset()
+	SetLimit("", Limit{Window: time.Minute, Key: LimitBySite})
+	for i := 0; i < 3; i++ {
+		Printf("a %d", i)
+		Printf("b %d", i)
+	}
+	Printf("c")
+	if "a 0\nb 0\nc\n" != b.String() {
+		t.Fatalf("Wrong output:\n%s", b.String())
+	}
+}
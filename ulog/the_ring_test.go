@@ -0,0 +1,125 @@
+package ulog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	saved := log.Writer()
+	log.SetOutput(io.Discard)
+	defer func() {
+		log.SetOutput(saved)
+		SetRing(0, 0)
+	}()
+
+	//
+	// bounded by count and by bytes
+	//
+	r := NewRing(3, 0)
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		r.Add(Record{Time: time.Now(), Message: msg})
+	}
+	if records := r.Query(Query{}); 3 != len(records) ||
+		"b" != records[0].Message || "d" != records[2].Message {
+		t.Fatalf("Wrong records: %v", records)
+	}
+	r = NewRing(100, 200)
+	for i := 0; i < 10; i++ {
+		r.Add(Record{Time: time.Now(), Message: "0123456789"})
+	}
+	if records := r.Query(Query{}); 2 != len(records) {
+		t.Fatalf("Should be 2 records by bytes, got %d", len(records))
+	}
+
+	//
+	// records from ulog
+	//
+	SetDebugEnabledFor("ringTest")
+	defer func() {
+		debugEnabledFor_.Delete("ringTest")
+		debugEnabledCount_.Add(-1)
+	}()
+	SetRing(100, 0)
+	Printf("started")
+	Warnf("slow %d", 1)
+	Error("connect failed", "host", "example.com")
+	DebugfFor("ringTest", "timeout on %d", 7)
+
+	ring := GetRing()
+	records := ring.Query(Query{MinLevel: LevelWarn})
+	if 2 != len(records) || "slow 1" != records[0].Message ||
+		LevelError != records[1].Level ||
+		"host" != records[1].Fields[0] || "example.com" != records[1].Fields[1] {
+		t.Fatalf("Wrong records: %v", records)
+	}
+	records = ring.Query(Query{MinLevel: LevelDebug, Component: "ring*",
+		Contains: "timeout"})
+	if 1 != len(records) || "timeout on 7" != records[0].Message {
+		t.Fatalf("Wrong records: %v", records)
+	}
+	records = ring.Query(Query{Limit: 1})
+	if 1 != len(records) || "connect failed" != records[0].Message {
+		t.Fatalf("Wrong records: %v", records)
+	}
+	if records = ring.Query(Query{Until: records[0].Time}); 2 != len(records) {
+		t.Fatalf("Should be 2 records before, got %v", records)
+	}
+	NewDebug("ringTest").F("retry %d", 3)
+	records = ring.Query(Query{MinLevel: LevelDebug, Contains: "retry"})
+	if 1 != len(records) || "retry 3" != records[0].Message ||
+		"ringTest" != records[0].Component {
+		t.Fatalf("Debug.F should be kept, got %v", records)
+	}
+
+	//
+	// over HTTP
+	//
+	srv := httptest.NewServer(RingHandler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?level=error&since=1h")
+	if err != nil {
+		t.Fatalf("Unable to get: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasSuffix(string(body),
+		"ERROR: connect failed [host=example.com]\n") ||
+		1 != strings.Count(string(body), "\n") {
+		t.Fatalf("Wrong body:\n%s", body)
+	}
+
+	resp, _ = http.Get(srv.URL + "?level=bogus")
+	resp.Body.Close()
+	if http.StatusBadRequest != resp.StatusCode {
+		t.Fatalf("Should be bad request, got %d", resp.StatusCode)
+	}
+
+	//
+	// follow
+	//
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+		srv.URL+"?follow=true&format=json&contains=live", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to follow: %s", err)
+	}
+	defer resp.Body.Close()
+	Printf("live %d", 1)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Unable to read followed record: %s", err)
+	} else if !strings.Contains(line, `"msg":"live 1"`) {
+		t.Fatalf("Wrong followed record: %s", line)
+	}
+}
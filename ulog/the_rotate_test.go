@@ -0,0 +1,100 @@
+package ulog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatePolicy(t *testing.T) {
+	policy := RotatePolicy{Every: 6 * time.Hour}
+	at := time.Date(2024, 3, 5, 13, 30, 0, 0, time.Local)
+	if start := policy.periodStart(at); start.Hour() != 12 || 0 != start.Minute() {
+		t.Fatalf("period start wrong: %s", start)
+	} else if next := policy.nextPeriod(at); 18 != next.Hour() {
+		t.Fatalf("next period wrong: %s", next)
+	} else if next = (RotatePolicy{Daily: true}).nextPeriod(at); 6 != next.Day() ||
+		0 != next.Hour() {
+		t.Fatalf("next day wrong: %s", next)
+	}
+
+	dir := t.TempDir()
+	logF := filepath.Join(dir, "test.log")
+	listRotated := func() (rv []string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if "test.log" != entry.Name() {
+				rv = append(rv, entry.Name())
+			}
+		}
+		return
+	}
+
+	//
+	// time based rotation, with compression
+	//
+	wm, err := NewWriteManager(logF, 1_000_000, 10)
+	if err != nil {
+		t.Fatalf("Unable to create: %s", err)
+	}
+	wm.SetPolicy(RotatePolicy{Daily: true, Compress: true})
+	if wm.rotateAt.IsZero() {
+		t.Fatalf("rotation time not set")
+	}
+	for i := 0; i < 3; i++ {
+		wm.Write([]byte("period " + string(rune('a'+i)) + "\n"))
+		wm.lock.Lock()
+		wm.rotateAt = time.Now().Add(-time.Second) // period ended
+		wm.lock.Unlock()
+	}
+	wm.Close()
+
+	rotated := listRotated()
+	if 2 != len(rotated) {
+		t.Fatalf("Should be 2 rotated files, got %v", rotated)
+	}
+	for _, name := range rotated {
+		if !wm.rotateMatch.MatchString(name) || !strings.HasSuffix(name, ".gz") {
+			t.Fatalf("rotated file %s not compressed or not matched", name)
+		}
+	}
+	f, err := os.Open(filepath.Join(dir, rotated[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Unable to read %s: %s", rotated[0], err)
+	}
+	content, err := io.ReadAll(gz)
+	f.Close()
+	if err != nil || !strings.HasPrefix(string(content), "period ") {
+		t.Fatalf("compressed content wrong: '%s', %v", content, err)
+	}
+	if current, _ := os.ReadFile(logF); "period c\n" != string(current) {
+		t.Fatalf("current log wrong: '%s'", current)
+	}
+
+	//
+	// retention by age and total size
+	//
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	os.Chtimes(filepath.Join(dir, rotated[0]), old, old)
+	wm.SetPolicy(RotatePolicy{MaxAge: 7 * 24 * time.Hour})
+	wm.prune()
+	if rotated = listRotated(); 1 != len(rotated) {
+		t.Fatalf("Should have removed old file, got %v", rotated)
+	}
+	wm.SetPolicy(RotatePolicy{MaxTotal: 1})
+	wm.prune()
+	if rotated = listRotated(); 0 != len(rotated) {
+		t.Fatalf("Should have removed file over total, got %v", rotated)
+	}
+}
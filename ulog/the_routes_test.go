@@ -0,0 +1,150 @@
+package ulog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	var b bytes.Buffer
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(&b)
+	log.SetFlags(0)
+	savedTesting, savedStdout := testing_, stdout_
+	testing_, stdout_ = false, false
+	defer func() {
+		SetRoutes(nil, nil)
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+		testing_, stdout_ = savedTesting, savedStdout
+	}()
+
+	dir := t.TempDir()
+	protoF := filepath.Join(dir, "proto.log")
+	errorsF := filepath.Join(dir, "errors.log")
+
+	err := SetRoutes(nil, []Route{{Sink: "bogus"}})
+	if nil == err {
+		t.Fatalf("Should not route to unknown sink")
+	}
+	err = SetRoutes(
+		[]Sink{
+			{Name: "proto", Output: protoF},
+			{Name: "errors", Output: errorsF},
+		},
+		[]Route{
+			{Components: []string{"protoQuiet"}, Sink: SinkMain},
+			{Components: []string{"proto*"}, Levels: []slog.Level{LevelDebug},
+				Sink: "proto"},
+			{Levels: []slog.Level{LevelError}, Sink: "errors", Tee: true},
+		})
+	if err != nil {
+		t.Fatalf("Unable to set routes: %s", err)
+	}
+
+	SetDebugEnabledFor("protoA")
+	SetDebugEnabledFor("protoQuiet")
+	defer func() {
+		for _, component := range []string{"protoA", "protoQuiet"} {
+			debugEnabledFor_.Delete(component)
+			debugEnabledCount_.Add(-1)
+		}
+	}()
+	b.Reset()
+
+	DebugfFor("protoA", "packet %d", 1)
+	NewDebug("protoA").F("packet %d", 2)
+	DebugfFor("protoQuiet", "quiet")
+	Printf("main")
+	Errorf("failed")
+
+	if "DEBUG: protoQuiet: quiet\nmain\nERROR: failed\n" != b.String() {
+		t.Fatalf("Wrong main output:\n%s", b.String())
+	}
+	SetRoutes(nil, nil) // close files
+
+	content, err := os.ReadFile(protoF)
+	if err != nil {
+		t.Fatalf("Unable to read proto log: %s", err)
+	} else if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); 2 != len(lines) ||
+		!strings.HasSuffix(lines[0], "DEBUG: protoA: packet 1") ||
+		!strings.HasSuffix(lines[1], "DEBUG: protoA: packet 2") {
+		t.Fatalf("Wrong proto output:\n%s", content)
+	}
+	content, err = os.ReadFile(errorsF)
+	if err != nil {
+		t.Fatalf("Unable to read errors log: %s", err)
+	} else if !strings.HasSuffix(string(content), "ERROR: failed\n") {
+		t.Fatalf("Wrong errors output:\n%s", content)
+	}
+}
+
+// replacing routes while records are output to them should not leave files
+// open
+func TestRoutesSwap(t *testing.T) {
+	fds := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skipf("Unable to count files: %s", err)
+		}
+		return len(entries)
+	}
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(io.Discard)
+	savedTesting, savedStdout := testing_, stdout_
+	testing_, stdout_ = false, false
+	defer func() {
+		SetRoutes(nil, nil)
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+		testing_, stdout_ = savedTesting, savedStdout
+	}()
+
+	sinks := []Sink{{Name: "swap", Output: filepath.Join(t.TempDir(), "swap.log")}}
+	routes := []Route{{Levels: []slog.Level{LevelWarn}, Sink: "swap"}}
+	before := fds()
+
+	err := SetRoutes(sinks, routes)
+	if err != nil {
+		t.Fatalf("Unable to set routes: %s", err)
+	}
+	var wg, started sync.WaitGroup
+	stopC := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			Warnf("swapping")
+			started.Done()
+			for {
+				select {
+				case <-stopC:
+					return
+				default:
+					Warnf("swapping")
+				}
+			}
+		}()
+	}
+	started.Wait()
+	for i := 0; i < 100; i++ {
+		if err = SetRoutes(sinks, routes); err != nil {
+			t.Fatalf("Unable to set routes: %s", err)
+		}
+	}
+	close(stopC)
+	wg.Wait()
+	SetRoutes(nil, nil)
+
+	if after := fds(); before != after {
+		t.Fatalf("Files left open: %d before, %d after", before, after)
+	}
+}
@@ -0,0 +1,111 @@
+package ulog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	var b bytes.Buffer
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(&b)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+		SetFormat(FormatLog)
+	}()
+
+	//
+	// classic output is unchanged, with attributes rendered as fields
+	//
+	Warnf("warning %d", 1)
+	Info("connected", "host", "a b", "port", 80)
+	if "WARN: warning 1\nconnected [host=\"a b\" port=80]\n" != b.String() {
+		t.Fatalf("classic output wrong:\n%s", b.String())
+	}
+
+	if nil == SetFormat("bogus") {
+		t.Fatalf("Should not accept bogus format")
+	} else if err := SetFormat(FormatJson); err != nil {
+		t.Fatalf("Unable to set format: %s", err)
+	} else if FormatJson != GetFormat() {
+		t.Fatalf("format should be json, got %s", GetFormat())
+	}
+	SetDebugEnabledFor("slogTest")
+
+	b.Reset()
+	warns, errs := atomic.LoadInt64(&Warns), atomic.LoadInt64(&Errors)
+	Warnf("warning %d", 2)
+	DebugfFor("slogTest", "debug %s", "it")
+	TODO("fix")
+	logger := slog.New(Handler()).With("component", "slogTest")
+	logger.Debug("from slog", "n", 5)
+	logger.Error("slog error", slog.Group("req", "id", 7))
+	slog.New(Handler()).Debug("disabled")
+
+	if warns+1 != atomic.LoadInt64(&Warns) || errs+1 != atomic.LoadInt64(&Errors) {
+		t.Fatalf("Counters not updated")
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if 5 != len(lines) {
+		t.Fatalf("Should be 5 lines, got %d:\n%s", len(lines), b.String())
+	}
+	for i, expect := range []map[string]any{
+		{"level": "WARN", "msg": "warning 2"},
+		{"level": "DEBUG", "msg": "debug it", "component": "slogTest"},
+		{"level": "TODO", "msg": "fix"},
+		{"level": "DEBUG", "msg": "from slog", "component": "slogTest", "n": 5.0},
+		{"level": "ERROR", "msg": "slog error", "req.id": 7.0},
+	} {
+		var record map[string]any
+		err := json.Unmarshal([]byte(lines[i]), &record)
+		if err != nil {
+			t.Fatalf("Unable to parse line %d: %s\n%s", i, err, lines[i])
+		}
+		for k, v := range expect {
+			if record[k] != v {
+				t.Fatalf("Line %d: %s should be %v:\n%s", i, k, v, lines[i])
+			}
+		}
+	}
+}
+
+type ctxKey_ struct{}
+
+func TestContextFields(t *testing.T) {
+	var b bytes.Buffer
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(&b)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+	}()
+
+	AddContextFields(func(ctx context.Context) []any {
+		if id, ok := ctx.Value(ctxKey_{}).(string); ok {
+			return []any{"req", id}
+		}
+		return nil
+	})
+	ctx := context.WithValue(context.Background(), ctxKey_{}, "r1")
+
+	kv := make([]any, 2, 4)
+	kv[0], kv[1] = "n", 1
+	InfoContext(ctx, "with", kv...)
+	WarnContext(context.Background(), "without")
+	slog.New(Handler()).ErrorContext(ctx, "from slog")
+	if "with [n=1 req=r1]\nWARN: without\nERROR: from slog [req=r1]\n" !=
+		b.String() {
+		t.Fatalf("Wrong output:\n%s", b.String())
+	} else if nil != kv[:3][2] {
+		t.Fatalf("Should not change caller's slice")
+	}
+}
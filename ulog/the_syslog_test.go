@@ -0,0 +1,124 @@
+package ulog
+
+import (
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslog(t *testing.T) {
+	dir := t.TempDir()
+	listen := func(name string) (conn *net.UnixConn, socket string) {
+		socket = filepath.Join(dir, name)
+		conn, err := net.ListenUnixgram("unixgram",
+			&net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			t.Fatalf("Unable to listen on %s: %s", socket, err)
+		}
+		return
+	}
+	read := func(conn *net.UnixConn) string {
+		buff := make([]byte, 8192)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buff)
+		if err != nil {
+			t.Fatalf("Unable to read record: %s", err)
+		}
+		return string(buff[:n])
+	}
+	savedFlags := log.Flags()
+	defer func() {
+		Init("stdout", 0, 0)
+		log.SetFlags(savedFlags)
+	}()
+	pid := strconv.Itoa(os.Getpid())
+	SetDebugEnabledFor("syslogTest")
+
+	//
+	// syslog
+	//
+	conn, socket := listen("syslog.sock")
+	defer conn.Close()
+	SyslogSocket = socket
+	SyslogIdent = "tester"
+	err := Init(OutputSyslog, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to init syslog: %s", err)
+	} else if OutputSyslog != GetOutput() {
+		t.Fatalf("output should be syslog, got %s", GetOutput())
+	}
+
+	Warnf("warning %d", 1)
+	record := read(conn)
+	expect := regexp.MustCompile(`^<28>1 \S+ \S+ tester ` + pid + ` - - warning 1$`)
+	if !expect.MatchString(record) {
+		t.Fatalf("syslog warning wrong: %s", record)
+	}
+
+	DebugfFor("syslogTest", "debug")
+	if record = read(conn); !strings.HasPrefix(record, "<31>1 ") ||
+		!strings.HasSuffix(record, ` - [ulog@32473 component="syslogTest"] debug`) {
+		t.Fatalf("syslog debug wrong: %s", record)
+	}
+
+	Error("failed", "file", `a "b"`)
+	if record = read(conn); !strings.HasPrefix(record, "<27>1 ") ||
+		!strings.HasSuffix(record, ` - [ulog@32473 file="a \"b\""] failed`) {
+		t.Fatalf("syslog error wrong: %s", record)
+	}
+
+	log.Printf("FATAL: from std log")
+	if record = read(conn); !strings.HasPrefix(record, "<26>1 ") ||
+		!strings.HasSuffix(record, " - - from std log") {
+		t.Fatalf("syslog std log wrong: %s", record)
+	}
+
+	//
+	// journald
+	//
+	jconn, jsocket := listen("journal.sock")
+	defer jconn.Close()
+	JournaldSocket = jsocket
+	err = Init(OutputJournald, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to init journald: %s", err)
+	}
+
+	TODO("fix\nthis")
+	record = read(jconn)
+	if !strings.HasPrefix(record, "MESSAGE\n\x08\x00\x00\x00\x00\x00\x00\x00fix\nthis\n") ||
+		!strings.Contains(record, "\nPRIORITY=5\n") ||
+		!strings.Contains(record, "\nSYSLOG_IDENTIFIER=tester\n") ||
+		!strings.Contains(record, "\nSYSLOG_PID="+pid+"\n") {
+		t.Fatalf("journald TODO wrong: %q", record)
+	}
+
+	NewDebug("syslogTest").F("from %s", "debug")
+	record = read(jconn)
+	if !strings.HasPrefix(record, "MESSAGE=from debug\nPRIORITY=7\n") ||
+		!strings.HasSuffix(record, "\nCOMPONENT=syslogTest\n") {
+		t.Fatalf("journald debug wrong: %q", record)
+	}
+
+	Info("connected", "remote-host", "h", "_trusted", 1)
+	record = read(jconn)
+	if !strings.HasSuffix(record, "\nREMOTE_HOST=h\nTRUSTED=1\n") {
+		t.Fatalf("journald fields wrong: %q", record)
+	}
+
+	//
+	// time is logged again when leaving the daemon
+	//
+	err = Init("stdout", 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to init stdout: %s", err)
+	} else if savedFlags != log.Flags() {
+		t.Fatalf("log flags not restored: %x, expected %x", log.Flags(), savedFlags)
+	}
+}
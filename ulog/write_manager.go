@@ -1,10 +1,13 @@
 package ulog
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,6 +16,10 @@ import (
 )
 
 // an io.Writer to manage log output file rotation
+//
+// By default, the file is rotated when it exceeds max bytes, and keep rotated
+// files are retained.  SetPolicy adds time based rotation, compression, and
+// retention by age and total size.
 type WriteManager struct {
 	lock        sync.Mutex
 	max         int64
@@ -25,6 +32,53 @@ type WriteManager struct {
 	baseNoExt   string
 	nameNoExt   string
 	rotateMatch *regexp.Regexp
+	policy      RotatePolicy
+	rotateAt    time.Time      // when to rotate next, if time based
+	pruneLock   sync.Mutex     // serialize pruning of rotated files
+	background  sync.WaitGroup // compressions in progress
+}
+
+// rotation and retention policies in addition to size and count
+type RotatePolicy struct {
+	Daily    bool          // rotate at local midnight
+	Every    time.Duration // rotate at this interval, aligned to local midnight
+	Compress bool          // gzip rotated files in the background
+	MaxAge   time.Duration // remove rotated files older than this
+	MaxTotal int64         // remove oldest rotated files beyond this many bytes
+}
+
+// the rotation interval, or 0 if not time based
+func (this RotatePolicy) interval() time.Duration {
+	if this.Daily && (0 >= this.Every || 24*time.Hour < this.Every) {
+		return 24 * time.Hour
+	} else if 0 < this.Every {
+		return this.Every
+	}
+	return 0
+}
+
+// the start of the rotation period containing t
+func (this RotatePolicy) periodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	interval := this.interval()
+	if 24*time.Hour <= interval {
+		return midnight
+	}
+	return midnight.Add(t.Sub(midnight) / interval * interval)
+}
+
+// the start of the rotation period after the one containing t
+func (this RotatePolicy) nextPeriod(t time.Time) time.Time {
+	start := this.periodStart(t)
+	if 24*time.Hour <= this.interval() {
+		return start.AddDate(0, 0, 1) // DST safe
+	}
+	next := start.Add(this.interval())
+	if tomorrow := start.AddDate(0, 0, 1); tomorrow.Before(next) {
+		next = this.periodStart(tomorrow)
+	}
+	return next
 }
 
 // create a new writer to manage a log file, with max bytes per log file
@@ -61,16 +115,29 @@ func NewWriteManager(
 		baseNoExt: base,
 		nameNoExt: absFile[:len(absFile)-len(ext)],
 		// must match format in rotate()
-		rotateMatch: regexp.MustCompile(`^` + base + `\.\d{6}\.\d{6}\` + ext + `$`),
+		rotateMatch: regexp.MustCompile(`^` + regexp.QuoteMeta(base) +
+			`\.\d{6}\.\d{6}(\.\d+)?` + regexp.QuoteMeta(ext) + `(\.gz)?$`),
 	}
 	err = rv.next()
 	return
 }
 
+// set the rotation and retention policy, in addition to size and count
+func (this *WriteManager) SetPolicy(policy RotatePolicy) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.policy = policy
+	this.rotateAt = time.Time{}
+	if 0 != policy.interval() {
+		this.rotateAt = policy.nextPeriod(time.Now())
+	}
+}
+
 // implement io.Closer
 func (this *WriteManager) Close() error {
 	this.lock.Lock()
 	defer this.lock.Unlock()
+	defer this.background.Wait()
 	if nil != this.w {
 		w := this.w
 		this.w = nil
@@ -89,6 +156,17 @@ func (this *WriteManager) Write(bb []byte) (n int, err error) {
 	}
 	this.lock.Lock()
 	defer this.lock.Unlock()
+	if !this.rotateAt.IsZero() {
+		if now := time.Now(); !now.Before(this.rotateAt) {
+			this.rotateAt = this.policy.nextPeriod(now)
+			if 0 != this.size && nil != this.w {
+				this.w.Close()
+				this.w = nil
+				this.size = 0
+				this.rotate(now)
+			}
+		}
+	}
 	if nil == this.w {
 		if err = this.next(); err != nil {
 			return 0, err
@@ -112,7 +190,7 @@ func (this *WriteManager) next() (err error) {
 	}
 	if fi, ignore := os.Stat(this.name); ignore == nil {
 		this.size = fi.Size()
-		if this.size >= this.max {
+		if this.size >= this.max || this.isStale(fi) {
 			this.size = 0
 			this.rotate(fi.ModTime())
 		}
@@ -129,12 +207,93 @@ func (this *WriteManager) next() (err error) {
 	return nil
 }
 
+// is the existing file from a previous rotation period?
+func (this *WriteManager) isStale(fi os.FileInfo) bool {
+	if 0 == this.policy.interval() || 0 == fi.Size() {
+		return false
+	}
+	return fi.ModTime().Before(this.policy.periodStart(time.Now()))
+}
+
 func (this *WriteManager) rotate(when time.Time) {
 	// must match regexp in this.rotateMatch
-	dst := this.nameNoExt + when.Format(".060102.150405") + this.ext
-	os.Remove(dst)
+	stamp := this.nameNoExt + when.Format(".060102.150405")
+	dst := stamp + this.ext
+	if this.policy.Compress {
+		//
+		// a rotated file may still be being compressed, so do not reuse names
+		//
+		for i := 1; exists(dst) || exists(dst+".gz"); i++ {
+			dst = stamp + "." + strconv.Itoa(i) + this.ext
+		}
+	} else {
+		os.Remove(dst)
+	}
 	os.Rename(this.name, dst)
 
+	if this.policy.Compress {
+		this.background.Add(1)
+		go func() {
+			defer this.background.Done()
+			err := compress(dst)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Unable to compress %s: %s\n", dst, err)
+			}
+			this.prune()
+		}()
+	} else {
+		this.prune()
+	}
+}
+
+func exists(file string) bool {
+	_, err := os.Lstat(file)
+	return nil == err
+}
+
+// gzip the file, replacing it with file.gz
+func compress(file string) (err error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	tmp := file + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if nil == err {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); nil == err {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+	if fi, serr := in.Stat(); nil == serr {
+		os.Chtimes(tmp, fi.ModTime(), fi.ModTime()) // keep age for retention
+	}
+	err = os.Rename(tmp, file+".gz")
+	if nil == err {
+		err = os.Remove(file)
+	}
+	return
+}
+
+// remove rotated files beyond the count, age, and size limits
+func (this *WriteManager) prune() {
+	this.pruneLock.Lock()
+	defer this.pruneLock.Unlock()
+
 	//
 	// only keep so many log files around
 	//
@@ -158,13 +317,29 @@ func (this *WriteManager) rotate(when time.Time) {
 			matching = append(matching, files[i])
 		}
 	}
-	if this.keep > len(matching) {
-		return
-	}
 
 	uio.SortByModTime(matching) // oldest to newest
 
-	last := len(matching) - this.keep
+	last := 0
+	if this.keep <= len(matching) {
+		last = len(matching) - this.keep
+	}
+	if 0 < this.policy.MaxAge {
+		oldest := time.Now().Add(-this.policy.MaxAge)
+		for last < len(matching) && matching[last].ModTime().Before(oldest) {
+			last++
+		}
+	}
+	if 0 < this.policy.MaxTotal {
+		var total int64
+		for i := len(matching) - 1; i >= last; i-- {
+			total += matching[i].Size()
+			if total > this.policy.MaxTotal {
+				last = i + 1
+				break
+			}
+		}
+	}
 
 	for i := range matching[:last] {
 		rm := filepath.Join(this.dir, matching[i].Name())
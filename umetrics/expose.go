@@ -0,0 +1,156 @@
+package umetrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tredeske/u/ulog"
+)
+
+// the media type of the Prometheus text format
+const TextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func init() {
+	Default.CounterFunc("ulog_warns_total", "WARN messages logged",
+		func() float64 { return float64(atomic.LoadInt64(&ulog.Warns)) })
+	Default.CounterFunc("ulog_errors_total", "ERROR messages logged",
+		func() float64 { return float64(atomic.LoadInt64(&ulog.Errors)) })
+	Default.CounterFunc("ulog_todos_total", "TODO messages logged",
+		func() float64 { return float64(atomic.LoadInt64(&ulog.Todos)) })
+	Default.GaugeFunc("go_goroutines", "Number of goroutines",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	start := float64(time.Now().UnixNano()) / 1e9
+	Default.GaugeFunc("process_start_time_seconds",
+		"Start time of the process since unix epoch in seconds",
+		func() float64 { return start })
+}
+
+// implement http.Handler, responding with the metrics in the Prometheus text
+// format
+func (this *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", TextContentType)
+	this.Expose(w)
+}
+
+// write the metrics in the Prometheus text format
+func (this *Registry) Expose(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range this.sorted() {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (this *family_) write(w *bufio.Writer) {
+	fn := this.fn.Load()
+	var children []*child_
+	if nil == fn {
+		children = this.sorted()
+		if 0 == len(children) { // such as labelled metrics not yet used
+			return
+		}
+	}
+	w.WriteString("# HELP ")
+	w.WriteString(this.name)
+	w.WriteByte(' ')
+	w.WriteString(escapeHelp(this.help))
+	w.WriteString("\n# TYPE ")
+	w.WriteString(this.name)
+	w.WriteByte(' ')
+	w.WriteString(this.kind.String())
+	w.WriteByte('\n')
+	if nil != fn {
+		writeSample(w, this.name, nil, nil, "", "", (*fn)())
+		return
+	}
+	for _, c := range children {
+		switch m := c.metric.(type) {
+		case *Counter:
+			writeSample(w, this.name, this.labels, c.values, "", "",
+				float64(m.Get()))
+		case *Gauge:
+			writeSample(w, this.name, this.labels, c.values, "", "", m.Get())
+		case func() float64:
+			writeSample(w, this.name, this.labels, c.values, "", "", m())
+		case *Histogram:
+			var cumulative uint64
+			for i, upper := range m.upper {
+				cumulative += m.counts[i].Load()
+				writeSample(w, this.name+"_bucket", this.labels, c.values,
+					"le", formatFloat(upper), float64(cumulative))
+			}
+			cumulative += m.counts[len(m.upper)].Load()
+			writeSample(w, this.name+"_bucket", this.labels, c.values,
+				"le", "+Inf", float64(cumulative))
+			_, sum := m.Get()
+			writeSample(w, this.name+"_sum", this.labels, c.values, "", "", sum)
+			writeSample(w, this.name+"_count", this.labels, c.values, "", "",
+				float64(cumulative))
+		}
+	}
+}
+
+// write a sample line, with an extra label (such as 'le') if set
+func writeSample(
+	w *bufio.Writer,
+	name string,
+	labels, values []string,
+	extraLabel, extraValue string,
+	v float64,
+) {
+	w.WriteString(name)
+	if 0 != len(labels) || 0 != len(extraLabel) {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if 0 != i {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if 0 != len(extraLabel) {
+			if 0 != len(labels) {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, label, value string) {
+	w.WriteString(label)
+	w.WriteString(`="`)
+	w.WriteString(labelEscaper_.Replace(value))
+	w.WriteByte('"')
+}
+
+var (
+	labelEscaper_ = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper_  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper_.Replace(help)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
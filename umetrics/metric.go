@@ -0,0 +1,126 @@
+package umetrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// a count that only goes up
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (this *Counter) Inc() { this.v.Add(1) }
+
+func (this *Counter) Add(n uint64) { this.v.Add(n) }
+
+func (this *Counter) Get() uint64 { return this.v.Load() }
+
+// a value that goes up and down
+type Gauge struct {
+	bits atomic.Uint64 // float64
+}
+
+func (this *Gauge) Set(v float64) { this.bits.Store(math.Float64bits(v)) }
+
+func (this *Gauge) Get() float64 { return math.Float64frombits(this.bits.Load()) }
+
+func (this *Gauge) Inc() { this.Add(1) }
+
+func (this *Gauge) Dec() { this.Add(-1) }
+
+func (this *Gauge) Add(delta float64) {
+	addFloat(&this.bits, delta)
+}
+
+// atomically add delta to the float64 in bits
+func addFloat(bits *atomic.Uint64, delta float64) {
+	for {
+		prior := bits.Load()
+		next := math.Float64bits(math.Float64frombits(prior) + delta)
+		if bits.CompareAndSwap(prior, next) {
+			return
+		}
+	}
+}
+
+// default histogram buckets, suited to request latency in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// count buckets, starting at start, each width wide
+func LinearBuckets(start, width float64, count int) (rv []float64) {
+	rv = make([]float64, count)
+	for i := range rv {
+		rv[i] = start + float64(i)*width
+	}
+	return
+}
+
+// count buckets, starting at start, each factor times the prior
+func ExponentialBuckets(start, factor float64, count int) (rv []float64) {
+	rv = make([]float64, count)
+	for i := range rv {
+		rv[i] = start
+		start *= factor
+	}
+	return
+}
+
+// sort and check bucket upper bounds, dropping any +Inf, as that is implied
+func checkBuckets(name string, buckets []float64) (rv []float64) {
+	if 0 == len(buckets) {
+		buckets = DefBuckets
+	}
+	rv = append([]float64(nil), buckets...)
+	sort.Float64s(rv)
+	if math.IsInf(rv[len(rv)-1], 1) {
+		rv = rv[:len(rv)-1]
+	}
+	for i := 1; i < len(rv); i++ {
+		if rv[i] == rv[i-1] {
+			panic(fmt.Sprintf("histogram %s has duplicate bucket %g", name, rv[i]))
+		}
+	}
+	return
+}
+
+// counts of observed values in buckets
+type Histogram struct {
+	upper  []float64       // bucket upper bounds
+	counts []atomic.Uint64 // per bucket (not cumulative), then +Inf
+	sum    atomic.Uint64   // float64
+	count  atomic.Uint64
+}
+
+func newHistogram(upper []float64) *Histogram {
+	return &Histogram{
+		upper:  upper,
+		counts: make([]atomic.Uint64, len(upper)+1),
+	}
+}
+
+// record the value
+func (this *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(this.upper, v) // first upper >= v
+	this.counts[i].Add(1)
+	addFloat(&this.sum, v)
+	this.count.Add(1)
+}
+
+// record the duration, in seconds
+func (this *Histogram) ObserveDuration(d time.Duration) {
+	this.Observe(d.Seconds())
+}
+
+// record the time since start, in seconds
+func (this *Histogram) Since(start time.Time) {
+	this.Observe(time.Since(start).Seconds())
+}
+
+// get the number of values observed, and their sum
+func (this *Histogram) Get() (count uint64, sum float64) {
+	return this.count.Load(), math.Float64frombits(this.sum.Load())
+}
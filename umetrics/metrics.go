@@ -0,0 +1,361 @@
+// Package umetrics provides counters, gauges and histograms, with labels,
+// kept in a Registry that can be exposed in the Prometheus text format.
+//
+// Metrics are registered once, and then updated with atomic operations, so
+// they are cheap enough for packet paths:
+//
+//	var packets = umetrics.NewCounter("unet_packets_total", "Packets received")
+//	var latency = umetrics.NewHistogram("req_seconds", "Request latency",
+//	    umetrics.DefBuckets)
+//	var requests = umetrics.NewCounterVec("req_total", "Requests", "method", "code")
+//
+//	packets.Inc()
+//	latency.Since(start)
+//	requests.With("GET", "200").Inc()
+//
+// Finding a labelled metric (With) takes a map lookup, so on hot paths keep
+// the result:
+//
+//	getOk := requests.With("GET", "200")
+//
+// Values kept elsewhere can be exposed with CounterFunc, GaugeFunc, and
+// GaugeVec.Func.  Labelled metrics that are no longer needed can be removed
+// with Delete.
+//
+// The Default registry is served by the 'metrics' component (see
+// urest.AddMetricsManager), or by any http server:
+//
+//	http.Handle("/metrics", umetrics.Default)
+package umetrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// the registry used by the New... funcs
+var Default = NewRegistry()
+
+var (
+	nameRe_  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRe_ = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type kind_ int
+
+const (
+	kindCounter kind_ = iota
+	kindGauge
+	kindHistogram
+)
+
+func (this kind_) String() string {
+	switch this {
+	case kindCounter:
+		return "counter"
+	case kindGauge:
+		return "gauge"
+	}
+	return "histogram"
+}
+
+// Registry holds metrics by name
+type Registry struct {
+	lock     sync.Mutex
+	families map[string]*family_
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family_{}}
+}
+
+// all metrics with the same name
+type family_ struct {
+	name     string
+	help     string
+	kind     kind_
+	labels   []string
+	buckets  []float64                      // for histograms
+	fn       atomic.Pointer[func() float64] // for CounterFunc and GaugeFunc
+	lock     sync.Mutex                     // for adding children
+	children sync.Map                       // key of label values -> *child_
+}
+
+// a metric with its label values
+type child_ struct {
+	values []string
+	metric any // *Counter, *Gauge, *Histogram, or func() float64
+}
+
+// get the family, creating it if needed.  registering the same name again
+// gets the same family, so long as it is the same kind with the same labels.
+// anything else is a programming error, so panics.
+func (this *Registry) family(
+	name, help string,
+	kind kind_,
+	buckets []float64,
+	fn func() float64,
+	labels []string,
+) *family_ {
+	if !nameRe_.MatchString(name) {
+		panic(fmt.Sprintf("invalid metric name: '%s'", name))
+	}
+	for _, label := range labels {
+		if !labelRe_.MatchString(label) || strings.HasPrefix(label, "__") ||
+			(kindHistogram == kind && "le" == label) {
+			panic(fmt.Sprintf("invalid label name for %s: '%s'", name, label))
+		}
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	f := this.families[name]
+	if nil != f {
+		if kind != f.kind || strings.Join(labels, ",") != strings.Join(f.labels, ",") ||
+			(nil != fn) != (nil != f.fn.Load()) {
+			panic(fmt.Sprintf("metric %s already registered differently", name))
+		}
+		if nil != fn { // such as when the owner is rebuilt
+			f.fn.Store(&fn)
+		}
+		return f
+	}
+	if kindHistogram == kind {
+		buckets = checkBuckets(name, buckets)
+	}
+	f = &family_{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  append([]string(nil), labels...),
+		buckets: buckets,
+	}
+	if nil != fn {
+		f.fn.Store(&fn)
+	}
+	this.families[name] = f
+	return f
+}
+
+// remove the metric
+func (this *Registry) Unregister(name string) {
+	this.lock.Lock()
+	delete(this.families, name)
+	this.lock.Unlock()
+}
+
+// get the families, sorted by name
+func (this *Registry) sorted() (rv []*family_) {
+	this.lock.Lock()
+	rv = make([]*family_, 0, len(this.families))
+	for _, f := range this.families {
+		rv = append(rv, f)
+	}
+	this.lock.Unlock()
+	sort.Slice(rv, func(i, j int) bool { return rv[i].name < rv[j].name })
+	return
+}
+
+// get the key of the label values
+func (this *family_) key(values []string) string {
+	if len(values) != len(this.labels) {
+		panic(fmt.Sprintf("metric %s needs %d label values, got %d",
+			this.name, len(this.labels), len(values)))
+	}
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0]
+	}
+	return strings.Join(values, "\xff")
+}
+
+// get the metric for the label values, creating it if needed
+func (this *family_) get(values []string) any {
+	key := this.key(values)
+	if c, ok := this.children.Load(key); ok {
+		return c.(*child_).metric
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if c, ok := this.children.Load(key); ok {
+		return c.(*child_).metric
+	}
+	c := &child_{values: append([]string(nil), values...)}
+	switch this.kind {
+	case kindCounter:
+		c.metric = &Counter{}
+	case kindGauge:
+		c.metric = &Gauge{}
+	default:
+		c.metric = newHistogram(this.buckets)
+	}
+	this.children.Store(key, c)
+	return c.metric
+}
+
+// set the metric for the label values, replacing any existing one
+func (this *family_) set(values []string, metric any) {
+	key := this.key(values)
+	this.lock.Lock()
+	this.children.Store(key, &child_{
+		values: append([]string(nil), values...),
+		metric: metric,
+	})
+	this.lock.Unlock()
+}
+
+// remove the metric for the label values
+func (this *family_) remove(values []string) {
+	this.children.Delete(this.key(values))
+}
+
+// get the children, sorted by label values
+func (this *family_) sorted() (rv []*child_) {
+	this.children.Range(func(_, c any) bool {
+		rv = append(rv, c.(*child_))
+		return true
+	})
+	sort.Slice(rv, func(i, j int) bool {
+		a, b := rv[i].values, rv[j].values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return
+}
+
+//
+// registration
+//
+
+// get the counter with no labels
+func (this *Registry) Counter(name, help string) *Counter {
+	return this.family(name, help, kindCounter, nil, nil, nil).get(nil).(*Counter)
+}
+
+// get the counters with the labels
+func (this *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: this.family(name, help, kindCounter, nil, nil, labels)}
+}
+
+// expose a count kept elsewhere as a counter
+func (this *Registry) CounterFunc(name, help string, fn func() float64) {
+	this.family(name, help, kindCounter, nil, fn, nil)
+}
+
+// get the gauge with no labels
+func (this *Registry) Gauge(name, help string) *Gauge {
+	return this.family(name, help, kindGauge, nil, nil, nil).get(nil).(*Gauge)
+}
+
+// get the gauges with the labels
+func (this *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: this.family(name, help, kindGauge, nil, nil, labels)}
+}
+
+// expose a value kept elsewhere as a gauge
+func (this *Registry) GaugeFunc(name, help string, fn func() float64) {
+	this.family(name, help, kindGauge, nil, fn, nil)
+}
+
+// get the histogram with no labels, with the bucket upper bounds
+func (this *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	return this.family(name, help, kindHistogram, buckets, nil, nil).
+		get(nil).(*Histogram)
+}
+
+// get the histograms with the labels, with the bucket upper bounds
+func (this *Registry) HistogramVec(
+	name, help string,
+	buckets []float64,
+	labels ...string,
+) *HistogramVec {
+	return &HistogramVec{
+		f: this.family(name, help, kindHistogram, buckets, nil, labels),
+	}
+}
+
+// get the Default counter with no labels
+func NewCounter(name, help string) *Counter {
+	return Default.Counter(name, help)
+}
+
+// get the Default counters with the labels
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.CounterVec(name, help, labels...)
+}
+
+// get the Default gauge with no labels
+func NewGauge(name, help string) *Gauge {
+	return Default.Gauge(name, help)
+}
+
+// get the Default gauges with the labels
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.GaugeVec(name, help, labels...)
+}
+
+// get the Default histogram with no labels
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return Default.Histogram(name, help, buckets)
+}
+
+// get the Default histograms with the labels
+func NewHistogramVec(
+	name, help string,
+	buckets []float64,
+	labels ...string,
+) *HistogramVec {
+	return Default.HistogramVec(name, help, buckets, labels...)
+}
+
+//
+// vectors
+//
+
+// counters by label values
+type CounterVec struct{ f *family_ }
+
+// get the counter for the label values, in the order of the labels
+func (this *CounterVec) With(values ...string) *Counter {
+	return this.f.get(values).(*Counter)
+}
+
+// remove the counter for the label values
+func (this *CounterVec) Delete(values ...string) { this.f.remove(values) }
+
+// gauges by label values
+type GaugeVec struct{ f *family_ }
+
+// get the gauge for the label values, in the order of the labels
+func (this *GaugeVec) With(values ...string) *Gauge {
+	return this.f.get(values).(*Gauge)
+}
+
+// expose a value kept elsewhere as the gauge for the label values, replacing
+// any existing gauge for them
+func (this *GaugeVec) Func(fn func() float64, values ...string) {
+	this.f.set(values, fn)
+}
+
+// remove the gauge for the label values
+func (this *GaugeVec) Delete(values ...string) { this.f.remove(values) }
+
+// histograms by label values
+type HistogramVec struct{ f *family_ }
+
+// get the histogram for the label values, in the order of the labels
+func (this *HistogramVec) With(values ...string) *Histogram {
+	return this.f.get(values).(*Histogram)
+}
+
+// remove the histogram for the label values
+func (this *HistogramVec) Delete(values ...string) { this.f.remove(values) }
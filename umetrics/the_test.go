@@ -0,0 +1,121 @@
+package umetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.Counter("test_total", "Things counted")
+	c.Inc()
+	c.Add(2)
+	if c != r.Counter("test_total", "again") {
+		t.Fatalf("Registering again should get same counter")
+	} else if 3 != c.Get() {
+		t.Fatalf("Counter should be 3, got %d", c.Get())
+	}
+
+	g := r.GaugeVec("test_depth", "Queue depth", "queue")
+	g.With("b").Set(2.5)
+	g.With("a").Inc()
+	g.With("a").Dec()
+	g.With("a").Add(7)
+	g.Func(func() float64 { return 3 }, "c")
+	g.With("d").Inc()
+	g.Delete("d")
+
+	h := r.HistogramVec("test_seconds", "Latency", []float64{1, 0.1},
+		"method")
+	get := h.With("GET")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get.Observe(0.0625)
+			get.Observe(0.5)
+			get.Observe(4)
+		}()
+	}
+	wg.Wait()
+	if count, sum := get.Get(); 30 != count || 45.625 != sum {
+		t.Fatalf("Wrong histogram count=%d sum=%g", count, sum)
+	}
+
+	r.CounterVec("test_unused_total", "Not yet used", "path")
+	r.GaugeFunc("test_answer", "The answer", func() float64 { return 42 })
+	r.CounterVec("test_quoted_total", "Quoting\nhelp", "path").
+		With(`a"b\c`).Inc()
+
+	var b strings.Builder
+	if err := r.Expose(&b); err != nil {
+		t.Fatalf("Unable to expose: %s", err)
+	}
+	expect := `# HELP test_answer The answer
+# TYPE test_answer gauge
+test_answer 42
+# HELP test_depth Queue depth
+# TYPE test_depth gauge
+test_depth{queue="a"} 7
+test_depth{queue="b"} 2.5
+test_depth{queue="c"} 3
+# HELP test_quoted_total Quoting\nhelp
+# TYPE test_quoted_total counter
+test_quoted_total{path="a\"b\\c"} 1
+# HELP test_seconds Latency
+# TYPE test_seconds histogram
+test_seconds_bucket{method="GET",le="0.1"} 10
+test_seconds_bucket{method="GET",le="1"} 20
+test_seconds_bucket{method="GET",le="+Inf"} 30
+test_seconds_sum{method="GET"} 45.625
+test_seconds_count{method="GET"} 30
+# HELP test_total Things counted
+# TYPE test_total counter
+test_total 3
+`
+	if expect != b.String() {
+		t.Fatalf("Wrong exposition:\n%s", b.String())
+	}
+
+	//
+	// programming errors
+	//
+	for _, bad := range []func(){
+		func() { r.Gauge("test_total", "wrong kind") },
+		func() { r.CounterVec("test_total", "wrong labels", "x") },
+		func() { r.Counter("bad-name", "") },
+		func() { g.With("a", "b") },
+		func() { r.HistogramVec("test_le", "", nil, "le") },
+	} {
+		func() {
+			defer func() {
+				if nil == recover() {
+					t.Fatalf("Should have panicked")
+				}
+			}()
+			bad()
+		}()
+	}
+
+	//
+	// over HTTP
+	//
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unable to get: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if TextContentType != resp.Header.Get("Content-Type") ||
+		!strings.Contains(string(body), "test_total 3\n") {
+		t.Fatalf("Wrong response: %s\n%s", resp.Header, body)
+	}
+}
@@ -0,0 +1,39 @@
+package upostgres
+
+import (
+	"github.com/lib/pq"
+	"github.com/tredeske/u/uerr"
+)
+
+func init() {
+	uerr.AddClassifier(classifyPq)
+}
+
+// classify postgres errors by SQLSTATE for uerr.ClassOf
+func classifyPq(err error) uerr.Class {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return uerr.ClassUnknown
+	}
+	switch pqErr.Code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return uerr.ClassRetriable
+	case "57014": // query_canceled (statement_timeout)
+		return uerr.ClassTimeout
+	case "42501": // insufficient_privilege
+		return uerr.ClassForbidden
+	case "42P01", "42883", "3D000": // undefined table, function, database
+		return uerr.ClassNotFound
+	case "23505": // unique_violation
+		return uerr.ClassConflict
+	}
+	switch pqErr.Code.Class() {
+	case "08", "53", "57", "58": // connection, resources, operator, system
+		return uerr.ClassRetriable
+	case "28": // invalid_authorization_specification
+		return uerr.ClassUnauthorized
+	case "22", "23": // data_exception, integrity_constraint_violation
+		return uerr.ClassInvalid
+	}
+	return uerr.ClassPermanent
+}
@@ -0,0 +1,107 @@
+package urest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tredeske/u/golum"
+	"github.com/tredeske/u/uconfig"
+	"github.com/tredeske/u/umetrics"
+)
+
+var (
+	metricsAdded_ bool
+
+	clientRequests_ = umetrics.NewCounterVec("urest_client_requests_total",
+		"Requests made by named Requestors, by status ('error' if no response)",
+		"name", "host", "status")
+	clientSeconds_ = umetrics.NewHistogramVec("urest_client_request_seconds",
+		"Time for named Requestors to get response headers, by status",
+		umetrics.DefBuckets, "name", "host", "status")
+)
+
+// record the request made by the named Requestor
+func observeRequest(
+	name string,
+	req *http.Request,
+	resp *http.Response,
+	start time.Time,
+) {
+	host := req.Host
+	if 0 == len(host) && nil != req.URL {
+		host = req.URL.Host
+	}
+	status := "error"
+	if nil != resp {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	clientSeconds_.With(name, host, status).Since(start)
+	clientRequests_.With(name, host, status).Inc()
+}
+
+// add the 'metrics' component type, which serves umetrics.Default in the
+// Prometheus text format:
+//
+//	components:
+//	- name:     metrics
+//	  type:     metrics
+//	  config:
+//	    httpAddress: ":9100"
+//	    path:        /metrics
+func AddMetricsManager() {
+	if !metricsAdded_ {
+		metricsAdded_ = true
+		golum.AddReloadable("metrics", &metricsServer_{})
+	}
+}
+
+type metricsServer_ struct {
+	server *http.Server
+	path   string
+}
+
+func (this *metricsServer_) Help(name string, help *uconfig.Help) {
+	p := help.Init(name, "Serves metrics in the Prometheus text format")
+	p.NewItem("path", "string", "URL path to serve metrics on").
+		Default("/metrics")
+	ShowHttpServer("", "", p)
+}
+
+func (this *metricsServer_) Reload(
+	name string,
+	c *uconfig.Chain,
+) (
+	rv golum.Reloadable,
+	err error,
+) {
+	next := &metricsServer_{path: "/metrics"}
+	err = c.
+		GetString("path", &next.path, uconfig.StringNotBlank()).
+		Build(&next.server, BuildHttpServer).
+		Done()
+	if err != nil {
+		return
+	} else if 0 == len(next.server.Addr) {
+		err = errors.New("metrics: httpAddress not set")
+		return
+	} else if !strings.HasPrefix(next.path, "/") {
+		next.path = "/" + next.path
+	}
+	mux := http.NewServeMux()
+	mux.Handle(next.path, umetrics.Default)
+	next.server.Handler = mux
+	rv = next
+	return
+}
+
+func (this *metricsServer_) Start() (err error) {
+	StartServer(this.server, nil, nil)
+	return
+}
+
+func (this *metricsServer_) Stop() {
+	StopServer(this.server, 0)
+}
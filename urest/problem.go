@@ -0,0 +1,51 @@
+package urest
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/tredeske/u/uerr"
+)
+
+// respond to the request with err as an RFC 7807 problem+json document, with
+// the HTTP status from the classification of err (see uerr.HTTPStatus), so
+// that the error can be rebuilt by a Requestor.
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	problem := uerr.ToProblem(err)
+	if nil == problem {
+		problem = &uerr.Problem{Status: http.StatusInternalServerError}
+	}
+	problem.Title = http.StatusText(problem.Status)
+	if nil != req && nil != req.URL {
+		problem.Instance = req.URL.Path
+	}
+	doc, merr := problem.Marshal()
+	if merr != nil { // only if a field cannot be encoded
+		problem.Fields = nil
+		problem.Chain = nil
+		doc, _ = problem.Marshal()
+	}
+	w.Header().Set("Content-Type", uerr.ProblemContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc)))
+	w.WriteHeader(problem.Status)
+	w.Write(doc)
+}
+
+// a handler func that returns an error.  any error is written by WriteError,
+// so should be returned before the handler has written the response.
+type ErrorHandlerFunc func(w http.ResponseWriter, req *http.Request) error
+
+// implement http.Handler
+func (f ErrorHandlerFunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	err := f(w, req)
+	if err != nil {
+		WriteError(w, req, err)
+	}
+}
+
+// is the response a problem+json document?
+func isProblem(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return nil == err && uerr.ProblemContentType == mediaType
+}
@@ -57,12 +57,17 @@ const errNoResp_ = uerr.Const("No response - was HTTP method even called?")
 //
 //	var reqW, respW bytes.Buffer
 //	_, err := urest.NewRequestor(client).Dump(&reqW,&respW).PostJson(...
+//
+// If a name is set (SetName), then requests are counted and timed in the
+// urest_client_* metrics (see umetrics), labelled with the name, the host,
+// and the status.
 type Requestor struct {
 	Client   *http.Client
 	Request  *http.Request
 	Response *http.Response
 	err      error
 	cancel   context.CancelFunc
+	name     string // if set, publish metrics with this name
 }
 
 // create a new request chain.  if client is nil (not recommended), then use
@@ -84,6 +89,13 @@ func (this *Requestor) Reset() *Requestor {
 	return this
 }
 
+// publish metrics for requests made by this, labelled with name.  the name
+// is kept across Reset.
+func (this *Requestor) SetName(name string) *Requestor {
+	this.name = name
+	return this
+}
+
 // set a timeout to this request
 //
 // since we create the context, we handle cancelation/cleanup
@@ -354,7 +366,14 @@ func (this *Requestor) Do() *Requestor {
 		if nil != this.Request.Body && 0 == this.Request.ContentLength {
 			this.Request.ContentLength = -1
 		}
+		var start time.Time
+		if 0 != len(this.name) {
+			start = time.Now()
+		}
 		this.Response, this.err = this.Client.Do(this.Request)
+		if 0 != len(this.name) {
+			observeRequest(this.name, this.Request, this.Response, start)
+		}
 		cancel := this.cancel
 		if nil != cancel {
 			cancel()
@@ -907,11 +926,35 @@ func (this *Requestor) IsOk() *Requestor {
 	return this.StatusIs(http.StatusOK)
 }
 
+// record an error for an unexpected status.  if the response is a
+// problem+json document (see WriteError), then the error chain is rebuilt
+// from it.
 func (this *Requestor) invalidStatus() {
 	var body []byte
 	this.BodyBytes(&body)
-	this.err = fmt.Errorf("Invalid status: %d, resp: '%s'",
-		this.Response.StatusCode, string(body))
+	status := this.Response.StatusCode
+	if isProblem(this.Response) {
+		problem, err := uerr.ParseProblem(body)
+		if nil == err {
+			this.err = uerr.ChainfCode(problem.Err(), status,
+				"Invalid status: %d", status)
+			return
+		}
+	}
+	this.err = uerr.ChainfCode(nil, status,
+		"Invalid status: %d, resp: '%s'", status, string(body))
+}
+
+// error unless response status is 2xx
+func (this *Requestor) IsSuccess() *Requestor {
+	if nil == this.err {
+		if nil == this.Response {
+			this.err = errNoResp_
+		} else if 2 != this.Response.StatusCode/100 {
+			this.invalidStatus()
+		}
+	}
+	return this
 }
 
 // error unless response status is one of the indicated ones
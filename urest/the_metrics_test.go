@@ -0,0 +1,79 @@
+package urest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tredeske/u/golum"
+	"github.com/tredeske/u/umetrics"
+)
+
+func TestMetricsServer(t *testing.T) {
+	AddMetricsManager()
+	umetrics.NewCounter("urest_test_total", "Test count").Inc()
+
+	err := golum.TestLoadAndStart([]byte(`
+components:
+- name:         metrics
+  type:         metrics
+  config:
+    httpAddress:  "localhost:28089"
+    path:         /test/metrics
+`))
+	if err != nil {
+		t.Fatalf("Unable to load and start: %s", err)
+	}
+	defer golum.TestStop()
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ { // server starts in background
+		resp, err = http.Get("http://localhost:28089/test/metrics")
+		if nil == err {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Unable to get metrics: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "urest_test_total 1\n") {
+		t.Fatalf("Wrong metrics:\n%s", body)
+	}
+
+	//
+	// named Requestor
+	//
+	var text string
+	_, err = NewRequestor(nil).
+		SetName("metricsTest").
+		SetUrlString("http://localhost:28089/test/metrics").
+		Get().
+		IsOk().
+		Done()
+	if err != nil {
+		t.Fatalf("Unable to get metrics: %s", err)
+	}
+	_, err = NewRequestor(nil).
+		SetName("metricsTest").
+		SetUrlString("http://localhost:28089/test/metrics").
+		Get().
+		IsOk().
+		BodyText(&text).
+		Done()
+	if err != nil {
+		t.Fatalf("Unable to get metrics: %s", err)
+	}
+	for _, expect := range []string{
+		`urest_client_requests_total{name="metricsTest",host="localhost:28089",status="200"} 1`,
+		`urest_client_request_seconds_count{name="metricsTest",host="localhost:28089",status="200"} 1`,
+	} {
+		if !strings.Contains(text, expect+"\n") {
+			t.Fatalf("Missing %s in:\n%s", expect, text)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package urest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tredeske/u/uerr"
+)
+
+func TestProblem(t *testing.T) {
+	server := httptest.NewServer(ErrorHandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) error {
+			err := uerr.Chainf(io.EOF, "reading").Classify(uerr.ClassNotFound)
+			return uerr.With(err, "path", req.URL.Path)
+		}))
+	defer server.Close()
+
+	_, err := NewRequestor(nil).
+		SetUrlString(server.URL + "/thing").
+		Get().
+		IsSuccess().
+		Done()
+	if nil == err {
+		t.Fatalf("Should have failed")
+	} else if uerr.ClassNotFound != uerr.ClassOf(err) {
+		t.Fatalf("Class wrong for %s", err)
+	} else if "/thing" != uerr.Fields(err)["path"] {
+		t.Fatalf("Fields wrong for %s", err)
+	} else if !strings.HasPrefix(err.Error(),
+		"Invalid status: 404, caused by: reading, caused by: EOF") {
+		t.Fatalf("Error wrong: %s", err)
+	}
+}
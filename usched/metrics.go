@@ -0,0 +1,59 @@
+package usched
+
+import (
+	"time"
+
+	"github.com/tredeske/u/umetrics"
+)
+
+var (
+	jobRuns_ = umetrics.NewCounterVec("usched_job_runs_total",
+		"Job runs", "scheduler", "job")
+	jobFailures_ = umetrics.NewCounterVec("usched_job_failures_total",
+		"Job runs that panicked", "scheduler", "job")
+	jobSkipped_ = umetrics.NewCounterVec("usched_job_skipped_total",
+		"Job runs skipped due to the job already running", "scheduler", "job")
+	jobSeconds_ = umetrics.NewHistogramVec("usched_job_seconds",
+		"Job run duration",
+		umetrics.ExponentialBuckets(0.01, 4, 10), "scheduler", "job")
+)
+
+// metrics published for jobs of a Scheduler with a Name
+type jobMetrics_ struct {
+	scheduler string
+	job       string
+	runs      *umetrics.Counter
+	failures  *umetrics.Counter
+	skipped   *umetrics.Counter
+	seconds   *umetrics.Histogram
+}
+
+func newJobMetrics(scheduler, job string) *jobMetrics_ {
+	return &jobMetrics_{
+		scheduler: scheduler,
+		job:       job,
+		runs:      jobRuns_.With(scheduler, job),
+		failures:  jobFailures_.With(scheduler, job),
+		skipped:   jobSkipped_.With(scheduler, job),
+		seconds:   jobSeconds_.With(scheduler, job),
+	}
+}
+
+// record the run that started at start.  deferred, so it can tell if the job
+// panicked, which it passes on.
+func (this *jobMetrics_) ran(start time.Time) {
+	this.runs.Inc()
+	this.seconds.Since(start)
+	if it := recover(); nil != it {
+		this.failures.Inc()
+		panic(it)
+	}
+}
+
+// stop publishing for the job
+func (this *jobMetrics_) remove() {
+	jobRuns_.Delete(this.scheduler, this.job)
+	jobFailures_.Delete(this.scheduler, this.job)
+	jobSkipped_.Delete(this.scheduler, this.job)
+	jobSeconds_.Delete(this.scheduler, this.job)
+}
@@ -44,6 +44,9 @@ func (this SchedulableFunc) OnSchedule() { this() }
 //
 // Performs early initialization of jobs, as directed.
 //
+// If Name is set, publishes usched_job_* metrics (see umetrics) for jobs
+// added after it is set, labelled with the name and the job name.
+//
 type Scheduler struct {
 	theCron *cron.Cron          // schedules jobs
 	lock    sync.Mutex          // for handles map
@@ -52,6 +55,7 @@ type Scheduler struct {
 	Min     time.Duration       // minimum interval allowed
 	Max     time.Duration       // maximum interval allowed
 	Init    int                 // max allowed concurrent initializations, or -1
+	Name    string              // if set, publish metrics with this name
 }
 
 // implements cron.Job
@@ -63,6 +67,7 @@ type handle_ struct {
 	cronInterval string           // how often (based on interval)
 	name         string           // unique name
 	ancestor     *handle_         // detect ancestor already running
+	metrics      *jobMetrics_     // if Scheduler.Name set
 }
 
 //
@@ -162,6 +167,9 @@ func (this *Scheduler) Add(name, interval string, s Schedulable) (err error) {
 		schedulable: s,
 		ancestor:    ancestor,
 	}
+	if 0 != len(this.Name) {
+		h.metrics = newJobMetrics(this.Name, name)
+	}
 
 	h.cronInterval, err = this.calcInterval(interval)
 	if err != nil {
@@ -198,6 +206,9 @@ func (this *Scheduler) Remove(name string) {
 	defer this.lock.Unlock()
 	if h, ok := this.handles[name]; ok {
 		this.remove(h)
+		if nil != h.metrics {
+			h.metrics.remove()
+		}
 	}
 }
 
@@ -521,14 +532,23 @@ func (this *handle_) Run() {
 				this.ancestor = nil // we can finally run
 			} else {
 				log.Printf("sched: ancestor of %s already running", this.name)
+				if nil != this.metrics {
+					this.metrics.skipped.Inc()
+				}
 				return
 			}
 		}
 
 		ulog.Debugf("sched: running %s", this.name)
+		if nil != this.metrics {
+			defer this.metrics.ran(time.Now())
+		}
 		this.schedulable.OnSchedule()
 
 	} else {
 		log.Printf("sched: %s already running", this.name)
+		if nil != this.metrics {
+			this.metrics.skipped.Inc()
+		}
 	}
 }
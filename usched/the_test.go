@@ -1,8 +1,11 @@
 package usched
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/tredeske/u/umetrics"
 )
 
 type testSchedulable_ struct {
@@ -87,3 +90,49 @@ func TestChanJob(t *testing.T) {
 
 	job.Close() // notify we're done (in this case, don't invoke us anymore)
 }
+
+func TestScheduleMetrics(t *testing.T) {
+	s := NewScheduler()
+	s.Name = "metricsTest"
+
+	fail := false
+	err := s.AddFunc("job", "@hourly", func() {
+		if fail {
+			panic("failed")
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unable to add func: %s", err)
+	}
+	s.RunJobSync("job")
+	fail = true
+	func() {
+		defer func() { recover() }()
+		s.RunJobSync("job")
+	}()
+	if !s.LockJob("job") {
+		t.Fatalf("Unable to lock job")
+	}
+	s.RunJobSync("job") // skipped
+	s.UnlockJob("job")
+
+	var b strings.Builder
+	umetrics.Default.Expose(&b)
+	for _, expect := range []string{
+		`usched_job_runs_total{scheduler="metricsTest",job="job"} 2`,
+		`usched_job_failures_total{scheduler="metricsTest",job="job"} 1`,
+		`usched_job_skipped_total{scheduler="metricsTest",job="job"} 1`,
+		`usched_job_seconds_count{scheduler="metricsTest",job="job"} 2`,
+	} {
+		if !strings.Contains(b.String(), expect+"\n") {
+			t.Fatalf("Missing %s in:\n%s", expect, b.String())
+		}
+	}
+
+	s.Remove("job")
+	b.Reset()
+	umetrics.Default.Expose(&b)
+	if strings.Contains(b.String(), "usched_job") {
+		t.Fatalf("Job metrics should be removed:\n%s", b.String())
+	}
+}
@@ -4,6 +4,8 @@ package usftp
 
 import (
 	"fmt"
+
+	"github.com/tredeske/u/uerr"
 )
 
 const (
@@ -232,6 +234,36 @@ func (s *StatusError) FxCode() fxerr {
 	return fxerr(s.Code)
 }
 
+func init() {
+	uerr.AddClassifier(classifyStatus)
+}
+
+// classify StatusError for uerr.ClassOf
+func classifyStatus(err error) uerr.Class {
+	s, ok := err.(*StatusError)
+	if !ok {
+		return uerr.ClassUnknown
+	}
+	switch s.Code {
+	case sshFxNoSuchFile, sshFxNoSuchPath, sshFxNoMedia:
+		return uerr.ClassNotFound
+	case sshFxPermissionDenied, sshFxWriteProtect, sshFxCannotDelete:
+		return uerr.ClassForbidden
+	case sshFxUnknownPrincipal:
+		return uerr.ClassUnauthorized
+	case sshFxNoConnection, sshFxConnectionLost, sshFxLockConflict,
+		sshFxByteRangeLockConflict, sshFxDeletePending:
+		return uerr.ClassRetriable
+	case sshFxFileAlreadyExists, sshFxDirNotEmpty:
+		return uerr.ClassConflict
+	case sshFxBadMessage, sshFxInvalidHandle, sshFxInvalidFilename,
+		sshFxInvalidParameter, sshFxNotADirectory, sshFxFileIsADirectory,
+		sshFxLinkLoop:
+		return uerr.ClassInvalid
+	}
+	return uerr.ClassPermanent
+}
+
 /*
 func getSupportedExtensionByName(extensionName string) (sshExtensionPair, error) {
 	for _, supportedExtension := range supportedSFTPExtensions {
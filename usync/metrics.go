@@ -0,0 +1,76 @@
+package usync
+
+import (
+	"sync/atomic"
+
+	"github.com/tredeske/u/umetrics"
+)
+
+var (
+	workersRunning_ = umetrics.NewGaugeVec("usync_workers",
+		"Worker goroutines running", "name")
+	workersBusy_ = umetrics.NewGaugeVec("usync_workers_busy",
+		"Workers working on a request", "name")
+	workersQueued_ = umetrics.NewGaugeVec("usync_workers_queued",
+		"Requests waiting for a worker", "name")
+	workersDraining_ = umetrics.NewGaugeVec("usync_workers_draining",
+		"1 if draining, 0 if not", "name")
+	workersRequests_ = umetrics.NewCounterVec("usync_workers_requests_total",
+		"Requests worked on", "name")
+	workersDrained_ = umetrics.NewCounterVec("usync_workers_drained_total",
+		"Requests discarded while draining", "name")
+)
+
+// metrics published by Workers with a Name
+type workerMetrics_ struct {
+	name     string
+	pool     *Workers
+	running  atomic.Int64 // worker goroutines
+	busy     *umetrics.Gauge
+	requests *umetrics.Counter
+	drained  *umetrics.Counter
+}
+
+func newWorkerMetrics(pool *Workers) *workerMetrics_ {
+	return &workerMetrics_{
+		name:     pool.Name,
+		pool:     pool,
+		busy:     workersBusy_.With(pool.Name),
+		requests: workersRequests_.With(pool.Name),
+		drained:  workersDrained_.With(pool.Name),
+	}
+}
+
+// workers are starting, so publish the gauges that follow the pool
+func (this *workerMetrics_) start(workers int) {
+	this.running.Add(int64(workers))
+	workersRunning_.Func(
+		func() float64 { return float64(this.running.Load()) }, this.name)
+	workersQueued_.Func(
+		func() float64 { return float64(len(this.pool.RequestC)) }, this.name)
+	workersDraining_.Func(
+		func() float64 {
+			if this.pool.drain.IsSet() {
+				return 1
+			}
+			return 0
+		}, this.name)
+}
+
+// a worker finished a request
+func (this *workerMetrics_) worked() {
+	this.busy.Dec()
+	this.requests.Inc()
+}
+
+// a worker is ending.  when the last one ends, stop following the pool, but
+// keep showing that no workers are running.
+func (this *workerMetrics_) stop(busy bool) {
+	if busy {
+		this.busy.Dec()
+	}
+	if 0 == this.running.Add(-1) {
+		workersQueued_.Delete(this.name)
+		workersDraining_.Delete(this.name)
+	}
+}
@@ -1,6 +1,12 @@
 package usync
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tredeske/u/umetrics"
+)
 
 func TestWorkers(t *testing.T) {
 
@@ -83,3 +89,55 @@ func TestWorkGang(t *testing.T) {
 		t.Fatalf("Pool not drained")
 	}
 }
+
+func TestWorkersMetrics(t *testing.T) {
+
+	pool := Workers{Name: "metricsTest"}
+	release := make(chan struct{})
+	pool.Go(2,
+		func() WorkF {
+			return func(req any) {
+				<-release
+			}
+		})
+	for i := 0; i < 5; i++ {
+		pool.Put(i)
+	}
+	if !AwaitTrue(time.Second, time.Millisecond, func() bool {
+		return 3 == len(pool.RequestC)
+	}) {
+		t.Fatalf("Workers did not pick up requests")
+	}
+
+	var b strings.Builder
+	umetrics.Default.Expose(&b)
+	for _, expect := range []string{
+		`usync_workers{name="metricsTest"} 2`,
+		`usync_workers_busy{name="metricsTest"} 2`,
+		`usync_workers_queued{name="metricsTest"} 3`,
+		`usync_workers_draining{name="metricsTest"} 0`,
+	} {
+		if !strings.Contains(b.String(), expect+"\n") {
+			t.Fatalf("Missing %s in:\n%s", expect, b.String())
+		}
+	}
+
+	close(release)
+	pool.Close()
+	pool.WaitDone()
+
+	b.Reset()
+	umetrics.Default.Expose(&b)
+	for _, expect := range []string{
+		`usync_workers{name="metricsTest"} 0`,
+		`usync_workers_busy{name="metricsTest"} 0`,
+		`usync_workers_requests_total{name="metricsTest"} 5`,
+	} {
+		if !strings.Contains(b.String(), expect+"\n") {
+			t.Fatalf("Missing %s in:\n%s", expect, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "usync_workers_queued") {
+		t.Fatalf("Queue should not be published after done:\n%s", b.String())
+	}
+}
@@ -28,11 +28,16 @@ type WorkF func(any)
 //	    }
 //	    pool.Close() // feeder closes pool
 //	}()
+//
+// If Name is set before Go, then the pool publishes usync_workers_* metrics
+// (see umetrics) labelled with the name.
 type Workers struct {
-	OnDone   func()         // if set, call this when workers done
-	RequestC Chan[any]      // where to post requests to be worked on
-	drain    AtomicBool     //
-	wg       sync.WaitGroup //
+	Name     string          // if set, publish metrics with this name
+	OnDone   func()          // if set, call this when workers done
+	RequestC Chan[any]       // where to post requests to be worked on
+	drain    AtomicBool      //
+	wg       sync.WaitGroup  //
+	metrics  *workerMetrics_ // if Name set
 
 	//
 	// if this is set, call whenever a goroutine panics
@@ -52,12 +57,24 @@ func (this *Workers) Go(workers int, factory func() (work WorkF)) {
 
 	this.wg.Add(workers)
 
+	if 0 != len(this.Name) {
+		if nil == this.metrics || this.Name != this.metrics.name {
+			this.metrics = newWorkerMetrics(this)
+		}
+		this.metrics.start(workers)
+	}
+	m := this.metrics
+
 	//
 	// the workers
 	//
 	for i := 0; i < workers; i++ {
 		go func(work func(any)) {
+			busy := false
 			defer func() {
+				if nil != m {
+					m.stop(busy)
+				}
 				this.wg.Done()
 				if it := recover(); nil != it {
 					log.Printf("WARN: sync.Worker panic: %s", it)
@@ -65,23 +82,35 @@ func (this *Workers) Go(workers int, factory func() (work WorkF)) {
 			}()
 			for req := range this.RequestC {
 
-				if !this.drain.IsSet() { // don't do any work if draining
-					if nil != this.OnPanic {
-						die := false
-						func() {
-							defer func() {
-								if it := recover(); it != nil {
-									die = this.OnPanic(it)
-								}
-							}()
-							work(req)
+				if this.drain.IsSet() { // don't do any work if draining
+					if nil != m {
+						m.drained.Inc()
+					}
+					continue
+				}
+				if nil != m {
+					busy = true
+					m.busy.Inc()
+				}
+				if nil != this.OnPanic {
+					die := false
+					func() {
+						defer func() {
+							if it := recover(); it != nil {
+								die = this.OnPanic(it)
+							}
 						}()
-						if die {
-							break
-						}
-					} else {
 						work(req)
+					}()
+					if die {
+						break
 					}
+				} else {
+					work(req)
+				}
+				if nil != m {
+					busy = false
+					m.worked()
 				}
 			}
 		}(factory())
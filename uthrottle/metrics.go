@@ -0,0 +1,28 @@
+package uthrottle
+
+import "github.com/tredeske/u/umetrics"
+
+var (
+	throttleUnits_ = umetrics.NewCounterVec("uthrottle_units_total",
+		"Units of work accounted", "name")
+	throttleWaits_ = umetrics.NewHistogramVec("uthrottle_wait_seconds",
+		"Time spent waiting for the throttle",
+		umetrics.ExponentialBuckets(0.001, 4, 8), "name")
+	throttleRate_ = umetrics.NewGaugeVec("uthrottle_rate",
+		"Units of work allowed per second", "name")
+)
+
+// metrics published by throttles with a Name
+type throttleMetrics_ struct {
+	units *umetrics.Counter
+	waits *umetrics.Histogram
+	rate  *umetrics.Gauge
+}
+
+func newThrottleMetrics(name string) *throttleMetrics_ {
+	return &throttleMetrics_{
+		units: throttleUnits_.With(name),
+		waits: throttleWaits_.With(name),
+		rate:  throttleRate_.With(name),
+	}
+}
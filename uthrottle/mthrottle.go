@@ -30,13 +30,18 @@ type Throttler interface {
 // The implementation is semi-lock-free.  A worker only acquires the lock
 // when the worker must sleep before continuing.
 //
+// If Name is set before Start, publishes uthrottle_* metrics (see umetrics)
+// labelled with the name.
+//
 type MThrottle struct {
-	used     int64         // units used by worker
-	avail    int64         // units available to worker
-	lock     sync.Mutex    // for waiting
-	cond     *sync.Cond    // for waiting
-	rate     int64         // units per second, or 0 if inactive
-	interval time.Duration // time interval
+	used     int64             // units used by worker
+	avail    int64             // units available to worker
+	lock     sync.Mutex        // for waiting
+	cond     *sync.Cond        // for waiting
+	rate     int64             // units per second, or 0 if inactive
+	interval time.Duration     // time interval
+	Name     string            // if set, publish metrics with this name
+	metrics  *throttleMetrics_ // if Name set
 }
 
 //
@@ -44,23 +49,36 @@ type MThrottle struct {
 //
 func (this *MThrottle) Account(amount int64) {
 	atomic.AddInt64(&this.used, amount)
+	if nil != this.metrics {
+		this.metrics.units.Add(uint64(amount))
+	}
 }
 
 //
 // tell throttle amount, and wait for next time to do something
 //
 func (this *MThrottle) Await(amount int64) {
+	if nil != this.metrics {
+		this.metrics.units.Add(uint64(amount))
+	}
 	if atomic.LoadInt64(&this.avail) < atomic.AddInt64(&this.used, amount) {
 		this.await() // allow this outer call to inline
 	}
 }
 
 func (this *MThrottle) await() {
+	var start time.Time
+	if nil != this.metrics {
+		start = time.Now()
+	}
 	this.lock.Lock()
 	for atomic.LoadInt64(&this.avail) < atomic.LoadInt64(&this.used) {
 		this.cond.Wait()
 	}
 	this.lock.Unlock()
+	if nil != this.metrics {
+		this.metrics.waits.Since(start)
+	}
 }
 
 func (this *MThrottle) Start(rate int64, interval time.Duration) {
@@ -80,6 +98,10 @@ func (this *MThrottle) Start(rate int64, interval time.Duration) {
 	if nil == this.cond {
 		this.cond = sync.NewCond(&this.lock)
 	}
+	if 0 != len(this.Name) {
+		this.metrics = newThrottleMetrics(this.Name)
+		this.metrics.rate.Set(float64(rate))
+	}
 
 	if 0 < this.rate {
 		go this.run()
@@ -99,6 +121,9 @@ func (this *MThrottle) SetRate(rate int64) {
 	this.lock.Lock()
 	needStart := 0 == this.rate
 	this.rate = rate
+	if nil != this.metrics {
+		this.metrics.rate.Set(float64(rate))
+	}
 	this.lock.Unlock()
 	if needStart {
 		go this.run()
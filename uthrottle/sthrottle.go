@@ -14,13 +14,18 @@ import (
 // running the benchmarks doesn't appear to show that for uncontended (single
 // goroutine) case.  at 400Gb/s, they both take 26% +- 1% of cpu.
 //
+// If Name is set before Start, publishes uthrottle_* metrics (see umetrics)
+// labelled with the name.
+//
 type SThrottle struct {
-	used     int64         // units used by worker
-	avail    int64         // units available to worker
-	dole     int64         // units per second, or 0 if inactive
-	lastT    int64         //
-	interval time.Duration // time interval
-	rate     int64         // units per second, or 0 if inactive
+	used     int64             // units used by worker
+	avail    int64             // units available to worker
+	dole     int64             // units per second, or 0 if inactive
+	lastT    int64             //
+	interval time.Duration     // time interval
+	rate     int64             // units per second, or 0 if inactive
+	Name     string            // if set, publish metrics with this name
+	metrics  *throttleMetrics_ // if Name set
 }
 
 //
@@ -28,6 +33,9 @@ type SThrottle struct {
 //
 func (this *SThrottle) Account(amount int64) {
 	this.used += amount
+	if nil != this.metrics {
+		this.metrics.units.Add(uint64(amount))
+	}
 }
 
 //
@@ -35,6 +43,9 @@ func (this *SThrottle) Account(amount int64) {
 //
 func (this *SThrottle) Await(amount int64) {
 	this.used += amount
+	if nil != this.metrics {
+		this.metrics.units.Add(uint64(amount))
+	}
 	if this.avail < this.used {
 		this.adjust()
 	}
@@ -47,8 +58,13 @@ func (this *SThrottle) adjust() {
 	nIntervals := 1 + this.used/dole
 	nextT := this.lastT + (nIntervals * int64(this.interval))
 	now := time.Now().UnixNano()
+	var wait time.Duration
 	if now < nextT {
-		time.Sleep(time.Duration(nextT - now))
+		wait = time.Duration(nextT - now)
+		time.Sleep(wait)
+	}
+	if nil != this.metrics {
+		this.metrics.waits.ObserveDuration(wait)
 	}
 	this.used = 0
 	this.avail = dole
@@ -66,6 +82,9 @@ func (this *SThrottle) Start(rate int64, interval time.Duration) {
 		panic("throttle already started!")
 	}
 	this.interval = interval
+	if 0 != len(this.Name) {
+		this.metrics = newThrottleMetrics(this.Name)
+	}
 	this.SetRate(rate)
 }
 
@@ -84,4 +103,7 @@ func (this *SThrottle) SetRate(rate int64) {
 	}
 	atomic.StoreInt64(&this.dole, dole)
 	atomic.StoreInt64(&this.rate, rate)
+	if nil != this.metrics {
+		this.metrics.rate.Set(float64(rate))
+	}
 }
@@ -3,15 +3,18 @@ package uthrottle
 import (
 	"fmt"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/tredeske/u/umetrics"
 )
 
 func TestThrottles(t *testing.T) {
 	const AMOUNT int64 = 1000000
 	for name, throttle := range map[string]Throttler{
-		"S": &SThrottle{},
-		"M": &MThrottle{},
+		"S": &SThrottle{Name: "testS"},
+		"M": &MThrottle{Name: "testM"},
 	} {
 		t.Run(name, func(t *testing.T) {
 			throttle.Start(AMOUNT, 0)
@@ -29,6 +32,21 @@ func TestThrottles(t *testing.T) {
 				t.Fatalf("Took %s, but should have taken .25s", since)
 			}
 			fmt.Printf("took: %s\n", since)
+
+			var b strings.Builder
+			umetrics.Default.Expose(&b)
+			for _, expect := range []string{
+				fmt.Sprintf(`uthrottle_units_total{name="test%s"} %d`,
+					name, AMOUNT/2),
+				fmt.Sprintf(`uthrottle_rate{name="test%s"} %g`, name,
+					float64(AMOUNT)),
+				fmt.Sprintf(`uthrottle_wait_seconds_bucket{name="test%s",le="+Inf"}`,
+					name),
+			} {
+				if !strings.Contains(b.String(), expect) {
+					t.Fatalf("Missing %s in:\n%s", expect, b.String())
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,417 @@
+package utrace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tredeske/u/uexit"
+	"github.com/tredeske/u/ulog"
+	"github.com/tredeske/u/umetrics"
+	"github.com/tredeske/u/urest"
+)
+
+// where finished spans go (see SetExporter)
+type Exporter interface {
+	// export a batch of spans.  called from one goroutine at a time.
+	Export(spans []*SpanData) (err error)
+
+	// release any resources
+	Close() (err error)
+}
+
+var (
+	spansExported_ = umetrics.NewCounter("utrace_spans_exported_total",
+		"Spans exported")
+	spansDropped_ = umetrics.NewCounter("utrace_spans_dropped_total",
+		"Spans dropped due to a full queue or a failed export")
+)
+
+// queues finished spans, exporting them in batches
+type batcher_ struct {
+	exporter Exporter
+	batch    int
+	lock     sync.Mutex
+	spans    []*SpanData
+	kickC    chan struct{}      // batch is ready
+	flushC   chan chan struct{} // export queued now
+	stopC    chan struct{}
+	doneC    chan struct{}
+}
+
+var (
+	exporting_    atomic.Pointer[batcher_]
+	exporterLock_ sync.Mutex // serialize SetExporter
+	atFlush_      sync.Once
+)
+
+// Export finished spans with e, in batches of up to batch spans (default 512)
+// at least every interval (default 5s).  Up to 4 batches are queued, beyond
+// which spans are dropped.  Any prior Exporter is flushed and closed.  A nil
+// e stops exporting.
+//
+// Queued spans are also exported when the process exits through uexit.
+func SetExporter(e Exporter, batch int, interval time.Duration) {
+	if 0 >= batch {
+		batch = 512
+	}
+	if 0 >= interval {
+		interval = 5 * time.Second
+	}
+	exporterLock_.Lock()
+	defer exporterLock_.Unlock()
+
+	var b *batcher_
+	if nil != e {
+		b = &batcher_{
+			exporter: e,
+			batch:    batch,
+			spans:    make([]*SpanData, 0, batch),
+			kickC:    make(chan struct{}, 1),
+			flushC:   make(chan chan struct{}),
+			stopC:    make(chan struct{}),
+			doneC:    make(chan struct{}),
+		}
+		go b.run(interval)
+		atFlush_.Do(func() { uexit.AtFlush(Flush) })
+	}
+	if prior := exporting_.Swap(b); nil != prior {
+		close(prior.stopC)
+		<-prior.doneC
+	}
+}
+
+// export any queued spans now, returning when done
+func Flush() {
+	if b := exporting_.Load(); nil != b {
+		doneC := make(chan struct{})
+		select {
+		case b.flushC <- doneC:
+			<-doneC
+		case <-b.doneC:
+		}
+	}
+}
+
+// queue the finished span for export, if exporting
+func queue(span *SpanData) {
+	if b := exporting_.Load(); nil != b {
+		b.add(span)
+	}
+}
+
+func (this *batcher_) add(span *SpanData) {
+	this.lock.Lock()
+	full := len(this.spans) >= 4*this.batch
+	if !full {
+		this.spans = append(this.spans, span)
+	}
+	ready := len(this.spans) == this.batch
+	this.lock.Unlock()
+	if full {
+		spansDropped_.Inc()
+	} else if ready {
+		select {
+		case this.kickC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (this *batcher_) run(interval time.Duration) {
+	defer close(this.doneC)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			this.export()
+		case <-this.kickC:
+			this.export()
+		case doneC := <-this.flushC:
+			this.export()
+			close(doneC)
+		case <-this.stopC:
+			this.export()
+			if err := this.exporter.Close(); err != nil {
+				ulog.Warnf("utrace: unable to close exporter: %s", err)
+			}
+			return
+		}
+	}
+}
+
+// export all queued spans, a batch at a time
+func (this *batcher_) export() {
+	this.lock.Lock()
+	spans := this.spans
+	this.spans = make([]*SpanData, 0, this.batch)
+	this.lock.Unlock()
+
+	for 0 != len(spans) {
+		n := min(len(spans), this.batch)
+		if err := this.exporter.Export(spans[:n]); err != nil {
+			ulog.Warnf("utrace: unable to export %d spans: %s", n, err)
+			spansDropped_.Add(uint64(n))
+		} else {
+			spansExported_.Add(uint64(n))
+		}
+		spans = spans[n:]
+	}
+}
+
+//
+// JSON lines
+//
+
+// an Exporter writing each span as a JSON line, such as:
+//
+//	{"traceId":"4bf9...","spanId":"00f0...","parentSpanId":"...","name":"GET /x",
+//	 "kind":"server","start":"2024-01-02T15:04:05.123456Z",
+//	 "end":"2024-01-02T15:04:05.125Z","attrs":{"http.status_code":200}}
+type JsonExporter struct {
+	w io.Writer
+}
+
+// create an Exporter writing JSON lines to w.  if w is an io.Closer, it is
+// closed by Close.
+func NewJsonExporter(w io.Writer) *JsonExporter {
+	return &JsonExporter{w: w}
+}
+
+// create an Exporter writing JSON lines to file, which is rotated when it
+// exceeds size bytes (default 64 MiB), keeping keep rotated files (default 4)
+func NewFileExporter(file string, size int64, keep int) (rv *JsonExporter, err error) {
+	if ext := filepath.Ext(file); 0 == len(ext) ||
+		len(ext) == len(filepath.Base(file)) {
+		err = fmt.Errorf("trace file '%s' must have a name and an extension", file)
+		return
+	} else if 0 >= size {
+		size = 64 << 20
+	} else if 1000 > size {
+		size = 1000
+	}
+	if 0 >= keep {
+		keep = 4
+	} else if 1024 < keep {
+		keep = 1024
+	}
+	wm, err := ulog.NewWriteManager(file, size, keep)
+	if err != nil {
+		return
+	}
+	return NewJsonExporter(wm), nil
+}
+
+type jsonSpan_ struct {
+	TraceID string         `json:"traceId"`
+	SpanID  string         `json:"spanId"`
+	Parent  string         `json:"parentSpanId,omitempty"`
+	Name    string         `json:"name"`
+	Kind    string         `json:"kind"`
+	Start   time.Time      `json:"start"`
+	End     time.Time      `json:"end"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Err     string         `json:"error,omitempty"`
+}
+
+// implement Exporter
+func (this *JsonExporter) Export(spans []*SpanData) (err error) {
+	for _, span := range spans {
+		js := jsonSpan_{
+			TraceID: span.TraceID.String(),
+			SpanID:  span.SpanID.String(),
+			Name:    span.Name,
+			Kind:    span.Kind.String(),
+			Start:   span.StartT,
+			End:     span.EndT,
+			Err:     span.Err,
+		}
+		if span.Parent.IsValid() {
+			js.Parent = span.Parent.String()
+		}
+		eachAttr(span.Attrs, func(k string, v any) {
+			if nil == js.Attrs {
+				js.Attrs = make(map[string]any, len(span.Attrs)/2)
+			}
+			js.Attrs[k] = v
+		})
+		var line []byte
+		line, err = json.Marshal(&js)
+		if err != nil {
+			return
+		}
+		// one write per line, so rotation does not split lines
+		if _, err = this.w.Write(append(line, '\n')); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// implement Exporter
+func (this *JsonExporter) Close() (err error) {
+	if c, ok := this.w.(io.Closer); ok {
+		err = c.Close()
+	}
+	return
+}
+
+// call fn with each key and value of kv, with values that are not JSON
+// friendly converted to strings
+func eachAttr(kv []any, fn func(k string, v any)) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			k = fmt.Sprint(kv[i])
+		}
+		switch v := kv[i+1].(type) {
+		case string, bool, int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64:
+			fn(k, v)
+		case float32:
+			eachAttr([]any{k, float64(v)}, fn)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) { // not valid JSON
+				fn(k, fmt.Sprint(v))
+			} else {
+				fn(k, v)
+			}
+		case time.Duration:
+			fn(k, v.String())
+		default:
+			fn(k, fmt.Sprint(v))
+		}
+	}
+}
+
+//
+// OTLP
+//
+
+// an Exporter posting spans to an OTLP/HTTP endpoint in the OTLP JSON
+// encoding, such as to an OpenTelemetry collector.
+type OtlpExporter struct {
+	Url     string        // such as http://collector:4318/v1/traces
+	Service string        // service.name of the spans
+	Client  *http.Client  // if nil, then a default client
+	Timeout time.Duration // for each export (default 10s)
+}
+
+type otlpValue_ struct {
+	String *string  `json:"stringValue,omitempty"`
+	Int    *string  `json:"intValue,omitempty"` // int64 as string
+	Double *float64 `json:"doubleValue,omitempty"`
+	Bool   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttr_ struct {
+	Key   string     `json:"key"`
+	Value otlpValue_ `json:"value"`
+}
+
+type otlpStatus_ struct {
+	Code    int    `json:"code"` // 1: ok, 2: error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan_ struct {
+	TraceID string      `json:"traceId"`
+	SpanID  string      `json:"spanId"`
+	Parent  string      `json:"parentSpanId,omitempty"`
+	Name    string      `json:"name"`
+	Kind    int         `json:"kind"`
+	Start   string      `json:"startTimeUnixNano"`
+	End     string      `json:"endTimeUnixNano"`
+	Attrs   []otlpAttr_ `json:"attributes,omitempty"`
+	Status  otlpStatus_ `json:"status"`
+}
+
+type otlpScopeSpans_ struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan_ `json:"spans"`
+}
+
+type otlpResourceSpans_ struct {
+	Resource struct {
+		Attrs []otlpAttr_ `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans_ `json:"scopeSpans"`
+}
+
+type otlpRequest_ struct {
+	ResourceSpans []otlpResourceSpans_ `json:"resourceSpans"`
+}
+
+func otlpAttr(k string, v any) (rv otlpAttr_) {
+	rv.Key = k
+	switch v := v.(type) {
+	case bool:
+		rv.Value.Bool = &v
+	case float32:
+		f := float64(v)
+		rv.Value.Double = &f
+	case float64:
+		rv.Value.Double = &v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprint(v)
+		rv.Value.Int = &s
+	default:
+		s := fmt.Sprint(v)
+		rv.Value.String = &s
+	}
+	return
+}
+
+// implement Exporter
+func (this *OtlpExporter) Export(spans []*SpanData) (err error) {
+	scope := otlpScopeSpans_{Spans: make([]otlpSpan_, len(spans))}
+	scope.Scope.Name = "github.com/tredeske/u/utrace"
+	for i, span := range spans {
+		s := &scope.Spans[i]
+		s.TraceID = span.TraceID.String()
+		s.SpanID = span.SpanID.String()
+		if span.Parent.IsValid() {
+			s.Parent = span.Parent.String()
+		}
+		s.Name = span.Name
+		s.Kind = int(span.Kind)
+		s.Start = strconv.FormatInt(span.StartT.UnixNano(), 10)
+		s.End = strconv.FormatInt(span.EndT.UnixNano(), 10)
+		eachAttr(span.Attrs, func(k string, v any) {
+			s.Attrs = append(s.Attrs, otlpAttr(k, v))
+		})
+		if 0 != len(span.Err) {
+			s.Status = otlpStatus_{Code: 2, Message: span.Err}
+		} else {
+			s.Status.Code = 1
+		}
+	}
+	rs := otlpResourceSpans_{ScopeSpans: []otlpScopeSpans_{scope}}
+	rs.Resource.Attrs = []otlpAttr_{otlpAttr("service.name", this.Service)}
+
+	timeout := this.Timeout
+	if 0 >= timeout {
+		timeout = 10 * time.Second
+	}
+	_, err = urest.NewRequestor(this.Client).
+		SetTimeout(timeout).
+		SetUrlString(this.Url).
+		SetBodyJson(&otlpRequest_{ResourceSpans: []otlpResourceSpans_{rs}}).
+		Post().
+		IsSuccess().
+		Done()
+	return
+}
+
+// implement Exporter
+func (this *OtlpExporter) Close() (err error) { return }
@@ -0,0 +1,113 @@
+package utrace
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tredeske/u/golum"
+	"github.com/tredeske/u/uconfig"
+	"github.com/tredeske/u/urest"
+)
+
+var added_ bool
+
+// add the 'tracing' component type, which exports finished spans to a
+// rotating JSON lines file, or to an OTLP/HTTP endpoint:
+//
+//	components:
+//	- name:     tracing
+//	  type:     tracing
+//	  config:
+//	    otlpUrl:  http://collector:4318/v1/traces
+//	    service:  myService
+func AddManagers() {
+	if !added_ {
+		added_ = true
+		golum.AddReloadable("tracing", &tracing_{})
+	}
+}
+
+type tracing_ struct {
+	file     string
+	size     int64
+	keep     int
+	otlp     OtlpExporter
+	batch    int
+	interval time.Duration
+}
+
+func (this *tracing_) Help(name string, help *uconfig.Help) {
+	p := help.Init(name,
+		"Exports finished trace spans to a JSON lines file or OTLP/HTTP endpoint")
+	p.NewItem("file", "string",
+		"JSON lines file to export to (rotated).  Either this or otlpUrl.").
+		Optional()
+	p.NewItem("size", "int", "Bytes in file before rotating").Default("64Mi")
+	p.NewItem("keep", "int", "Rotated files to keep").Default(4)
+	p.NewItem("otlpUrl", "string",
+		"OTLP/HTTP traces endpoint to export to.  Either this or file.").
+		Optional()
+	p.NewItem("service", "string", "service.name of exported spans").
+		Default("program name")
+	p.NewItem("timeout", "duration", "Time allowed for each OTLP export").
+		Default("10s")
+	p.NewItem("batch", "int", "Max spans exported at once").Default(512)
+	p.NewItem("interval", "duration", "Max time between exports").Default("5s")
+	urest.ShowHttpClient("otlpClient", "HTTP client for OTLP export", p)
+}
+
+func (this *tracing_) Reload(
+	name string,
+	c *uconfig.Chain,
+) (
+	rv golum.Reloadable,
+	err error,
+) {
+	next := &tracing_{
+		size:     64 << 20,
+		keep:     4,
+		batch:    512,
+		interval: 5 * time.Second,
+	}
+	next.otlp.Service = filepath.Base(os.Args[0])
+	var client *http.Client
+	err = c.
+		GetString("file", &next.file).
+		GetByteSize("size", &next.size).
+		GetInt("keep", &next.keep, uconfig.IntRange(1, 1024)).
+		GetString("otlpUrl", &next.otlp.Url).
+		GetString("service", &next.otlp.Service, uconfig.StringNotBlank()).
+		GetDuration("timeout", &next.otlp.Timeout).
+		GetInt("batch", &next.batch, uconfig.IntRange(1, 1<<16)).
+		GetDuration("interval", &next.interval).
+		BuildIf("otlpClient", &client, urest.BuildHttpClient).
+		Done()
+	if err != nil {
+		return
+	} else if (0 == len(next.file)) == (0 == len(next.otlp.Url)) {
+		err = errors.New("tracing: exactly one of file or otlpUrl must be set")
+		return
+	}
+	next.otlp.Client = client
+	rv = next
+	return
+}
+
+func (this *tracing_) Start() (err error) {
+	var e Exporter = &this.otlp
+	if 0 != len(this.file) {
+		e, err = NewFileExporter(this.file, this.size, this.keep)
+		if err != nil {
+			return
+		}
+	}
+	SetExporter(e, this.batch, this.interval)
+	return
+}
+
+func (this *tracing_) Stop() {
+	SetExporter(nil, 0, 0)
+}
@@ -0,0 +1,132 @@
+package utrace
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// set the trace context headers of req from the span in its context, if any.
+// use with urest.Requestor:
+//
+//	urest.NewRequestor(client).WithContext(ctx).BeforeRequest(utrace.Inject)
+func Inject(req *http.Request) (err error) {
+	setHeaders(req.Header, SpanContextFrom(req.Context()))
+	return
+}
+
+func setHeaders(h http.Header, sc SpanContext) {
+	if sc.IsValid() {
+		h.Set(TraceparentHeader, sc.Traceparent())
+		if 0 != len(sc.State) {
+			h.Set(TracestateHeader, sc.State)
+		}
+	}
+}
+
+// get the span context of another process from the trace context headers
+func Extract(h http.Header) (rv SpanContext, err error) {
+	rv, err = ParseTraceparent(h.Get(TraceparentHeader))
+	if nil == err {
+		rv.State = h.Get(TracestateHeader)
+	}
+	return
+}
+
+// an http.RoundTripper making a client span for each request that is a child
+// of the span in the request context, and propagating it to the server
+//
+//	client := &http.Client{Transport: &utrace.Transport{}}
+type Transport struct {
+	Base http.RoundTripper // if nil, then http.DefaultTransport
+}
+
+// implement http.RoundTripper
+func (this *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := start(req.Context(), KindClient, req.Method+" "+req.URL.Host,
+		[]any{"http.method", req.Method, "http.url", req.URL.String()})
+	defer span.End()
+
+	req = req.Clone(ctx) // RoundTrippers must not change req
+	setHeaders(req.Header, span.Context())
+
+	base := this.Base
+	if nil == base {
+		base = http.DefaultTransport
+	}
+	resp, err = base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.SetAttrs("http.status_code", resp.StatusCode)
+		if 500 <= resp.StatusCode {
+			span.SetError(statusError_(resp.StatusCode))
+		}
+	}
+	return
+}
+
+// Wrap next so that each request gets a server span, continuing the trace of
+// the client (see Extract), and the request context carries the span.
+//
+//	server.Handler = utrace.Middleware(mux)
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if sc, err := Extract(req.Header); nil == err {
+			ctx = ContextWithRemote(ctx, sc)
+		}
+		ctx, span := start(ctx, KindServer, req.Method+" "+req.URL.Path,
+			[]any{"http.method", req.Method, "http.target", req.URL.Path})
+		sw := &statusWriter_{ResponseWriter: w}
+		defer func() {
+			if 0 == sw.status {
+				sw.status = http.StatusOK
+			}
+			span.SetAttrs("http.status_code", sw.status)
+			if 500 <= sw.status {
+				span.SetError(statusError_(sw.status))
+			}
+			span.End()
+		}()
+		next.ServeHTTP(sw, req.WithContext(ctx))
+	})
+}
+
+// an error for an HTTP status
+type statusError_ int
+
+func (this statusError_) Error() string {
+	return "HTTP status " + strconv.Itoa(int(this))
+}
+
+// capture the response status
+type statusWriter_ struct {
+	http.ResponseWriter
+	status int
+}
+
+func (this *statusWriter_) WriteHeader(status int) {
+	if 0 == this.status {
+		this.status = status
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusWriter_) Write(b []byte) (int, error) {
+	if 0 == this.status {
+		this.status = http.StatusOK
+	}
+	return this.ResponseWriter.Write(b)
+}
+
+// implement http.Flusher, for streaming responses
+func (this *statusWriter_) Flush() {
+	if f, ok := this.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// for http.ResponseController
+func (this *statusWriter_) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
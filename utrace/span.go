@@ -0,0 +1,325 @@
+// Package utrace provides lightweight distributed tracing.
+//
+// A Span times an operation.  Spans started from a context carrying a span
+// become its children, sharing its trace ID:
+//
+//	ctx, span := utrace.Start(ctx, "reconcile", "account", id)
+//	defer span.End()
+//	...
+//	if err != nil {
+//	    span.SetError(err)
+//	}
+//
+// Spans are propagated between processes with the W3C traceparent header.
+// Outgoing requests carry the span of their context, either with Transport
+// (which also makes a client span for each request), or with Inject:
+//
+//	client := &http.Client{Transport: &utrace.Transport{}}
+//	_, err := urest.NewRequestor(client).WithContext(ctx).SetUrlString(url).
+//	    Get().IsOk().Done()
+//
+//	_, err := urest.NewRequestor(client).WithContext(ctx).SetUrlString(url).
+//	    BeforeRequest(utrace.Inject).Get().IsOk().Done()
+//
+// Servers continue the trace of incoming requests with Middleware, which
+// makes a server span for each request:
+//
+//	server.Handler = utrace.Middleware(mux)
+//
+// Messages logged with the context of a span carry its trace_id and span_id
+// (see ulog.InfoContext and ulog.Handler).
+//
+// Finished spans are exported in batches (see SetExporter) to a rotating
+// JSON lines file (NewFileExporter), or to an OTLP/HTTP endpoint, such as an
+// OpenTelemetry collector (OtlpExporter).  The 'tracing' component (see
+// AddManagers) sets this up from config.
+package utrace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/tredeske/u/ulog"
+)
+
+// the W3C trace context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// identifies a trace
+type TraceID [16]byte
+
+func (this TraceID) IsValid() bool { return TraceID{} != this }
+
+func (this TraceID) String() string { return hex.EncodeToString(this[:]) }
+
+// identifies a span within a trace
+type SpanID [8]byte
+
+func (this SpanID) IsValid() bool { return SpanID{} != this }
+
+func (this SpanID) String() string { return hex.EncodeToString(this[:]) }
+
+func newTraceID() (rv TraceID) {
+	for !rv.IsValid() {
+		putUint64(rv[:8], rand.Uint64())
+		putUint64(rv[8:], rand.Uint64())
+	}
+	return
+}
+
+func newSpanID() (rv SpanID) {
+	for !rv.IsValid() {
+		putUint64(rv[:], rand.Uint64())
+	}
+	return
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := range b {
+		b[i] = byte(v >> (8 * i))
+	}
+}
+
+// the identity of a span that is propagated to other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool   // the trace is recorded
+	State   string // tracestate header, passed on as is
+}
+
+func (this SpanContext) IsValid() bool {
+	return this.TraceID.IsValid() && this.SpanID.IsValid()
+}
+
+// get the traceparent header value
+func (this SpanContext) Traceparent() string {
+	flags := "00"
+	if this.Sampled {
+		flags = "01"
+	}
+	return "00-" + this.TraceID.String() + "-" + this.SpanID.String() + "-" +
+		flags
+}
+
+// parse a traceparent header value, such as:
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(s string) (rv SpanContext, err error) {
+	// version-traceid-spanid-flags, with later versions allowed to append
+	if 55 > len(s) || '-' != s[2] || '-' != s[35] || '-' != s[52] ||
+		(55 < len(s) && ("00" == s[:2] || '-' != s[55])) ||
+		"ff" == s[:2] || !isLowerHex(s[:2]) || !isLowerHex(s[3:35]) ||
+		!isLowerHex(s[36:52]) || !isLowerHex(s[53:55]) {
+		err = fmt.Errorf("invalid traceparent '%s'", s)
+		return
+	}
+	hex.Decode(rv.TraceID[:], []byte(s[3:35]))
+	hex.Decode(rv.SpanID[:], []byte(s[36:52]))
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(s[53:55]))
+	rv.Sampled = 0 != flags[0]&1
+	if !rv.IsValid() {
+		err = fmt.Errorf("invalid traceparent '%s' - zero ID", s)
+	}
+	return
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9') && !('a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// the kind of a span
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1 // an operation within a process
+	KindServer                   // handling a request from another process
+	KindClient                   // making a request of another process
+)
+
+func (this Kind) String() string {
+	switch this {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	}
+	return "internal"
+}
+
+// a finished span, as given to an Exporter
+type SpanData struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Parent  SpanID // invalid if this is the root of the trace
+	Name    string
+	Kind    Kind
+	StartT  time.Time
+	EndT    time.Time
+	Attrs   []any  // key, value, ...
+	Err     string // set if the operation failed
+}
+
+// a timed operation within a trace.  a nil Span is valid, and does nothing.
+type Span struct {
+	lock    sync.Mutex
+	data    SpanData
+	sampled bool
+	state   string
+	ended   bool
+}
+
+type spanKey_ struct{}
+type remoteKey_ struct{}
+
+// get the span in ctx, or nil if none
+func FromContext(ctx context.Context) (rv *Span) {
+	if nil != ctx {
+		rv, _ = ctx.Value(spanKey_{}).(*Span)
+	}
+	return
+}
+
+// get a context carrying span, so that spans started from it are children
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey_{}, span)
+}
+
+// get a context carrying the span of another process (see ParseTraceparent),
+// so that spans started from it continue that trace
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey_{}, sc)
+}
+
+// get the span context in ctx, from a span or from another process
+func SpanContextFrom(ctx context.Context) (rv SpanContext) {
+	if span := FromContext(ctx); nil != span {
+		return span.Context()
+	} else if nil != ctx {
+		rv, _ = ctx.Value(remoteKey_{}).(SpanContext)
+	}
+	return
+}
+
+// start a span that is a child of the span in ctx, if any, with key/value
+// attributes, returning a context carrying the span.  call End when done.
+func Start(
+	ctx context.Context,
+	name string,
+	kv ...any,
+) (
+	context.Context,
+	*Span,
+) {
+	return start(ctx, KindInternal, name, kv)
+}
+
+func start(
+	ctx context.Context,
+	kind Kind,
+	name string,
+	kv []any,
+) (
+	context.Context,
+	*Span,
+) {
+	if nil == ctx {
+		ctx = context.Background()
+	}
+	span := &Span{
+		data: SpanData{
+			SpanID: newSpanID(),
+			Name:   name,
+			Kind:   kind,
+			StartT: time.Now(),
+			Attrs:  append([]any(nil), kv...),
+		},
+	}
+	if parent := SpanContextFrom(ctx); parent.IsValid() {
+		span.data.TraceID = parent.TraceID
+		span.data.Parent = parent.SpanID
+		span.sampled = parent.Sampled
+		span.state = parent.State
+	} else {
+		span.data.TraceID = newTraceID()
+		span.sampled = true
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// get the identity of the span, for propagation
+func (this *Span) Context() (rv SpanContext) {
+	if nil != this {
+		rv = SpanContext{
+			TraceID: this.data.TraceID,
+			SpanID:  this.data.SpanID,
+			Sampled: this.sampled,
+			State:   this.state,
+		}
+	}
+	return
+}
+
+// add key/value attributes to the span
+func (this *Span) SetAttrs(kv ...any) {
+	if nil != this {
+		this.lock.Lock()
+		this.data.Attrs = append(this.data.Attrs, kv...)
+		this.lock.Unlock()
+	}
+}
+
+// mark the span as failed, if err is not nil
+func (this *Span) SetError(err error) {
+	if nil != this && nil != err {
+		this.lock.Lock()
+		this.data.Err = err.Error()
+		this.lock.Unlock()
+	}
+}
+
+// finish the span, exporting it if sampled (see SetExporter).  only the
+// first call has any effect.
+func (this *Span) End() {
+	if nil == this {
+		return
+	}
+	this.lock.Lock()
+	if this.ended {
+		this.lock.Unlock()
+		return
+	}
+	this.ended = true
+	this.data.EndT = time.Now()
+	data := this.data
+	this.lock.Unlock()
+	if this.sampled {
+		queue(&data)
+	}
+}
+
+// the trace_id and span_id attributes for ulog messages logged with a
+// context carrying a span
+func logFields(ctx context.Context) []any {
+	if sc := SpanContextFrom(ctx); sc.IsValid() {
+		return []any{"trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String()}
+	}
+	return nil
+}
+
+func init() {
+	ulog.AddContextFields(logFields)
+}
@@ -0,0 +1,241 @@
+package utrace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tredeske/u/golum"
+	"github.com/tredeske/u/ulog"
+	"github.com/tredeske/u/urest"
+)
+
+// keep exported spans in memory
+type memExporter_ struct {
+	lock  sync.Mutex
+	spans []*SpanData
+}
+
+func (this *memExporter_) Export(spans []*SpanData) error {
+	this.lock.Lock()
+	this.spans = append(this.spans, spans...)
+	this.lock.Unlock()
+	return nil
+}
+
+func (this *memExporter_) Close() error { return nil }
+
+func TestTraceparent(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	if err != nil {
+		t.Fatalf("Unable to parse: %s", err)
+	} else if !sc.Sampled || "00f067aa0ba902b7" != sc.SpanID.String() ||
+		tp != sc.Traceparent() {
+		t.Fatalf("Wrong span context: %#v", sc)
+	}
+	if _, err = ParseTraceparent("01" + tp[2:] + "-future"); err != nil {
+		t.Fatalf("Should allow later version: %s", err)
+	}
+	for _, bad := range []string{
+		"",
+		tp + "-extra",
+		"ff" + tp[2:],
+		strings.ToUpper(tp),
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	} {
+		if _, err = ParseTraceparent(bad); nil == err {
+			t.Fatalf("Should not parse '%s'", bad)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	var logB bytes.Buffer
+	saved, savedFlags := log.Writer(), log.Flags()
+	log.SetOutput(&logB)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(saved)
+		log.SetFlags(savedFlags)
+	}()
+
+	mem := &memExporter_{}
+	SetExporter(mem, 0, 0)
+	defer SetExporter(nil, 0, 0)
+
+	srv := httptest.NewServer(Middleware(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ctx, span := Start(req.Context(), "work")
+			defer span.End()
+			ulog.InfoContext(ctx, "working")
+			if "/fail" == req.URL.Path {
+				span.SetError(errors.New("failed"))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		})))
+	defer srv.Close()
+
+	ctx, root := Start(context.Background(), "root", "n", 1)
+	client := &http.Client{Transport: &Transport{}}
+	_, err := urest.NewRequestor(client).
+		WithContext(ctx).
+		SetUrlString(srv.URL + "/ok").
+		Get().
+		IsOk().
+		Done()
+	if err != nil {
+		t.Fatalf("Unable to get: %s", err)
+	}
+	_, err = urest.NewRequestor(nil).
+		WithContext(ctx).
+		SetUrlString(srv.URL + "/fail").
+		BeforeRequest(Inject).
+		Get().
+		StatusIs(http.StatusInternalServerError).
+		Done()
+	if err != nil {
+		t.Fatalf("Unable to get: %s", err)
+	}
+	root.End()
+	root.End()                 // only once
+	for i := 0; i < 100; i++ { // server spans end after response sent
+		Flush()
+		mem.lock.Lock()
+		n := len(mem.spans)
+		mem.lock.Unlock()
+		if 6 <= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	byName := map[string]*SpanData{}
+	for _, span := range mem.spans {
+		byName[span.Name] = span
+		if root.data.TraceID != span.TraceID {
+			t.Fatalf("Span %s not in trace", span.Name)
+		}
+	}
+	client1, server1, server2 := byName["GET "+srv.URL[len("http://"):]],
+		byName["GET /ok"], byName["GET /fail"]
+	if 6 != len(mem.spans) || nil == client1 || nil == server1 || nil == server2 {
+		t.Fatalf("Wrong spans: %d %v", len(mem.spans), byName)
+	} else if root.data.SpanID != client1.Parent || KindClient != client1.Kind ||
+		client1.SpanID != server1.Parent || KindServer != server1.Kind ||
+		root.data.SpanID != server2.Parent {
+		t.Fatalf("Spans not linked")
+	} else if "HTTP status 500" != server2.Err || 0 != len(server1.Err) {
+		t.Fatalf("Wrong errors: '%s' '%s'", server1.Err, server2.Err)
+	} else if root.data.Parent.IsValid() || 2 != len(byName["root"].Attrs) {
+		t.Fatalf("Wrong root: %#v", byName["root"])
+	}
+
+	expect := "working [trace_id=" + root.data.TraceID.String() + " span_id="
+	if 2 != strings.Count(logB.String(), expect) {
+		t.Fatalf("Log should have trace IDs:\n%s", logB.String())
+	}
+}
+
+func TestExport(t *testing.T) {
+	AddManagers()
+
+	//
+	// OTLP, to a stand-in collector
+	//
+	bodyC := make(chan []byte, 10)
+	collector := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			if "/v1/traces" != req.URL.Path ||
+				"application/json" != req.Header.Get("Content-Type") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			bodyC <- body
+		}))
+	defer collector.Close()
+
+	err := golum.TestLoadAndStart([]byte(`
+components:
+- name:         tracing
+  type:         tracing
+  config:
+    otlpUrl:    ` + collector.URL + `/v1/traces
+    service:    traceTest
+`))
+	if err != nil {
+		t.Fatalf("Unable to load and start: %s", err)
+	}
+
+	_, span := Start(context.Background(), "otlp", "count", 3, "ok", true)
+	span.SetError(errors.New("oops"))
+	span.End()
+	Flush()
+
+	var req otlpRequest_
+	if err = json.Unmarshal(<-bodyC, &req); err != nil {
+		t.Fatalf("Unable to decode OTLP: %s", err)
+	}
+	rs := req.ResourceSpans[0]
+	s := rs.ScopeSpans[0].Spans[0]
+	if "traceTest" != *rs.Resource.Attrs[0].Value.String ||
+		span.data.TraceID.String() != s.TraceID || "otlp" != s.Name ||
+		int(KindInternal) != s.Kind || 2 != s.Status.Code ||
+		"3" != *s.Attrs[0].Value.Int || !*s.Attrs[1].Value.Bool {
+		t.Fatalf("Wrong OTLP: %+v", s)
+	}
+
+	//
+	// JSON lines file
+	//
+	file := filepath.Join(t.TempDir(), "spans.json")
+	err = golum.TestReload([]byte(`
+components:
+- name:         tracing
+  type:         tracing
+  config:
+    file:       ` + file + `
+`))
+	if err != nil {
+		t.Fatalf("Unable to reload: %s", err)
+	}
+	ctx, span := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child", "took", 5)
+	child.End()
+	span.End()
+	golum.TestStop() // flushes
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("Unable to open spans: %s", err)
+	}
+	defer f.Close()
+	var spans []map[string]any
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var m map[string]any
+		if err = json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("Unable to decode line: %s", err)
+		}
+		spans = append(spans, m)
+	}
+	if 2 != len(spans) || "child" != spans[0]["name"] ||
+		span.data.SpanID.String() != spans[0]["parentSpanId"] ||
+		5.0 != spans[0]["attrs"].(map[string]any)["took"] ||
+		nil != spans[1]["parentSpanId"] {
+		t.Fatalf("Wrong spans: %v", spans)
+	}
+}